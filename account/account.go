@@ -2,30 +2,28 @@ package account
 
 import (
 	"blink-liveview-websocket/common"
+	"blink-liveview-websocket/login"
 	"context"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"os/signal"
-	"syscall"
-
-	"golang.org/x/term"
 )
 
 // Fetches the list of Blink devices and prints them to the console
 // Select one of the devices to start a liveview stream
 //
-// token: the Blink API token
-//
-// accountId: the Blink account ID
-//
-// region: the Blink API region
-func Run(token string, accountId int, region string) {
-	baseUrl := common.GetApiUrl(region)
-	homescreenUrl := fmt.Sprintf("%s/api/v4/accounts/%d/homescreen", baseUrl, accountId)
-	devices, err := common.Homescreen(homescreenUrl, token)
-	if err != nil {
+// client: the Blink API client holding the token, account ID and region
+func Run(client *common.BlinkClient) {
+	homescreenCtx, cancelHomescreenCtx := signal.NotifyContext(context.Background(), os.Interrupt)
+	devices, err := client.Homescreen(homescreenCtx, client.HomescreenUrl())
+	// Restore the default interrupt handling for the device prompt
+	cancelHomescreenCtx()
+	if common.IsUnauthorized(err) {
+		log.Println("the API token is invalid or expired. Log in again with the login command", err)
+		os.Exit(1)
+	} else if err != nil {
 		log.Println("error getting homescreen", err)
 		os.Exit(1)
 	}
@@ -81,15 +79,9 @@ getDevice:
 		cancelCtx()
 	}()
 
-	accountDetails := common.AccountDetails{
-		Region:     region,
-		Token:      token,
-		DeviceType: device.DeviceType,
-		AccountId:  accountId,
-		NetworkId:  device.NetworkId,
-		CameraId:   device.DeviceId,
-	}
-	if err := common.Livestream(ctx, accountDetails, inputPipe); err != nil {
+	if err := client.Livestream(ctx, device.DeviceType, device.NetworkId, device.DeviceId, inputPipe); common.IsDeviceBusy(err) || common.IsRateLimited(err) {
+		log.Println("the device is busy. Wait a few seconds and try again", err)
+	} else if err != nil {
 		log.Println("error starting liveview session", err)
 	}
 
@@ -99,59 +91,20 @@ getDevice:
 	}
 }
 
-// Authenticates with the Blink API using the provided email and password
-// and fetches the list of Blink devices
+// Authenticates with the Blink API using the provided email and password,
+// saves the session to a profile and fetches the list of Blink devices
 // Select one of the devices to start a liveview stream
 //
+// client: the Blink API client to authenticate. Updated with the session details on success
+//
 // email: the Blink account email address
 //
 // password: the Blink account password
-func RunWithCredentials(email string, password string) {
-	fingerprint, err := common.GetFingerprint("")
-	if err != nil {
-		log.Println("error getting fingerprint", err)
-		os.Exit(1)
-	}
-
-	loginResp, err := common.Login(email, password, "", fingerprint)
-	if err != nil {
-		log.Println("error logging in", err)
-		os.Exit(1)
-	}
-
-	var tsvResp *common.LoginResponse
-	if loginResp.TwoStepVerification == "sms" {
-		log.Println("Client verification is required. A SMS code has been sent to your phone.")
-		fmt.Print("Code: ")
-		codeBytes, err := term.ReadPassword(int(syscall.Stdin))
-		if err != nil {
-			os.Exit(1)
-		}
-		code := string(codeBytes)
-		fmt.Println()
-
-		var tsvErr error
-		if tsvResp, tsvErr = common.Login(email, password, code, fingerprint); tsvErr != nil {
-			log.Println("error verifying pin", tsvErr)
-			os.Exit(1)
-		}
-	} else {
-		// TODO: Handle other TSV states or skip TSV if not required
-		log.Println("Unexpected two-step verification state:", loginResp.TwoStepVerification)
-		os.Exit(1)
-	}
-
-	tierInfo, err := common.GetTierInfo(tsvResp.AccessToken)
-	if err != nil {
-		log.Println("error getting tier info", err)
-		os.Exit(1)
-	}
-
-	if err := fingerprint.Store(); err != nil {
-		log.Println("error saving the fingerprint. Next login will require a new SMS code.", err)
-	}
-
-	log.Printf("Logged in successfully.\n\tToken: %s,\n\tAccountId: %d,\n\tRegion: %s\n", tsvResp.AccessToken, tierInfo.AccountId, tierInfo.Tier)
+//
+// profileName: the name of the profile to save the session to
+func RunWithCredentials(client *common.BlinkClient, email string, password string, profileName string) {
+	profile := login.Authenticate(context.Background(), client, email, password, profileName)
+	log.Printf("Logged in successfully. Saved profile %s. Use --profile %s next time\n", profile.Name, profile.Name)
 
-	Run(tsvResp.AccessToken, tierInfo.AccountId, tierInfo.Tier)
+	Run(profile.Client())
 }
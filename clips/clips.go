@@ -0,0 +1,123 @@
+package clips
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+)
+
+// Lists the cloud clips created between since and until
+//
+// client: the Blink API client holding the token, account ID and region
+//
+// since: only clips created after this time are listed
+//
+// until: only clips created before this time are listed. A zero value disables the filter
+func List(client *common.BlinkClient, since time.Time, until time.Time) {
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancelCtx()
+
+	clips := listMedia(ctx, client, since, until)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tNETWORK\tCAMERA\tSOURCE\tWATCHED")
+	for _, clip := range clips {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%t\n", clip.Id, clip.CreatedAt.Local().Format(time.DateTime), clip.NetworkName, clip.DeviceName, clip.Source, clip.Watched)
+	}
+	w.Flush()
+}
+
+// Downloads the cloud clips created between since and until into dir/network/camera/date.
+// Clips that have already been downloaded are skipped.
+//
+// client: the Blink API client holding the token, account ID and region
+//
+// dir: the directory to mirror the clips into
+//
+// since: only clips created after this time are downloaded
+//
+// until: only clips created before this time are downloaded. A zero value disables the filter
+func Download(client *common.BlinkClient, dir string, since time.Time, until time.Time) {
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancelCtx()
+
+	clips := listMedia(ctx, client, since, until)
+
+	var downloaded, skipped, failed int
+	for _, clip := range clips {
+		if ctx.Err() != nil {
+			log.Println("Download interrupted")
+			break
+		}
+
+		path := filepath.Join(dir, common.ClipPath(clip))
+		if _, err := os.Stat(path); err == nil {
+			skipped++
+			continue
+		}
+
+		n, err := downloadClip(ctx, client, clip, path)
+		if err != nil {
+			log.Printf("error downloading clip %d: %v\n", clip.Id, err)
+			failed++
+			continue
+		}
+
+		log.Printf("Saved clip %d to %s (%d bytes)\n", clip.Id, path, n)
+		downloaded++
+	}
+
+	log.Printf("Downloaded %d clips, skipped %d, failed %d\n", downloaded, skipped, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// listMedia lists the clips or exits on error
+func listMedia(ctx context.Context, client *common.BlinkClient, since time.Time, until time.Time) []common.MediaClip {
+	clips, err := client.ListAllMedia(ctx, since, until)
+	if common.IsUnauthorized(err) {
+		log.Println("the API token is invalid or expired", err)
+		os.Exit(1)
+	} else if errors.Is(err, common.ErrMediaTruncated) {
+		log.Println("too many clips to list, narrow the range with --since and --until", err)
+		os.Exit(1)
+	} else if err != nil {
+		log.Println("error listing clips", err)
+		os.Exit(1)
+	}
+
+	return clips
+}
+
+// downloadClip downloads the clip to a temporary file and renames it into place,
+// so an interrupted download is retried on the next run
+func downloadClip(ctx context.Context, client *common.BlinkClient, clip common.MediaClip, path string) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, err
+	}
+
+	tmpPath := path + ".part"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := client.DownloadClip(ctx, clip, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return 0, err
+	}
+
+	return n, os.Rename(tmpPath, path)
+}
@@ -2,40 +2,34 @@ package cmd
 
 import (
 	"blink-liveview-websocket/account"
-	"fmt"
-	"os"
-	"syscall"
+	"blink-liveview-websocket/common"
 
 	"github.com/spf13/cobra"
-	"golang.org/x/term"
 )
 
 var accountCmd = &cobra.Command{
 	Use:   "account",
 	Short: "Start a liveview stream by logging in with your Blink account and selecting a camera",
 	Long: `This command will authenticate with your Blink account, fetch a list of available cameras,
-and start a liveview stream from the selected camera.
+and start a liveview stream from the selected camera. The session is saved to a login profile.
 
-Additionally, you can provide an API token, account ID, and region to bypass the login process.
+Additionally, you can provide a saved profile, or an API token, account ID, and region to
+bypass the login process.
 
 Use this command if you want to start a liveview stream, but do not have the
 full connection credentials already.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if cmd.Flag("email").Value.String() != "" {
-			fmt.Print("Password: ")
-			passwordBytes, err := term.ReadPassword(int(syscall.Stdin))
-			if err != nil {
-				os.Exit(1)
+		if email := cmd.Flag("email").Value.String(); email != "" {
+			profileName := cmd.Flag("profile").Value.String()
+			if profileName == "" {
+				profileName = common.DEFAULT_PROFILE
 			}
-			pass := string(passwordBytes)
-			fmt.Println()
 
-			account.RunWithCredentials(cmd.Flag("email").Value.String(), pass)
+			account.RunWithCredentials(common.NewBlinkClient("", "", 0), email, readPassword(), profileName)
 			return
 		}
 
-		accountId, _ := cmd.Flags().GetInt("account-id")
-		account.Run(cmd.Flag("token").Value.String(), accountId, cmd.Flag("region").Value.String())
+		account.Run(newClient(cmd))
 	},
 }
 
@@ -44,11 +38,13 @@ func init() {
 
 	accountCmd.Flags().StringP("email", "e", "", "Blink account email address")
 
+	accountCmd.Flags().StringP("profile", "p", "", "The saved login profile to use, or to save the session to with --email")
 	accountCmd.Flags().StringP("token", "t", "", "Blink auth token")
 	accountCmd.Flags().IntP("account-id", "a", 0, "Blink account ID")
 	accountCmd.Flags().StringP("region", "r", "", "Blink API region")
 	accountCmd.MarkFlagsRequiredTogether("token", "account-id", "region")
 
 	accountCmd.MarkFlagsMutuallyExclusive("email", "token")
-	accountCmd.MarkFlagsOneRequired("email", "token")
+	accountCmd.MarkFlagsMutuallyExclusive("profile", "token")
+	accountCmd.MarkFlagsOneRequired("email", "token", "profile")
 }
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"blink-liveview-websocket/common"
+	"fmt"
+	"log"
+	"os"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// addClientFlags registers the flags required to build a Blink API client.
+// Either a saved profile or the token, account ID and region must be provided.
+//
+// cmd: the command to register the flags on
+func addClientFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("profile", "p", "", "The saved login profile to use (see the login command)")
+	cmd.Flags().StringP("region", "r", "", "The Blink API subdomain/region to use (e.g. u011)")
+	cmd.Flags().StringP("token", "t", "", "The Blink API token to use for authentication")
+	cmd.Flags().IntP("account-id", "a", 0, "The Blink account ID")
+	cmd.MarkFlagsRequiredTogether("token", "account-id", "region")
+	cmd.MarkFlagsMutuallyExclusive("profile", "token")
+	cmd.MarkFlagsOneRequired("profile", "token")
+}
+
+// newClient builds a Blink API client from the flags registered by addClientFlags
+//
+// cmd: the command to read the flags from
+func newClient(cmd *cobra.Command) *common.BlinkClient {
+	if name := cmd.Flag("profile").Value.String(); name != "" {
+		return loadProfile(name).Client()
+	}
+
+	accountId, _ := cmd.Flags().GetInt("account-id")
+
+	return common.NewBlinkClient(cmd.Flag("token").Value.String(), cmd.Flag("region").Value.String(), accountId)
+}
+
+// loadProfile loads a saved profile, exiting if it cannot be loaded
+//
+// name: the name of the profile
+func loadProfile(name string) *common.Profile {
+	profile, err := common.LoadProfile("", name)
+	if err != nil {
+		log.Println("error loading profile. Log in with the login command first", err)
+		os.Exit(1)
+	}
+
+	if profile.Proxy != "" {
+		rawProxy, _ := rootCmd.PersistentFlags().GetString("proxy")
+		proxy, err := common.ResolveProxy(rawProxy, profile.Proxy)
+		if err != nil {
+			log.Println("invalid proxy in profile", profile.Name, err)
+			os.Exit(1)
+		}
+		common.DefaultProxy = proxy
+	}
+
+	return profile
+}
+
+// readPassword prompts for the account password without echoing it
+func readPassword() string {
+	return readSecret("Password: ")
+}
+
+// readSecret prompts for a secret without echoing it
+//
+// prompt: the prompt to print
+func readSecret(prompt string) string {
+	fmt.Print(prompt)
+	secretBytes, err := term.ReadPassword(int(syscall.Stdin))
+	if err != nil {
+		os.Exit(1)
+	}
+	fmt.Println()
+
+	return string(secretBytes)
+}
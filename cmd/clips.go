@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"blink-liveview-websocket/clips"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var clipsCmd = &cobra.Command{
+	Use:   "clips",
+	Short: "List and download motion clips stored in the Blink cloud",
+}
+
+var clipsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the cloud clips on the account",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		since, until, err := clipsRange(cmd)
+		if err != nil {
+			return err
+		}
+
+		clips.List(newClient(cmd), since, until)
+		return nil
+	},
+}
+
+var clipsDownloadCmd = &cobra.Command{
+	Use:   "download",
+	Short: "Download cloud clips into a local directory",
+	Long: `The download command mirrors cloud clips into a local directory tree
+organised by network, camera and date. Clips that have already been downloaded
+are skipped, so the command can be run repeatedly to archive new clips.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		since, until, err := clipsRange(cmd)
+		if err != nil {
+			return err
+		}
+
+		clips.Download(newClient(cmd), cmd.Flag("output").Value.String(), since, until)
+		return nil
+	},
+}
+
+// clipsRange parses the --since and --until flags
+func clipsRange(cmd *cobra.Command) (time.Time, time.Time, error) {
+	since, err := parseTimeFlag(cmd.Flag("since").Value.String())
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid --since: %w", err)
+	}
+	until, err := parseTimeFlag(cmd.Flag("until").Value.String())
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid --until: %w", err)
+	}
+
+	return since, until, nil
+}
+
+// parseTimeFlag parses a time given as RFC 3339, a local date (2006-01-02) or a duration ago (e.g. 24h)
+//
+// Example: parseTimeFlag("2025-07-13") = time.Date(2025, 7, 13, 0, 0, 0, 0, time.Local), nil
+func parseTimeFlag(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+
+	return time.Time{}, fmt.Errorf("expected an RFC 3339 time, a date (YYYY-MM-DD) or a duration, got %q", value)
+}
+
+func init() {
+	rootCmd.AddCommand(clipsCmd)
+	clipsCmd.AddCommand(clipsListCmd)
+	clipsCmd.AddCommand(clipsDownloadCmd)
+
+	for _, cmd := range []*cobra.Command{clipsListCmd, clipsDownloadCmd} {
+		addClientFlags(cmd)
+		cmd.Flags().String("since", "24h", "Only include clips created after this time (RFC 3339, YYYY-MM-DD or a duration ago)")
+		cmd.Flags().String("until", "", "Only include clips created before this time (RFC 3339, YYYY-MM-DD or a duration ago)")
+	}
+	clipsDownloadCmd.Flags().StringP("output", "o", "clips", "The directory to mirror the clips into")
+}
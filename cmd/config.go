@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"blink-liveview-websocket/config"
+	"io"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and change the settings of a single camera",
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Print the settings of a camera as JSON",
+	Run: func(cmd *cobra.Command, args []string) {
+		networkId, _ := cmd.Flags().GetInt("network-id")
+		cameraId, _ := cmd.Flags().GetInt("camera-id")
+
+		config.Get(newClient(cmd), cmd.Flag("device-type").Value.String(), networkId, cameraId)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <json|->",
+	Short: "Change the settings of a camera",
+	Long: `The set command applies the settings in the JSON object to the camera and
+waits for Blink to confirm the change. Settings that are not included are left
+unchanged. Use '-' to read the JSON from stdin.
+
+Example: config set '{"motion_sensitivity": 5, "video_length": 10}' ...`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		networkId, _ := cmd.Flags().GetInt("network-id")
+		cameraId, _ := cmd.Flags().GetInt("camera-id")
+
+		data := []byte(args[0])
+		if args[0] == "-" {
+			var err error
+			if data, err = io.ReadAll(os.Stdin); err != nil {
+				log.Println("error reading settings from stdin", err)
+				os.Exit(1)
+			}
+		}
+
+		config.Set(newClient(cmd), cmd.Flag("device-type").Value.String(), networkId, cameraId, data)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+
+	for _, cmd := range []*cobra.Command{configGetCmd, configSetCmd} {
+		addClientFlags(cmd)
+		cmd.Flags().StringP("device-type", "d", "", "The Blink device type (e.g. camera, owl, doorbell)")
+		cmd.MarkFlagRequired("device-type")
+		cmd.Flags().IntP("network-id", "n", 0, "The Blink network ID")
+		cmd.MarkFlagRequired("network-id")
+		cmd.Flags().IntP("camera-id", "c", 0, "The Blink camera ID")
+		cmd.MarkFlagRequired("camera-id")
+	}
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"blink-liveview-websocket/common"
+	"blink-liveview-websocket/credentials"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var credentialsCmd = &cobra.Command{
+	Use:   "credentials",
+	Short: "Manage the encryption of saved login profiles",
+	Long: `Saved login profiles and the fingerprint file are encrypted with AES-256-GCM when a
+passphrase is provided through the ` + common.CREDENTIAL_PASSPHRASE_ENV + ` environment variable or
+--ask-passphrase. Plaintext files are rejected once a passphrase is set. Encrypt them
+with the passphrase command, run without a current passphrase.`,
+}
+
+var credentialsPassphraseCmd = &cobra.Command{
+	Use:   "passphrase",
+	Short: "Change the passphrase used to encrypt saved credentials",
+	Long: `The passphrase command re-encrypts every saved profile and the fingerprint file
+with a new passphrase. The current passphrase is read from ` + common.CREDENTIAL_PASSPHRASE_ENV + `
+or --ask-passphrase. Leave the new passphrase empty to store the credentials in plaintext.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		credentials.ChangePassphrase(common.DefaultCredentialStore, readNewPassphrase(true))
+	},
+}
+
+var credentialsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export saved profiles to an encrypted file",
+	Run: func(cmd *cobra.Command, args []string) {
+		names, _ := cmd.Flags().GetStringSlice("profile")
+
+		credentials.Export(names, cmd.Flag("output").Value.String(), readNewPassphrase(false))
+	},
+}
+
+var credentialsImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import profiles from an exported file",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		overwrite, _ := cmd.Flags().GetBool("overwrite")
+
+		credentials.Import(args[0], common.NewCredentialStore(readSecret("Export passphrase: ")), overwrite)
+	},
+}
+
+// readNewPassphrase prompts for a new passphrase twice
+//
+// allowEmpty: whether an empty passphrase (no encryption) is accepted
+func readNewPassphrase(allowEmpty bool) *common.CredentialStore {
+	passphrase := readSecret("New passphrase: ")
+	if passphrase == "" && allowEmpty {
+		return nil
+	} else if passphrase == "" {
+		log.Println("the passphrase cannot be empty")
+		os.Exit(1)
+	}
+
+	if readSecret("Confirm passphrase: ") != passphrase {
+		log.Println("the passphrases do not match")
+		os.Exit(1)
+	}
+
+	return common.NewCredentialStore(passphrase)
+}
+
+func init() {
+	rootCmd.AddCommand(credentialsCmd)
+	credentialsCmd.AddCommand(credentialsPassphraseCmd)
+	credentialsCmd.AddCommand(credentialsExportCmd)
+	credentialsCmd.AddCommand(credentialsImportCmd)
+
+	credentialsExportCmd.Flags().StringSliceP("profile", "p", []string{}, "The profiles to export (comma-separated list). Exports every profile by default")
+	credentialsExportCmd.Flags().StringP("output", "o", "", "The file to write the export to")
+	credentialsExportCmd.MarkFlagRequired("output")
+
+	credentialsImportCmd.Flags().Bool("overwrite", false, "Replace existing profiles with the same name")
+}
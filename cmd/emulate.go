@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"blink-liveview-websocket/emulate"
+
+	"github.com/spf13/cobra"
+)
+
+var emulateCmd = &cobra.Command{
+	Use:   "emulate",
+	Short: "Run a local Blink backend for offline development",
+	Long: `This command runs a fake Blink backend. It serves the OAuth, tier_info, homescreen,
+liveview and command endpoints, and a TLS liveview server that validates the client
+handshake and keep-alives before streaming an MPEG-TS.
+
+Point the other commands at it with the global --rest-url, --oauth-url and --liveview-port
+flags. The exact flags are printed on startup.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := emulate.EmulatorOptions{
+			Region:           cmd.Flag("region").Value.String(),
+			Email:            cmd.Flag("email").Value.String(),
+			Password:         cmd.Flag("password").Value.String(),
+			VerificationCode: cmd.Flag("code").Value.String(),
+		}
+		opts.AccountId, _ = cmd.Flags().GetInt("account-id")
+		opts.SessionDuration, _ = cmd.Flags().GetDuration("session-duration")
+		opts.FrameInterval, _ = cmd.Flags().GetDuration("frame-interval")
+
+		emulate.Run(cmd.Flag("address").Value.String(), cmd.Flag("stream-address").Value.String(), cmd.Flag("source").Value.String(), opts)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(emulateCmd)
+
+	emulateCmd.Flags().StringP("address", "a", "127.0.0.1:8082", "HTTP address of the OAuth and REST API")
+	emulateCmd.Flags().String("stream-address", "127.0.0.1:8443", "TLS address of the liveview stream server")
+	emulateCmd.Flags().StringP("source", "s", "testsrc", "The stream to send: testsrc (ffmpeg test pattern), blank (no media) or the path of an MPEG-TS file to loop")
+	emulateCmd.Flags().Duration("frame-interval", 0, "The pause between two video frames. Defaults to "+emulate.FILE_FRAME_INTERVAL.String()+" for blank and file sources, which are not real-time")
+	emulateCmd.Flags().Duration("session-duration", 0, "How long a liveview session lasts before it is marked as complete. 0 keeps it until stopped")
+	emulateCmd.Flags().StringP("region", "r", "emu", "The region (tier) reported for the account")
+	emulateCmd.Flags().Int("account-id", 1, "The account ID reported for the account")
+	emulateCmd.Flags().StringP("email", "e", "", "The only email address accepted at login. Empty accepts any credentials")
+	emulateCmd.Flags().String("password", "", "The password accepted with --email")
+	emulateCmd.Flags().String("code", "", "Require this verification code after the password")
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"blink-liveview-websocket/common"
 	"blink-liveview-websocket/liveview"
 
 	"github.com/spf13/cobra"
@@ -15,34 +16,37 @@ without the need for logging in or utilizing the WebSocket server.
 You can use this command if you already have all of the connection credentials. 
 If you do not have all of the required information, use the account command instead.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		accountId, _ := cmd.Flags().GetInt("account-id")
 		networkId, _ := cmd.Flags().GetInt("network-id")
 		cameraId, _ := cmd.Flags().GetInt("camera-id")
 
-		liveview.Run(
-			cmd.Flag("region").Value.String(),
-			cmd.Flag("token").Value.String(),
-			cmd.Flag("device-type").Value.String(),
-			accountId,
-			networkId,
-			cameraId,
-		)
+		var renewal *common.RenewalOptions
+		if continuous, _ := cmd.Flags().GetBool("continuous"); continuous {
+			renewal = &common.RenewalOptions{}
+			renewal.MaxRenewals, _ = cmd.Flags().GetInt("max-renewals")
+			renewal.Gap, _ = cmd.Flags().GetDuration("renewal-gap")
+			renewal.MaxDuration, _ = cmd.Flags().GetDuration("max-duration")
+		}
+
+		redact, _ := cmd.Flags().GetBool("redact")
+
+		liveview.Run(newClient(cmd), cmd.Flag("device-type").Value.String(), networkId, cameraId, renewal, cmd.Flag("capture").Value.String(), redact)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(liveviewCmd)
 
-	liveviewCmd.Flags().StringP("region", "r", "", "The Blink API subdomain/region to use (e.g. u011)")
-	liveviewCmd.MarkFlagRequired("region")
-	liveviewCmd.Flags().StringP("token", "t", "", "The Blink API token to use for authentication")
-	liveviewCmd.MarkFlagRequired("token")
+	addClientFlags(liveviewCmd)
 	liveviewCmd.Flags().StringP("device-type", "d", "", "The Blink device type (e.g. owl, doorbell, etc)")
 	liveviewCmd.MarkFlagRequired("device-type")
-	liveviewCmd.Flags().IntP("account-id", "a", 0, "The Blink account ID")
-	liveviewCmd.MarkFlagRequired("account-id")
 	liveviewCmd.Flags().IntP("network-id", "n", 0, "The Blink network ID")
 	liveviewCmd.MarkFlagRequired("network-id")
 	liveviewCmd.Flags().IntP("camera-id", "c", 0, "The Blink camera ID")
 	liveviewCmd.MarkFlagRequired("camera-id")
+	liveviewCmd.Flags().Bool("continuous", false, "Start a new liveview session whenever Blink ends the current one")
+	liveviewCmd.Flags().Int("max-renewals", 0, "The maximum number of session renewals in continuous mode. 0 renews until interrupted")
+	liveviewCmd.Flags().Duration("renewal-gap", common.LIVEVIEW_RENEWAL_GAP, "The pause between two sessions in continuous mode")
+	liveviewCmd.Flags().Duration("max-duration", 0, "The maximum total duration of the stream in continuous mode. 0 disables the limit")
+	liveviewCmd.Flags().String("capture", "", "Record the raw stream traffic to this file, to play it back with the replay command")
+	liveviewCmd.Flags().Bool("redact", false, "Replace the client and connection IDs in the capture, e.g. to attach it to a bug report")
 }
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"blink-liveview-websocket/localstorage"
+
+	"github.com/spf13/cobra"
+)
+
+var localStorageCmd = &cobra.Command{
+	Use:   "local-storage",
+	Short: "List and download clips stored on a Sync Module 2 USB drive",
+}
+
+var localStorageListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the clips on the sync module's local storage",
+	Run: func(cmd *cobra.Command, args []string) {
+		networkId, _ := cmd.Flags().GetInt("network-id")
+		syncModuleId, _ := cmd.Flags().GetInt("sync-module-id")
+
+		localstorage.List(newClient(cmd), networkId, syncModuleId)
+	},
+}
+
+var localStorageDownloadCmd = &cobra.Command{
+	Use:   "download",
+	Short: "Download a clip from the sync module's local storage",
+	Long: `The download command asks the sync module to upload a clip from its USB
+storage and downloads the resulting MP4. Clip IDs are listed by the list command.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		networkId, _ := cmd.Flags().GetInt("network-id")
+		syncModuleId, _ := cmd.Flags().GetInt("sync-module-id")
+
+		localstorage.Download(newClient(cmd), networkId, syncModuleId, cmd.Flag("clip-id").Value.String(), cmd.Flag("output").Value.String())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(localStorageCmd)
+	localStorageCmd.AddCommand(localStorageListCmd)
+	localStorageCmd.AddCommand(localStorageDownloadCmd)
+
+	for _, cmd := range []*cobra.Command{localStorageListCmd, localStorageDownloadCmd} {
+		addClientFlags(cmd)
+		cmd.Flags().IntP("network-id", "n", 0, "The Blink network ID")
+		cmd.MarkFlagRequired("network-id")
+		cmd.Flags().IntP("sync-module-id", "s", 0, "The Blink sync module ID")
+		cmd.MarkFlagRequired("sync-module-id")
+	}
+	localStorageDownloadCmd.Flags().String("clip-id", "", "The ID of the clip to download")
+	localStorageDownloadCmd.MarkFlagRequired("clip-id")
+	localStorageDownloadCmd.Flags().StringP("output", "o", "-", "The file to write the MP4 to. Use '-' for stdout")
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"blink-liveview-websocket/common"
+	"blink-liveview-websocket/login"
+
+	"github.com/spf13/cobra"
+)
+
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Log in to a Blink account and save the session to a profile",
+	Long: `The login command authenticates with your Blink account and saves the tokens,
+account ID, region and fingerprint to a named profile in your user config directory.
+The profile file is only readable by the current user.
+
+Use --profile with the other commands to reuse the session without copying the token around.
+The --proxy flag is saved to the profile, so the other commands connect through the same proxy.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		login.Run(common.NewBlinkClient("", "", 0), cmd.Flag("email").Value.String(), readPassword(), cmd.Flag("profile").Value.String(), cmd.Flag("proxy").Value.String())
+	},
+}
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove a saved login profile",
+	Run: func(cmd *cobra.Command, args []string) {
+		login.Logout(cmd.Flag("profile").Value.String())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(loginCmd)
+	rootCmd.AddCommand(logoutCmd)
+
+	loginCmd.Flags().StringP("email", "e", "", "Blink account email address")
+	loginCmd.MarkFlagRequired("email")
+	loginCmd.Flags().StringP("profile", "p", common.DEFAULT_PROFILE, "The name of the profile to save the session to")
+
+	logoutCmd.Flags().StringP("profile", "p", common.DEFAULT_PROFILE, "The name of the profile to remove")
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"blink-liveview-websocket/common"
+	"blink-liveview-websocket/monitor"
+
+	"github.com/spf13/cobra"
+)
+
+var monitorCmd = &cobra.Command{
+	Use:   "monitor",
+	Short: "Monitor device battery, signal, temperature and online state",
+	Long: `The monitor command polls the Blink homescreen on an interval and emits an
+event whenever the battery, Wi-Fi or sync module (LFR) signal, temperature or
+online state of a device changes. Events that breach a threshold are flagged as alerts.
+
+Events are written to stdout as JSON lines, or POSTed to a webhook. The latest
+state of every device can also be served from a /devices/health JSON endpoint.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		interval, _ := cmd.Flags().GetDuration("interval")
+		alertsOnly, _ := cmd.Flags().GetBool("alerts-only")
+		thresholds := common.HealthThresholds{}
+		thresholds.MinBattery, _ = cmd.Flags().GetInt("min-battery")
+		thresholds.MinWifi, _ = cmd.Flags().GetInt("min-wifi")
+		thresholds.MinLfr, _ = cmd.Flags().GetInt("min-lfr")
+		thresholds.MinTemperature, _ = cmd.Flags().GetInt("min-temp")
+		thresholds.MaxTemperature, _ = cmd.Flags().GetInt("max-temp")
+
+		monitor.Run(newClient(cmd), monitor.Options{
+			Interval:   interval,
+			Thresholds: thresholds,
+			Webhook:    cmd.Flag("webhook").Value.String(),
+			AlertsOnly: alertsOnly,
+			Address:    cmd.Flag("address").Value.String(),
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(monitorCmd)
+
+	defaults := common.DefaultHealthThresholds()
+	addClientFlags(monitorCmd)
+	monitorCmd.Flags().Duration("interval", common.HEALTH_POLL_INTERVAL, "The interval between homescreen polls")
+	monitorCmd.Flags().Int("min-battery", defaults.MinBattery, "Alert when the battery drops below this many bars (0 disables)")
+	monitorCmd.Flags().Int("min-wifi", defaults.MinWifi, "Alert when the Wi-Fi signal drops below this many bars (0 disables)")
+	monitorCmd.Flags().Int("min-lfr", defaults.MinLfr, "Alert when the sync module signal drops below this many bars (0 disables)")
+	monitorCmd.Flags().Int("min-temp", defaults.MinTemperature, "Alert when the temperature drops below this value (°F)")
+	monitorCmd.Flags().Int("max-temp", defaults.MaxTemperature, "Alert when the temperature rises above this value (°F)")
+	monitorCmd.Flags().String("webhook", "", "POST events to this URL instead of writing them to stdout")
+	monitorCmd.Flags().Bool("alerts-only", false, "Only emit events that breach a threshold")
+	monitorCmd.Flags().String("address", "", "Serve the latest device state on /devices/health at this address (e.g. localhost:8081)")
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"blink-liveview-websocket/motion"
+
+	"github.com/spf13/cobra"
+)
+
+var motionCmd = &cobra.Command{
+	Use:       "motion <on|off>",
+	Short:     "Enable or disable motion detection on a single camera",
+	ValidArgs: []string{"on", "off"},
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Long: `The motion command enables or disables motion detection on a single
+camera, Mini (owl) or doorbell. The command waits for Blink to confirm the new state.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		networkId, _ := cmd.Flags().GetInt("network-id")
+		cameraId, _ := cmd.Flags().GetInt("camera-id")
+
+		motion.Run(newClient(cmd), cmd.Flag("device-type").Value.String(), networkId, cameraId, args[0] == "on")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(motionCmd)
+
+	addClientFlags(motionCmd)
+	motionCmd.Flags().StringP("device-type", "d", "", "The Blink device type (e.g. camera, owl, doorbell)")
+	motionCmd.MarkFlagRequired("device-type")
+	motionCmd.Flags().IntP("network-id", "n", 0, "The Blink network ID")
+	motionCmd.MarkFlagRequired("network-id")
+	motionCmd.Flags().IntP("camera-id", "c", 0, "The Blink camera ID")
+	motionCmd.MarkFlagRequired("camera-id")
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"blink-liveview-websocket/network"
+
+	"github.com/spf13/cobra"
+)
+
+var armCmd = &cobra.Command{
+	Use:   "arm",
+	Short: "Arm a Blink network (system)",
+	Long: `The arm command arms a Blink network, enabling motion detection for the
+cameras on it. The command waits for Blink to confirm the new state.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		networkId, _ := cmd.Flags().GetInt("network-id")
+		network.Run(newClient(cmd), networkId, true)
+	},
+}
+
+var disarmCmd = &cobra.Command{
+	Use:   "disarm",
+	Short: "Disarm a Blink network (system)",
+	Long: `The disarm command disarms a Blink network, disabling motion detection for the
+cameras on it. The command waits for Blink to confirm the new state.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		networkId, _ := cmd.Flags().GetInt("network-id")
+		network.Run(newClient(cmd), networkId, false)
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{armCmd, disarmCmd} {
+		rootCmd.AddCommand(c)
+
+		addClientFlags(c)
+		c.Flags().IntP("network-id", "n", 0, "The Blink network ID")
+		c.MarkFlagRequired("network-id")
+	}
+}
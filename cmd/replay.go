@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"blink-liveview-websocket/replay"
+
+	"github.com/spf13/cobra"
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <capture>",
+	Short: "Play back a liveview capture recorded with liveview --capture",
+	Long: `This command feeds the stream server traffic recorded in a capture through the
+same demuxer as a live stream, and plays the video with ffplay at the original
+or an accelerated timing.
+
+Use --redact-to to write a copy of the capture without the client and connection
+IDs instead, e.g. to attach it to a bug report. To serve a capture to WebSocket
+clients, use server --replay.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if output := cmd.Flag("redact-to").Value.String(); output != "" {
+			replay.Redact(args[0], output)
+			return
+		}
+
+		speed, _ := cmd.Flags().GetFloat64("speed")
+		replay.Run(args[0], speed)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+
+	replayCmd.Flags().Float64("speed", 1, "The playback speed relative to the original timing (e.g. 2 for twice as fast). 0 plays as fast as possible")
+	replayCmd.Flags().String("redact-to", "", "Write a copy of the capture with the client and connection IDs replaced to this file, instead of playing it")
+}
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"blink-liveview-websocket/common"
+	"log"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -8,6 +10,58 @@ import (
 
 var rootCmd = &cobra.Command{
 	Use: "blink-liveview-websocket",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Encrypt the saved credentials when a passphrase is available
+		if askPassphrase, _ := cmd.Flags().GetBool("ask-passphrase"); askPassphrase {
+			common.DefaultCredentialStore = common.NewCredentialStore(readSecret("Credential passphrase: "))
+		} else {
+			common.DefaultCredentialStore = common.CredentialStoreFromEnv()
+		}
+
+		// Point every client at alternative hosts (e.g. the emulate command)
+		if restUrl, _ := cmd.Flags().GetString("rest-url"); restUrl != "" {
+			common.REST_URL = restUrl
+		}
+		if oauthUrl, _ := cmd.Flags().GetString("oauth-url"); oauthUrl != "" {
+			common.OAUTH_URL = oauthUrl
+		}
+		if liveviewPort, _ := cmd.Flags().GetString("liveview-port"); liveviewPort != "" {
+			common.LIVEVIEW_PORT = liveviewPort
+		}
+
+		// Route the Blink API and the liveview stream through a proxy
+		// Commands using a login profile resolve it again with the profile's proxy (see loadProfile)
+		rawProxy, _ := cmd.Flags().GetString("proxy")
+		proxy, err := common.ResolveProxy(rawProxy, "")
+		if err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		common.DefaultProxy = proxy
+
+		// Verify the liveview stream server
+		streamTLS := &common.StreamTLS{}
+		streamTLS.Insecure, _ = cmd.Flags().GetBool("insecure")
+		if caBundle, _ := cmd.Flags().GetString("ca-bundle"); caBundle != "" {
+			pool, err := common.LoadCABundle(caBundle)
+			if err != nil {
+				log.Println(err)
+				os.Exit(1)
+			}
+			streamTLS.RootCAs = pool
+		}
+		streamTLS.Pins, _ = cmd.Flags().GetStringSlice("pin")
+		for _, pin := range streamTLS.Pins {
+			if err := common.ValidatePin(pin); err != nil {
+				log.Println(err)
+				os.Exit(1)
+			}
+		}
+		if streamTLS.Insecure && len(streamTLS.Pins) == 0 {
+			log.Println("WARNING: --insecure disables stream certificate verification. Camera feeds can be intercepted")
+		}
+		common.DefaultStreamTLS = streamTLS
+	},
 }
 
 func Execute() {
@@ -16,3 +70,14 @@ func Execute() {
 		os.Exit(1)
 	}
 }
+
+func init() {
+	rootCmd.PersistentFlags().Bool("ask-passphrase", false, "Prompt for the passphrase used to encrypt saved credentials (instead of "+common.CREDENTIAL_PASSPHRASE_ENV+")")
+	rootCmd.PersistentFlags().String("rest-url", "", "Override the Blink REST API base URL (e.g. http://127.0.0.1:8082 for the emulate command)")
+	rootCmd.PersistentFlags().String("oauth-url", "", "Override the Blink OAuth base URL")
+	rootCmd.PersistentFlags().String("liveview-port", common.LIVEVIEW_PORT, "The port accepted in liveview connection strings")
+	rootCmd.PersistentFlags().String("proxy", "", "Proxy for the Blink API and the liveview stream (http://, https://, socks5://, socks5h:// or \""+common.PROXY_DIRECT+"\"). Defaults to "+common.PROXY_ENV+", then the login profile, then HTTPS_PROXY")
+	rootCmd.PersistentFlags().Bool("insecure", false, "Skip the liveview stream certificate verification (pins are still enforced)")
+	rootCmd.PersistentFlags().String("ca-bundle", "", "PEM file of the CA certificates to verify the liveview stream with (instead of the system roots)")
+	rootCmd.PersistentFlags().StringSlice("pin", nil, "Accepted liveview stream public key as sha256/<base64> (repeatable)")
+}
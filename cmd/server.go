@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"blink-liveview-websocket/common"
+	"blink-liveview-websocket/handlers"
 	"blink-liveview-websocket/server"
+	"os"
 
 	"github.com/spf13/cobra"
 )
@@ -14,7 +17,15 @@ Each client acts as an independent subscriber to the streams,
 which means many clients can connect and view their own liveview stream simultaneously.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		origins, _ := cmd.Flags().GetStringSlice("origins")
-		server.Run(cmd.Flag("address").Value.String(), cmd.Flag("env").Value.String(), origins)
+
+		var profile *common.Profile
+		if name := cmd.Flag("profile").Value.String(); name != "" {
+			profile = loadProfile(name)
+		}
+
+		replaySpeed, _ := cmd.Flags().GetFloat64("replay-speed")
+
+		server.Run(cmd.Flag("address").Value.String(), cmd.Flag("env").Value.String(), origins, profile, os.Getenv(handlers.PROFILE_SECRET_ENV), cmd.Flag("replay").Value.String(), replaySpeed)
 	},
 }
 
@@ -23,5 +34,8 @@ func init() {
 
 	serverCmd.Flags().StringP("address", "a", "localhost:8080", "HTTP server address")
 	serverCmd.Flags().StringP("env", "e", "production", "Environment (development, production)")
+	serverCmd.Flags().StringP("profile", "p", "", "A saved login profile to use for clients that do not send credentials. Requires "+handlers.PROFILE_SECRET_ENV+" unless bound to a loopback address")
+	serverCmd.Flags().String("replay", "", "Play back this capture (see liveview --capture) to every liveview session instead of streaming from Blink")
+	serverCmd.Flags().Float64("replay-speed", 1, "The playback speed of --replay relative to the original timing. 0 plays as fast as possible")
 	serverCmd.Flags().StringSliceP("origins", "o", []string{}, "Allowed websocket origins (comma-separated list). Use '*' to allow all origins.")
 }
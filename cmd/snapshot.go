@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"blink-liveview-websocket/snapshot"
+
+	"github.com/spf13/cobra"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Capture a still image (thumbnail) from a camera",
+	Long: `The snapshot command asks the camera to capture a new thumbnail and
+downloads the resulting JPEG. This is much faster than starting a liveview
+stream, and uses less of the camera's battery.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		networkId, _ := cmd.Flags().GetInt("network-id")
+		cameraId, _ := cmd.Flags().GetInt("camera-id")
+
+		snapshot.Run(newClient(cmd), cmd.Flag("device-type").Value.String(), networkId, cameraId, cmd.Flag("output").Value.String())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+
+	addClientFlags(snapshotCmd)
+	snapshotCmd.Flags().StringP("device-type", "d", "", "The Blink device type (e.g. camera, owl, doorbell)")
+	snapshotCmd.MarkFlagRequired("device-type")
+	snapshotCmd.Flags().IntP("network-id", "n", 0, "The Blink network ID")
+	snapshotCmd.MarkFlagRequired("network-id")
+	snapshotCmd.Flags().IntP("camera-id", "c", 0, "The Blink camera ID")
+	snapshotCmd.MarkFlagRequired("camera-id")
+	snapshotCmd.Flags().StringP("output", "o", "-", "The file to write the JPEG to. Use '-' for stdout")
+}
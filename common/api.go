@@ -4,8 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"time"
 )
@@ -23,11 +23,153 @@ func SetRequestHeaders(req *http.Request, token string) {
 	req.Header.Set("content-type", "application/json; charset=UTF-8")
 }
 
+// COMMAND_POLL_INTERVAL is the default interval between command status polls in seconds
+var COMMAND_POLL_INTERVAL = 1
+
+// COMMAND_TIMEOUT is the maximum time to wait for a command to complete
+var COMMAND_TIMEOUT = 60 * time.Second
+
+// ErrCommandComplete is returned when Blink marks a command that is being kept alive as complete
+var ErrCommandComplete = errors.New("command marked as complete. Cannot poll further")
+
 type CommandResponse struct {
-	Code       int    `json:"code"`
-	StatusCode int    `json:"status_code"`
-	Message    string `json:"message"`
-	Complete   bool   `json:"complete"`
+	Code          int    `json:"code"`
+	StatusCode    int    `json:"status_code"`
+	Message       string `json:"message"`
+	Complete      bool   `json:"complete"`
+	Status        int    `json:"status"`
+	StatusMessage string `json:"status_msg"`
+}
+
+type CommandStartResponse struct {
+	Id        int    `json:"id"`
+	NetworkId int    `json:"network_id"`
+	Command   string `json:"command"`
+	State     string `json:"state"`
+}
+
+// commandUrl returns the URL used to poll a network command
+//
+// networkId: the network the command was issued on
+//
+// commandId: the ID of the command
+//
+// Example: commandUrl(1234, 5678) = "https://rest-u011.immedia-semi.com/network/1234/command/5678"
+func (c *BlinkClient) commandUrl(networkId int, commandId int) string {
+	return fmt.Sprintf("%s/network/%d/command/%d", c.ApiUrl(), networkId, commandId)
+}
+
+// startCommand sends a request that starts an asynchronous command on the network
+//
+// ctx: the context to use for the request
+//
+// url: the URL to send the command request to
+//
+// body: the JSON body to send. Optional
+//
+// Example: startCommand(ctx, "https://example.com", nil) = &CommandStartResponse{Id: 5678}, nil
+func (c *BlinkClient) startCommand(ctx context.Context, url string, body any) (*CommandStartResponse, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := c.newRequest(ctx, "POST", url, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	var result CommandStartResponse
+	if err := decodeBody(resp, &result); err != nil {
+		return nil, err
+	}
+	if result.Id == 0 {
+		return nil, fmt.Errorf("command response did not include a command ID")
+	}
+
+	return &result, nil
+}
+
+// WaitForCommand polls the command URL until Blink marks the command as complete.
+// Gives up after COMMAND_TIMEOUT or when the context is cancelled.
+//
+// ctx: the context to use for the command
+//
+// url: the URL to poll
+//
+// pollInterval: the interval to wait between polls in seconds. Defaults to COMMAND_POLL_INTERVAL when not positive
+//
+// Example: client.WaitForCommand(ctx, "https://example.com", 1) = &CommandResponse{Complete: true}, nil
+func (c *BlinkClient) WaitForCommand(ctx context.Context, url string, pollInterval int) (*CommandResponse, error) {
+	if pollInterval <= 0 {
+		pollInterval = COMMAND_POLL_INTERVAL
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, COMMAND_TIMEOUT)
+	defer cancel()
+
+	ticker := time.NewTicker(time.Duration(pollInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("command did not complete: %w", ctx.Err())
+		case <-ticker.C:
+			result, err := c.pollCommandOnce(ctx, url)
+			if err != nil {
+				return nil, err
+			}
+
+			if result.Complete {
+				return result, nil
+			}
+		}
+	}
+}
+
+// pollCommandOnce fetches the current state of a command
+//
+// ctx: the context to use for the request
+//
+// url: the command URL to poll
+//
+// Example: pollCommandOnce(ctx, "https://example.com") = &CommandResponse{Complete: false}, nil
+func (c *BlinkClient) pollCommandOnce(ctx context.Context, url string) (*CommandResponse, error) {
+	req, err := c.newRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.doRetry(req)
+	if err != nil {
+		return nil, fmt.Errorf("error polling command: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error polling command. %w", newAPIError(resp))
+	}
+
+	var result CommandResponse
+	if err := decodeBody(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
 }
 
 // PollCommand will repeatedly poll the command URL with the provided token
@@ -42,6 +184,23 @@ type CommandResponse struct {
 //
 // Example: go PollCommand("https://example.com", "api-token-here", 10)
 func PollCommand(ctx context.Context, url string, token string, pollInterval int) error {
+	return NewBlinkClient(token, "", 0).PollCommand(ctx, url, pollInterval)
+}
+
+// PollCommand will repeatedly poll the command URL until the context is cancelled
+//
+// ctx: the context to use for the command
+//
+// url: the URL to poll
+//
+// pollInterval: the interval to wait between polls in seconds. Defaults to COMMAND_POLL_INTERVAL when not positive
+//
+// Example: go client.PollCommand(ctx, "https://example.com", 10)
+func (c *BlinkClient) PollCommand(ctx context.Context, url string, pollInterval int) error {
+	if pollInterval <= 0 {
+		pollInterval = COMMAND_POLL_INTERVAL
+	}
+
 	ticker := time.NewTicker(time.Duration(pollInterval) * time.Second)
 	defer ticker.Stop()
 
@@ -50,33 +209,13 @@ func PollCommand(ctx context.Context, url string, token string, pollInterval int
 		case <-ctx.Done():
 			return nil
 		case <-ticker.C:
-			req, err := http.NewRequest("GET", url, nil)
-			if err != nil {
-				return err
-			}
-
-			SetRequestHeaders(req, token)
-
-			client := &http.Client{Timeout: time.Second * 10}
-			resp, err := client.Do(req)
-			if resp.StatusCode != http.StatusOK || err != nil {
-				return fmt.Errorf("error polling command. HTTP Status Code %d", resp.StatusCode)
-			}
-			defer resp.Body.Close()
-
-			body, err := io.ReadAll(resp.Body)
-			result := CommandResponse{}
-			if err != nil {
-				return err
-			}
-
-			err = json.Unmarshal(body, &result)
+			result, err := c.pollCommandOnce(ctx, url)
 			if err != nil {
 				return err
 			}
 
 			if result.Complete {
-				return fmt.Errorf("command marked as complete. Cannot poll further")
+				return ErrCommandComplete
 			}
 		}
 	}
@@ -94,38 +233,46 @@ type LiveviewResponse struct {
 
 // BeginLiveview starts the liveview intention for the camera
 //
+// ctx: the context to use for the request
+//
 // url: the URL to send the liveview request to
 //
 // token: the token to use for the request
 //
-// Example: BeginLiveview("https://example.com", "api-token-here")
-func BeginLiveview(url string, token string) (*LiveviewResponse, error) {
+// Example: BeginLiveview(ctx, "https://example.com", "api-token-here")
+func BeginLiveview(ctx context.Context, url string, token string) (*LiveviewResponse, error) {
+	return NewBlinkClient(token, "", 0).BeginLiveview(ctx, url)
+}
+
+// BeginLiveview starts the liveview intention for the camera
+//
+// ctx: the context to use for the request
+//
+// url: the URL to send the liveview request to
+//
+// Example: client.BeginLiveview(ctx, "https://example.com")
+func (c *BlinkClient) BeginLiveview(ctx context.Context, url string) (*LiveviewResponse, error) {
 	jsonBody, _ := json.Marshal(&LiveviewInput{
 		Intent: "liveview",
 	})
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
+	req, err := c.newRequest(ctx, "POST", url, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, err
 	}
 
-	SetRequestHeaders(req, token)
-
-	client := &http.Client{Timeout: time.Second * 10}
-	resp, err := client.Do(req)
-	if resp.StatusCode != http.StatusOK || err != nil {
-		return nil, fmt.Errorf("error starting liveview. HTTP Status Code %d", resp.StatusCode)
+	resp, err := c.doRetry(req)
+	if err != nil {
+		return nil, fmt.Errorf("error starting liveview: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error starting liveview. %w", newAPIError(resp))
 	}
 
 	var result LiveviewResponse
-	err = json.Unmarshal(body, &result)
-	if err != nil {
+	if err := decodeBody(resp, &result); err != nil {
 		return nil, err
 	}
 
@@ -134,39 +281,52 @@ func BeginLiveview(url string, token string) (*LiveviewResponse, error) {
 
 // StopCommand marks the command (liveview) as completed
 //
+// ctx: the context to use for the request
+//
 // url: the URL to send the liveview request to
 //
 // token: the token to use for the request
 //
-// Example: StopCommand("https://example.com", "api-token-here")
-func StopCommand(url string, token string) error {
-	req, err := http.NewRequest("POST", url, nil)
+// Example: StopCommand(ctx, "https://example.com", "api-token-here")
+func StopCommand(ctx context.Context, url string, token string) error {
+	return NewBlinkClient(token, "", 0).StopCommand(ctx, url)
+}
+
+// StopCommand marks the command (liveview) as completed
+//
+// ctx: the context to use for the request
+//
+// url: the URL to send the liveview request to
+//
+// Example: client.StopCommand(ctx, "https://example.com")
+func (c *BlinkClient) StopCommand(ctx context.Context, url string) error {
+	req, err := c.newRequest(ctx, "POST", url, nil)
 	if err != nil {
 		return err
 	}
 
-	SetRequestHeaders(req, token)
-
-	client := &http.Client{Timeout: time.Second * 10}
-	resp, err := client.Do(req)
-	if resp.StatusCode != http.StatusOK || err != nil {
-		return fmt.Errorf("cannot stop command. HTTP Status Code %d", resp.StatusCode)
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("cannot stop command: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cannot stop command. %w", newAPIError(resp))
 	}
 
 	var result CommandResponse
-	err = json.Unmarshal(body, &result)
-	if err != nil {
+	if err := decodeBody(resp, &result); err != nil {
 		return err
 	}
 
 	if result.Code != 902 {
-		return fmt.Errorf("cannot stop command. API Code %d with message %s", result.Code, result.Message)
+		return fmt.Errorf("cannot stop command. %w", &APIError{
+			StatusCode: resp.StatusCode,
+			Code:       result.Code,
+			Message:    result.Message,
+			Url:        url,
+		})
 	}
 
 	return nil
@@ -197,6 +357,25 @@ type LoginResponse struct {
 
 // Login logs in to the Blink API using the provided credentials
 //
+// ctx: the context to use for the request
+//
+// email: the email address to use for login
+//
+// password: the password to use for login
+//
+// code: the 2FA code to use for login (if applicable)
+//
+// fp: the fingerprint to use for login
+//
+// Example: Login(ctx, "x", "y", "123456", fingerprint)
+func Login(ctx context.Context, email string, password string, code string, fp *Fingerprint) (*LoginResponse, error) {
+	return NewBlinkClient("", "", 0).Login(ctx, email, password, code, fp)
+}
+
+// Login logs in to the Blink API using the provided credentials
+//
+// ctx: the context to use for the request
+//
 // email: the email address to use for login
 //
 // password: the password to use for login
@@ -205,8 +384,8 @@ type LoginResponse struct {
 //
 // fp: the fingerprint to use for login
 //
-// Example: Login("x", "y", "123456", fingerprint)
-func Login(email string, password string, code string, fp *Fingerprint) (*LoginResponse, error) {
+// Example: client.Login(ctx, "x", "y", "123456", fingerprint)
+func (c *BlinkClient) Login(ctx context.Context, email string, password string, code string, fp *Fingerprint) (*LoginResponse, error) {
 	jsonBody, _ := json.Marshal(&LoginBody{
 		Username:   email,
 		Password:   password,
@@ -216,7 +395,7 @@ func Login(email string, password string, code string, fp *Fingerprint) (*LoginR
 		ClientName: "blink-liveview-middleware",
 	})
 
-	req, err := http.NewRequest("POST", "https://api.oauth.blink.com/oauth/token", bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.OAuthBaseUrl()+"/oauth/token", bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, err
 	}
@@ -226,22 +405,27 @@ func Login(email string, password string, code string, fp *Fingerprint) (*LoginR
 	if code != "" {
 		req.Header.Set("2fa-code", code)
 	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
 
-	client := &http.Client{Timeout: time.Second * 10}
-	resp, err := client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %v", err)
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	// A 412 indicates that two-step verification is required
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPreconditionFailed:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, fmt.Errorf("%w. %w", ErrLoginRejected, newAPIError(resp))
+	default:
+		return nil, newAPIError(resp)
 	}
 
 	var result LoginResponse
-	err = json.Unmarshal(body, &result)
-	if err != nil {
+	if err := decodeBody(resp, &result); err != nil {
 		return nil, err
 	}
 
@@ -253,83 +437,142 @@ type TierInfoResponse struct {
 	AccountId int    `json:"account_id"`
 }
 
-func GetTierInfo(token string) (*TierInfoResponse, error) {
-	req, err := http.NewRequest("GET", GetApiUrl("")+"/api/v1/users/tier_info", nil)
+// GetTierInfo retrieves the account ID and region (tier) for the token
+//
+// ctx: the context to use for the request
+//
+// token: the API token to use for the request
+//
+// Example: GetTierInfo(ctx, "api-token-here")
+func GetTierInfo(ctx context.Context, token string) (*TierInfoResponse, error) {
+	return NewBlinkClient(token, "", 0).GetTierInfo(ctx)
+}
+
+// GetTierInfo retrieves the account ID and region (tier) for the client's token
+//
+// ctx: the context to use for the request
+//
+// Example: client.GetTierInfo(ctx) = &TierInfoResponse{Tier: "u011", AccountId: 1234}, nil
+func (c *BlinkClient) GetTierInfo(ctx context.Context) (*TierInfoResponse, error) {
+	req, err := c.newRequest(ctx, "GET", c.ApiUrl()+"/api/v1/users/tier_info", nil)
 	if err != nil {
 		return nil, err
 	}
 
-	SetRequestHeaders(req, token)
-
-	client := &http.Client{Timeout: time.Second * 10}
-	resp, err := client.Do(req)
-	if resp.StatusCode != http.StatusOK || err != nil {
-		return nil, fmt.Errorf("HTTP Status Code %d", resp.StatusCode)
+	resp, err := c.doRetry(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
 	}
 
 	var result TierInfoResponse
-	err = json.Unmarshal(body, &result)
-	if err != nil {
+	if err := decodeBody(resp, &result); err != nil {
 		return nil, err
 	}
 
 	return &result, nil
 }
 
+type DeviceSignals struct {
+	// The sync module (LFR) signal strength in bars
+	Lfr int `json:"lfr"`
+	// The Wi-Fi signal strength in bars
+	Wifi int `json:"wifi"`
+	// The battery level in bars
+	Battery int `json:"battery"`
+	// The temperature in degrees Fahrenheit. Nil for devices without a sensor
+	Temperature *int `json:"temp"`
+}
+
 type BaseCameraDevice struct {
-	Id        int    `json:"id"`
-	Name      string `json:"name"`
-	Type      string `json:"type"`
-	NetworkId int    `json:"network_id"`
+	Id              int           `json:"id"`
+	Name            string        `json:"name"`
+	Type            string        `json:"type"`
+	NetworkId       int           `json:"network_id"`
+	Serial          string        `json:"serial"`
+	Enabled         bool          `json:"enabled"`
+	Status          string        `json:"status"`
+	Battery         string        `json:"battery"`
+	Thumbnail       string        `json:"thumbnail"`
+	FirmwareVersion string        `json:"fw_version"`
+	Signals         DeviceSignals `json:"signals"`
+	UpdatedAt       string        `json:"updated_at"`
 }
 
 type BaseNetwork struct {
-	Id   int    `json:"id"`
-	Name string `json:"name"`
+	Id    int    `json:"id"`
+	Name  string `json:"name"`
+	Armed bool   `json:"armed"`
+}
+
+type SyncModule struct {
+	Id              int    `json:"id"`
+	Name            string `json:"name"`
+	Type            string `json:"type"`
+	NetworkId       int    `json:"network_id"`
+	Serial          string `json:"serial"`
+	Status          string `json:"status"`
+	FirmwareVersion string `json:"fw_version"`
+	UpdatedAt       string `json:"updated_at"`
 }
 
 type HomescreenResponse struct {
-	Networks  []BaseNetwork      `json:"networks"`
-	Owls      []BaseCameraDevice `json:"owls"`
-	Doorbells []BaseCameraDevice `json:"doorbells"`
+	Networks    []BaseNetwork      `json:"networks"`
+	SyncModules []SyncModule       `json:"sync_modules"`
+	Cameras     []BaseCameraDevice `json:"cameras"`
+	Owls        []BaseCameraDevice `json:"owls"`
+	Doorbells   []BaseCameraDevice `json:"doorbells"`
+}
+
+// HomescreenUrl returns the homescreen URL for the client's account
+//
+// Example: HomescreenUrl() = "https://rest-u011.immedia-semi.com/api/v4/accounts/1234/homescreen"
+func (c *BlinkClient) HomescreenUrl() string {
+	return fmt.Sprintf("%s/api/v4/accounts/%d/homescreen", c.ApiUrl(), c.AccountId)
 }
 
 // Homescreen retrieves the homescreen information from the Blink API
 //
+// ctx: the context to use for the request
+//
 // url: the URL to send the homescreen request to
 //
 // token: the API token to use for the request
 //
-// Example: Homescreen("https://example.com", "api-token-here")
-func Homescreen(url string, token string) (*HomescreenResponse, error) {
-	req, err := http.NewRequest("GET", url, nil)
+// Example: Homescreen(ctx, "https://example.com", "api-token-here")
+func Homescreen(ctx context.Context, url string, token string) (*HomescreenResponse, error) {
+	return NewBlinkClient(token, "", 0).Homescreen(ctx, url)
+}
+
+// Homescreen retrieves the homescreen information from the Blink API
+//
+// ctx: the context to use for the request
+//
+// url: the URL to send the homescreen request to
+//
+// Example: client.Homescreen(ctx, "https://example.com")
+func (c *BlinkClient) Homescreen(ctx context.Context, url string) (*HomescreenResponse, error) {
+	req, err := c.newRequest(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	SetRequestHeaders(req, token)
-
-	client := &http.Client{Timeout: time.Second * 10}
-	resp, err := client.Do(req)
-	if resp.StatusCode != http.StatusOK || err != nil {
-		return nil, fmt.Errorf("HTTP Status Code %d", resp.StatusCode)
+	resp, err := c.doRetry(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
 	}
 
 	var result HomescreenResponse
-	err = json.Unmarshal(body, &result)
-	if err != nil {
+	if err := decodeBody(resp, &result); err != nil {
 		return nil, err
 	}
 
@@ -4,7 +4,7 @@ import (
 	"blink-liveview-websocket/common"
 	"bytes"
 	"context"
-	"fmt"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -107,6 +107,20 @@ func TestPollCommandComplete(t *testing.T) {
 	assert.Equal(t, "command marked as complete. Cannot poll further", err.Error())
 }
 
+func TestPollCommandDefaultInterval(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"code": 200, "status_code": 200, "message": "OK", "complete": true}`))
+	}))
+	defer mockServer.Close()
+
+	for _, pollInterval := range []int{0, -1} {
+		err := common.PollCommand(context.Background(), mockServer.URL, "xyz-auth-token", pollInterval)
+
+		assert.Equal(t, "command marked as complete. Cannot poll further", err.Error())
+	}
+}
+
 func TestBeginLiveviewNominal(t *testing.T) {
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -114,7 +128,7 @@ func TestBeginLiveviewNominal(t *testing.T) {
 	}))
 	defer mockServer.Close()
 
-	resp, err := common.BeginLiveview(mockServer.URL, "xyz-auth-token")
+	resp, err := common.BeginLiveview(context.Background(), mockServer.URL, "xyz-auth-token")
 
 	assert.Equal(t, nil, err)
 	assert.Equal(t, 75888, resp.CommandId)
@@ -128,12 +142,31 @@ func TestBeginLiveviewHttpError(t *testing.T) {
 	}))
 	defer mockServer.Close()
 
-	resp, err := common.BeginLiveview(mockServer.URL, "xyz-auth-token")
+	resp, err := common.BeginLiveview(context.Background(), mockServer.URL, "xyz-auth-token")
 
 	assert.Equal(t, nil, resp)
 	assert.Equal(t, "error starting liveview. HTTP Status Code 500", err.Error())
 }
 
+func TestBeginLiveviewCancel(t *testing.T) {
+	release := make(chan struct{})
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer mockServer.Close()
+	defer close(release)
+
+	mockCtx, mockCancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, mockCancel)
+
+	start := time.Now()
+	resp, err := common.BeginLiveview(mockCtx, mockServer.URL, "xyz-auth-token")
+
+	assert.Equal(t, nil, resp)
+	assert.Equal(t, true, errors.Is(err, context.Canceled))
+	assert.Equal(t, true, time.Since(start) < 5*time.Second)
+}
+
 func TestStopCommandNominal(t *testing.T) {
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -141,7 +174,7 @@ func TestStopCommandNominal(t *testing.T) {
 	}))
 	defer mockServer.Close()
 
-	err := common.StopCommand(mockServer.URL, "xyz-auth-token")
+	err := common.StopCommand(context.Background(), mockServer.URL, "xyz-auth-token")
 
 	assert.Equal(t, nil, err)
 }
@@ -153,7 +186,7 @@ func TestStopCommandAPIError(t *testing.T) {
 	}))
 	defer mockServer.Close()
 
-	err := common.StopCommand(mockServer.URL, "xyz-auth-token")
+	err := common.StopCommand(context.Background(), mockServer.URL, "xyz-auth-token")
 
 	assert.Equal(t, "cannot stop command. API Code 800 with message Some error", err.Error())
 }
@@ -164,7 +197,7 @@ func TestStopCommandHttpError(t *testing.T) {
 	}))
 	defer mockServer.Close()
 
-	err := common.StopCommand(mockServer.URL, "xyz-auth-token")
+	err := common.StopCommand(context.Background(), mockServer.URL, "xyz-auth-token")
 
 	assert.Equal(t, "cannot stop command. HTTP Status Code 500", err.Error())
 }
@@ -175,6 +208,22 @@ func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
 	return f(req)
 }
 
+func TestStopCommandDeadline(t *testing.T) {
+	release := make(chan struct{})
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer mockServer.Close()
+	defer close(release)
+
+	mockCtx, mockCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer mockCancel()
+
+	err := common.StopCommand(mockCtx, mockServer.URL, "xyz-auth-token")
+
+	assert.Equal(t, true, errors.Is(err, context.DeadlineExceeded))
+}
+
 func TestLoginNominal(t *testing.T) {
 	orig := http.DefaultTransport
 	t.Cleanup(func() { http.DefaultTransport = orig })
@@ -194,7 +243,7 @@ func TestLoginNominal(t *testing.T) {
 	})
 
 	fp := common.Fingerprint{Value: "mock-fingerprint", New: false}
-	resp, err := common.Login("mock-email", "mock-password", "", &fp)
+	resp, err := common.Login(context.Background(), "mock-email", "mock-password", "", &fp)
 
 	assert.Equal(t, nil, err)
 	assert.Equal(t, "xyz-auth-token", resp.AccessToken)
@@ -224,7 +273,7 @@ func TestLoginSetsHeaders(t *testing.T) {
 	})
 
 	fp := common.Fingerprint{Value: "mock-fingerprint", New: false}
-	_, err := common.Login("email", "password", "", &fp)
+	_, err := common.Login(context.Background(), "email", "password", "", &fp)
 	assert.Equal(t, nil, err)
 }
 
@@ -245,7 +294,7 @@ func TestLoginWithTwoFactorHeader(t *testing.T) {
 	})
 
 	fp := common.Fingerprint{Value: "mock-fingerprint", New: false}
-	_, err := common.Login("email", "password", "123456", &fp)
+	_, err := common.Login(context.Background(), "email", "password", "123456", &fp)
 	assert.Equal(t, nil, err)
 }
 
@@ -253,15 +302,17 @@ func TestLoginHttpClientError(t *testing.T) {
 	orig := http.DefaultTransport
 	t.Cleanup(func() { http.DefaultTransport = orig })
 
+	errDial := errors.New("dial error")
 	http.DefaultTransport = roundTripperFunc(func(r *http.Request) (*http.Response, error) {
-		return nil, fmt.Errorf("dial error")
+		return nil, errDial
 	})
 
 	fp := common.Fingerprint{Value: "mock-fingerprint", New: false}
-	resp, err := common.Login("mock-email", "mock-password", "", &fp)
+	resp, err := common.Login(context.Background(), "mock-email", "mock-password", "", &fp)
 
 	assert.Equal(t, (*common.LoginResponse)(nil), resp)
 	assert.Equal(t, true, strings.HasPrefix(err.Error(), "HTTP request failed:"))
+	assert.Equal(t, true, errors.Is(err, errDial))
 }
 
 // Helper to avoid importing io for NopCloser in each test
@@ -281,7 +332,7 @@ func ioNopCloser(b *bytes.Buffer) nopCloser { return nopCloser{b} }
 // 		Value: "mock-fingerprint",
 // 		New:   false,
 // 	}
-// 	resp, err := common.Login("mock-email", "mock-password", "", &fp)
+// 	resp, err := common.Login(context.Background(), "mock-email", "mock-password", "", &fp)
 
 // 	assert.Equal(t, nil, resp)
 // 	assert.Equal(t, "HTTP Status Code 500", err.Error())
@@ -294,7 +345,7 @@ func TestHomescreenNominal(t *testing.T) {
 	}))
 	defer mockServer.Close()
 
-	resp, err := common.Homescreen(mockServer.URL, "xyz-auth-token")
+	resp, err := common.Homescreen(context.Background(), mockServer.URL, "xyz-auth-token")
 
 	assert.Equal(t, nil, err)
 	assert.Equal(t, 0, len(resp.Networks))
@@ -308,8 +359,45 @@ func TestHomescreenHttpError(t *testing.T) {
 	}))
 	defer mockServer.Close()
 
-	resp, err := common.Homescreen(mockServer.URL, "xyz-auth-token")
+	resp, err := common.Homescreen(context.Background(), mockServer.URL, "xyz-auth-token")
 
 	assert.Equal(t, nil, resp)
 	assert.Equal(t, "HTTP Status Code 500", err.Error())
 }
+
+func TestHomescreenDevices(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"networks": [{"id": 1, "name": "Home", "armed": true}],
+			"sync_modules": [{"id": 10, "name": "Sync", "network_id": 1, "serial": "G8T1", "status": "online", "fw_version": "4.4.8", "type": "sm2"}],
+			"cameras": [{"id": 3, "name": "Backyard", "type": "catalina", "network_id": 1, "enabled": true, "status": "done", "battery": "ok", "thumbnail": "/media/thumb", "fw_version": "10.61", "signals": {"lfr": 5, "wifi": 4, "battery": 3, "temp": 68}}],
+			"owls": [{"id": 4, "name": "Mini", "type": "owl", "network_id": 1, "enabled": false, "status": "online"}],
+			"doorbells": []
+		}`))
+	}))
+	defer mockServer.Close()
+
+	resp, err := common.Homescreen(context.Background(), mockServer.URL, "xyz-auth-token")
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, resp.Networks[0].Armed)
+	assert.Equal(t, common.SyncModule{
+		Id:              10,
+		Name:            "Sync",
+		Type:            "sm2",
+		NetworkId:       1,
+		Serial:          "G8T1",
+		Status:          "online",
+		FirmwareVersion: "4.4.8",
+	}, resp.SyncModules[0])
+	assert.Equal(t, "Backyard", resp.Cameras[0].Name)
+	assert.Equal(t, true, resp.Cameras[0].Enabled)
+	assert.Equal(t, "ok", resp.Cameras[0].Battery)
+	assert.Equal(t, "/media/thumb", resp.Cameras[0].Thumbnail)
+	assert.Equal(t, "10.61", resp.Cameras[0].FirmwareVersion)
+	assert.Equal(t, 68, *resp.Cameras[0].Signals.Temperature)
+	assert.Equal(t, common.DeviceSignals{Lfr: 5, Wifi: 4, Battery: 3, Temperature: resp.Cameras[0].Signals.Temperature}, resp.Cameras[0].Signals)
+	assert.Equal(t, false, resp.Owls[0].Enabled)
+	assert.Equal(t, "online", resp.Owls[0].Status)
+}
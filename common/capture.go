@@ -0,0 +1,457 @@
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// CAPTURE_MAGIC identifies a liveview capture file
+const CAPTURE_MAGIC = "BLVCAP"
+
+// CAPTURE_VERSION is the version of the capture format written by CaptureWriter
+const CAPTURE_VERSION = 1
+
+// The capture file header: the magic, the version and the start time in Unix nanoseconds
+const captureHeaderSize = len(CAPTURE_MAGIC) + 1 + 8
+
+// Every record starts with the event, the offset from the start in nanoseconds and the data length
+const captureRecordHeaderSize = 1 + 8 + 4
+
+// CAPTURE_REDACTED_CLIENT_ID replaces the client ID in redacted captures
+const CAPTURE_REDACTED_CLIENT_ID = 1
+
+// CAPTURE_REDACTED_CHAR replaces every character of the connection ID in redacted captures
+const CAPTURE_REDACTED_CHAR = 'X'
+
+// ErrInvalidCapture is returned when a file is not a liveview capture or is corrupted
+var ErrInvalidCapture = errors.New("invalid liveview capture")
+
+type CaptureEvent byte
+
+const (
+	// A new connection to the stream server. The data is the remote address
+	CaptureEventConnect CaptureEvent = iota
+	// Bytes received from the stream server
+	CaptureEventRead
+	// Bytes sent to the stream server (handshake, keep-alives)
+	CaptureEventWrite
+)
+
+// String returns the name of the capture event
+//
+// Example: CaptureEventRead.String() = "read"
+func (e CaptureEvent) String() string {
+	switch e {
+	case CaptureEventConnect:
+		return "connect"
+	case CaptureEventRead:
+		return "read"
+	case CaptureEventWrite:
+		return "write"
+	}
+
+	return "unknown"
+}
+
+type CaptureRecord struct {
+	// What happened on the connection
+	Event CaptureEvent
+	// The time since the start of the capture
+	Offset time.Duration
+	// The raw bytes read or written, or the remote address for a connect event
+	Data []byte
+}
+
+// CaptureWriter records the raw traffic of liveview connections, with timestamps, to a capture file.
+// Safe for concurrent use.
+type CaptureWriter struct {
+	// Replaces the client and connection IDs sent in the handshake. Must be set before the first record
+	Redact bool
+	// The time the capture started. Record offsets are relative to it
+	StartedAt time.Time
+
+	mu        sync.Mutex
+	writer    io.Writer
+	lastWrite []byte
+}
+
+// NewCaptureWriter writes the capture header and returns a writer for the records
+//
+// writer: the capture file
+//
+// startedAt: the start time of the capture, usually time.Now()
+//
+// Example: NewCaptureWriter(file, time.Now()) = &CaptureWriter{...}, nil
+func NewCaptureWriter(writer io.Writer, startedAt time.Time) (*CaptureWriter, error) {
+	header := make([]byte, captureHeaderSize)
+	copy(header, CAPTURE_MAGIC)
+	header[len(CAPTURE_MAGIC)] = CAPTURE_VERSION
+	binary.BigEndian.PutUint64(header[len(CAPTURE_MAGIC)+1:], uint64(startedAt.UnixNano()))
+
+	if _, err := writer.Write(header); err != nil {
+		return nil, fmt.Errorf("error writing capture header: %w", err)
+	}
+
+	return &CaptureWriter{StartedAt: startedAt, writer: writer}, nil
+}
+
+// Record writes an event that happened now
+//
+// event: the event type
+//
+// data: the bytes read or written. Not retained
+//
+// Example: capture.Record(CaptureEventWrite, FRAMES_KEEPALIVE) = nil
+func (c *CaptureWriter) Record(event CaptureEvent, data []byte) error {
+	return c.WriteRecord(CaptureRecord{Event: event, Offset: time.Since(c.StartedAt), Data: data})
+}
+
+// WriteRecord writes a record with its own offset (e.g. when copying a capture)
+//
+// record: the record to write
+//
+// Example: capture.WriteRecord(CaptureRecord{Event: CaptureEventRead, Offset: time.Second, Data: data}) = nil
+func (c *CaptureWriter) WriteRecord(record CaptureRecord) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := record.Data
+	if record.Event == CaptureEventWrite {
+		data = c.redact(data)
+	}
+
+	header := make([]byte, captureRecordHeaderSize)
+	header[0] = byte(record.Event)
+	binary.BigEndian.PutUint64(header[1:9], uint64(record.Offset))
+	binary.BigEndian.PutUint32(header[9:13], uint32(len(data)))
+
+	if _, err := c.writer.Write(append(header, data...)); err != nil {
+		return fmt.Errorf("error writing capture: %w", err)
+	}
+
+	return nil
+}
+
+// redact replaces the handshake frames that carry the client and connection IDs.
+// TCPStream writes each handshake frame separately, so the IDs are identified by the frame written before them.
+func (c *CaptureWriter) redact(data []byte) []byte {
+	previous := c.lastWrite
+	c.lastWrite = bytes.Clone(data)
+	if !c.Redact {
+		return data
+	}
+
+	handshake := NewHandshake("", 0).Frames()
+	switch {
+	case bytes.Equal(previous, handshake[0]) && len(data) == handshakeClientIdSize:
+		return binary.BigEndian.AppendUint32(nil, CAPTURE_REDACTED_CLIENT_ID)
+	case len(previous) == handshakeConnectionSize && bytes.Equal(previous[0:2], handshake[2][0:2]):
+		return bytes.Repeat([]byte{CAPTURE_REDACTED_CHAR}, len(data))
+	}
+
+	return data
+}
+
+// Conn wraps the connection so that every read and write is recorded
+//
+// conn: the connection to the stream server
+//
+// Example: stream := capture.Conn(conn)
+func (c *CaptureWriter) Conn(conn net.Conn) net.Conn {
+	return &captureConn{Conn: conn, capture: c}
+}
+
+// captureConn records the traffic of a connection. A failed capture fails the connection, so captures are never silently incomplete
+type captureConn struct {
+	net.Conn
+	capture *CaptureWriter
+}
+
+// Read reads from the connection and records the bytes read
+func (c *captureConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		if captureErr := c.capture.Record(CaptureEventRead, p[:n]); captureErr != nil {
+			return n, captureErr
+		}
+	}
+
+	return n, err
+}
+
+// Write writes to the connection and records the bytes written
+func (c *captureConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	if n > 0 {
+		if captureErr := c.capture.Record(CaptureEventWrite, p[:n]); captureErr != nil {
+			return n, captureErr
+		}
+	}
+
+	return n, err
+}
+
+// CaptureReader reads the records of a capture file
+type CaptureReader struct {
+	// The time the capture started
+	StartedAt time.Time
+
+	reader *bufio.Reader
+}
+
+// NewCaptureReader reads the capture header and returns a reader for the records
+//
+// reader: the capture file
+//
+// Example: NewCaptureReader(file) = &CaptureReader{...}, nil
+func NewCaptureReader(reader io.Reader) (*CaptureReader, error) {
+	bufReader := bufio.NewReaderSize(reader, 64*1024)
+
+	header := make([]byte, captureHeaderSize)
+	if _, err := io.ReadFull(bufReader, header); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCapture, err)
+	}
+	if string(header[:len(CAPTURE_MAGIC)]) != CAPTURE_MAGIC {
+		return nil, fmt.Errorf("%w: unexpected file type", ErrInvalidCapture)
+	}
+	if version := header[len(CAPTURE_MAGIC)]; version != CAPTURE_VERSION {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidCapture, version)
+	}
+
+	startedAt := int64(binary.BigEndian.Uint64(header[len(CAPTURE_MAGIC)+1:]))
+
+	return &CaptureReader{StartedAt: time.Unix(0, startedAt), reader: bufReader}, nil
+}
+
+// Next reads the next record.
+// Returns io.EOF at the end of the capture, and io.ErrUnexpectedEOF when the capture ends mid-record.
+//
+// Example: capture.Next() = &CaptureRecord{Event: CaptureEventRead, ...}, nil
+func (c *CaptureReader) Next() (*CaptureRecord, error) {
+	header := make([]byte, captureRecordHeaderSize)
+	if _, err := io.ReadFull(c.reader, header); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[9:13])
+	if length > uint32(FRAME_MAX_PAYLOAD) {
+		return nil, fmt.Errorf("%w: record of %d bytes", ErrInvalidCapture, length)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, data); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return &CaptureRecord{
+		Event:  CaptureEvent(header[0]),
+		Offset: time.Duration(binary.BigEndian.Uint64(header[1:9])),
+		Data:   data,
+	}, nil
+}
+
+// RedactCapture copies a capture, replacing the client and connection IDs sent in every handshake
+//
+// reader: the capture to redact
+//
+// writer: the redacted copy
+//
+// Example: RedactCapture(input, output) = nil
+func RedactCapture(reader io.Reader, writer io.Writer) error {
+	input, err := NewCaptureReader(reader)
+	if err != nil {
+		return err
+	}
+
+	output, err := NewCaptureWriter(writer, input.StartedAt)
+	if err != nil {
+		return err
+	}
+	output.Redact = true
+
+	for {
+		record, err := input.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("error reading capture: %w", err)
+		}
+
+		if err := output.WriteRecord(*record); err != nil {
+			return err
+		}
+	}
+}
+
+type ReplayOptions struct {
+	// The playback speed relative to the original timing (e.g. 2 for twice as fast). 0 replays as fast as possible
+	Speed float64
+	// Called with every packet read from the capture, including video. Optional.
+	// Must not block, and must copy the payload to keep it
+	OnPacket func(*StreamPacket)
+}
+
+// ReplayCaptureFile replays a capture file like ReplayCapture
+//
+// ctx: the context to use for the replay, including cancellation
+//
+// path: the path of the capture file
+//
+// writer: the pipe to write the video stream to
+//
+// opts: the replay options
+//
+// Example: ReplayCaptureFile(ctx, "session.blvcap", pipe, ReplayOptions{Speed: 1}) = nil
+func ReplayCaptureFile(ctx context.Context, path string, writer io.Writer, opts ReplayOptions) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening capture: %w", err)
+	}
+	defer file.Close()
+
+	return ReplayCapture(ctx, file, writer, opts)
+}
+
+// ReplayCapture feeds the bytes received in a capture through the demuxer, as if they came from the stream server.
+// Only the MPEG-TS video payload is written to the writer, like TCPStream.
+// Every connection in the capture is replayed in turn, unframed when it does not use the frame format.
+// Returns nil at the end of the capture or when the context is cancelled.
+//
+// ctx: the context to use for the replay, including cancellation
+//
+// reader: the capture
+//
+// writer: the pipe to write the video stream to
+//
+// opts: the replay options
+//
+// Example: ReplayCapture(ctx, file, pipe, ReplayOptions{Speed: 4}) = nil
+func ReplayCapture(ctx context.Context, reader io.Reader, writer io.Writer, opts ReplayOptions) error {
+	capture, err := NewCaptureReader(reader)
+	if err != nil {
+		return err
+	}
+
+	replay := &replayReader{ctx: ctx, capture: capture, speed: opts.Speed, start: time.Now()}
+	for !replay.finished {
+		demuxer := NewDemuxer(replay)
+		for {
+			packet, err := demuxer.Next()
+			if ctx.Err() != nil {
+				return nil
+			} else if errors.Is(err, ErrUnknownFraming) {
+				// Replay the connection unframed, like TCPStream
+				if _, err := io.Copy(writer, demuxer.Raw()); err != nil && ctx.Err() == nil {
+					return fmt.Errorf("error replaying capture: %w", err)
+				}
+				break
+			} else if errors.Is(err, io.EOF) {
+				break
+			} else if errors.Is(err, io.ErrUnexpectedEOF) {
+				// The original connection (or the capture) was closed mid-packet. Continue with the next one
+				log.Println("Replayed connection closed mid-packet")
+				break
+			} else if err != nil {
+				return fmt.Errorf("error replaying capture: %w", err)
+			}
+
+			if opts.OnPacket != nil {
+				opts.OnPacket(packet)
+			}
+
+			if packet.Kind == PacketKindVideo {
+				if _, err := writer.Write(packet.Payload); err != nil {
+					return fmt.Errorf("error writing to writer: %w", err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// replayReader returns the bytes received in a capture at their original (scaled) time.
+// Returns io.EOF at the end of each connection and at the end of the capture.
+type replayReader struct {
+	ctx     context.Context
+	capture *CaptureReader
+	speed   float64
+	start   time.Time
+	pending []byte
+	// Set once bytes of the current connection were returned
+	connected bool
+	// Set at the end of the capture
+	finished bool
+}
+
+// Read returns the bytes of the next read records, waiting until they are due
+func (r *replayReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.finished {
+			return 0, io.EOF
+		}
+
+		record, err := r.capture.Next()
+		if errors.Is(err, io.EOF) {
+			r.finished = true
+			return 0, io.EOF
+		} else if err != nil {
+			return 0, err
+		}
+
+		switch record.Event {
+		case CaptureEventConnect:
+			if r.connected {
+				// End the demuxer of the previous connection
+				r.connected = false
+				return 0, io.EOF
+			}
+		case CaptureEventRead:
+			if err := r.wait(record.Offset); err != nil {
+				return 0, err
+			}
+			r.pending = record.Data
+			r.connected = true
+		}
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+
+	return n, nil
+}
+
+// wait sleeps until the record offset is due at the replay speed
+func (r *replayReader) wait(offset time.Duration) error {
+	if r.speed <= 0 {
+		return r.ctx.Err()
+	}
+
+	delay := time.Until(r.start.Add(time.Duration(float64(offset) / r.speed)))
+	if delay <= 0 {
+		return r.ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-r.ctx.Done():
+		return r.ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,224 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+// readCapture reads every record of a capture
+func readCapture(t *testing.T, data []byte) []*common.CaptureRecord {
+	reader, err := common.NewCaptureReader(bytes.NewReader(data))
+	assert.Equal(t, err, nil)
+
+	var records []*common.CaptureRecord
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return records
+		}
+		assert.Equal(t, err, nil)
+		records = append(records, record)
+	}
+}
+
+// writeCapture writes the records to a new capture
+func writeCapture(t *testing.T, redact bool, records ...common.CaptureRecord) []byte {
+	var buf bytes.Buffer
+	capture, err := common.NewCaptureWriter(&buf, time.Unix(1700000000, 0))
+	assert.Equal(t, err, nil)
+	capture.Redact = redact
+
+	for _, record := range records {
+		assert.Equal(t, capture.WriteRecord(record), nil)
+	}
+
+	return buf.Bytes()
+}
+
+// handshakeRecords returns the handshake writes of a connection, one record per frame like TCPStream
+func handshakeRecords(connectionId string, clientId int) []common.CaptureRecord {
+	records := []common.CaptureRecord{{Event: common.CaptureEventConnect, Data: []byte("127.0.0.1:443")}}
+	for _, frame := range common.GetTCPAuthFrames(connectionId, clientId) {
+		records = append(records, common.CaptureRecord{Event: common.CaptureEventWrite, Data: frame})
+	}
+
+	return records
+}
+
+func TestCaptureRoundTrip(t *testing.T) {
+	data := writeCapture(t, false,
+		common.CaptureRecord{Event: common.CaptureEventConnect, Data: []byte("127.0.0.1:443")},
+		common.CaptureRecord{Event: common.CaptureEventWrite, Offset: time.Millisecond, Data: common.FRAMES_KEEPALIVE},
+		common.CaptureRecord{Event: common.CaptureEventRead, Offset: time.Second, Data: []byte("video")},
+	)
+
+	reader, err := common.NewCaptureReader(bytes.NewReader(data))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, reader.StartedAt.Equal(time.Unix(1700000000, 0)), true)
+
+	records := readCapture(t, data)
+	assert.Equal(t, len(records), 3)
+	assert.Equal(t, records[1], &common.CaptureRecord{Event: common.CaptureEventWrite, Offset: time.Millisecond, Data: common.FRAMES_KEEPALIVE})
+	assert.Equal(t, records[2].Event.String(), "read")
+	assert.Equal(t, records[2].Offset, time.Second)
+	assert.Equal(t, string(records[2].Data), "video")
+}
+
+func TestCaptureInvalid(t *testing.T) {
+	_, err := common.NewCaptureReader(bytes.NewReader([]byte("GIF89a-not-a-capture")))
+	assert.Equal(t, errors.Is(err, common.ErrInvalidCapture), true)
+
+	_, err = common.NewCaptureReader(bytes.NewReader(nil))
+	assert.Equal(t, errors.Is(err, common.ErrInvalidCapture), true)
+
+	data := writeCapture(t, false, common.CaptureRecord{Event: common.CaptureEventRead, Data: []byte("video")})
+	reader, err := common.NewCaptureReader(bytes.NewReader(data[:len(data)-1]))
+	assert.Equal(t, err, nil)
+	_, err = reader.Next()
+	assert.Equal(t, errors.Is(err, io.ErrUnexpectedEOF), true)
+}
+
+func TestCaptureRedact(t *testing.T) {
+	records := append(handshakeRecords("Cy5gwipn7Bui8L7z", 918202),
+		common.CaptureRecord{Event: common.CaptureEventWrite, Data: common.FRAMES_KEEPALIVE},
+		common.CaptureRecord{Event: common.CaptureEventRead, Data: []byte("Cy5gwipn7Bui8L7z")},
+	)
+	expected := common.GetTCPAuthFrames("XXXXXXXXXXXXXXXX", common.CAPTURE_REDACTED_CLIENT_ID)
+
+	redacted := readCapture(t, writeCapture(t, true, records...))
+	for i, frame := range expected {
+		assert.Equal(t, redacted[i+1].Data, frame)
+	}
+	assert.Equal(t, redacted[6].Data, common.FRAMES_KEEPALIVE)
+	// Only the handshake is redacted, the bytes received are kept as is
+	assert.Equal(t, string(redacted[7].Data), "Cy5gwipn7Bui8L7z")
+
+	// Redacting an existing capture gives the same result
+	var buf bytes.Buffer
+	assert.Equal(t, common.RedactCapture(bytes.NewReader(writeCapture(t, false, records...)), &buf), nil)
+	assert.Equal(t, buf.Bytes(), writeCapture(t, true, records...))
+}
+
+func TestTCPStreamCapture(t *testing.T) {
+	listener, cert := newTLSListener(t)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if _, err := common.ReadHandshake(conn); err != nil {
+			return
+		}
+
+		conn.Write(frame(common.FRAME_TYPE_VIDEO, 1, []byte("first")))
+		conn.Write(frame(common.FRAME_TYPE_METADATA, 2, []byte("{}")))
+		conn.Write(frame(common.FRAME_TYPE_VIDEO, 3, []byte("second")))
+	}()
+
+	var buf bytes.Buffer
+	capture, err := common.NewCaptureWriter(&buf, time.Now())
+	assert.Equal(t, err, nil)
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	var video bytes.Buffer
+	err = common.TCPStreamWithOptions(context.Background(), common.ConnectionDetails{Host: host, Port: port, ClientId: 1, ConnectionId: "Cy5gwipn7Bui8L7z"}, &video, common.StreamOptions{Capture: capture, TLS: trustedBy(cert)})
+	assert.Equal(t, true, errors.Is(err, io.EOF))
+	assert.Equal(t, "firstsecond", video.String())
+
+	records := readCapture(t, buf.Bytes())
+	assert.Equal(t, records[0].Event, common.CaptureEventConnect)
+	assert.Equal(t, string(records[0].Data), listener.Addr().String())
+
+	var written, read []byte
+	for _, record := range records[1:] {
+		if record.Event == common.CaptureEventWrite {
+			written = append(written, record.Data...)
+		} else {
+			read = append(read, record.Data...)
+		}
+	}
+	assert.Equal(t, written, bytes.Join(common.GetTCPAuthFrames("Cy5gwipn7Bui8L7z", 1), nil))
+	assert.Equal(t, len(read), 3*common.FRAME_HEADER_SIZE+len("first{}second"))
+
+	// The capture replays to the same video
+	var replayed bytes.Buffer
+	var kinds []common.PacketKind
+	err = common.ReplayCapture(context.Background(), bytes.NewReader(buf.Bytes()), &replayed, common.ReplayOptions{
+		OnPacket: func(packet *common.StreamPacket) {
+			kinds = append(kinds, packet.Kind)
+		},
+	})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, "firstsecond", replayed.String())
+	assert.Equal(t, []common.PacketKind{common.PacketKindVideo, common.PacketKindControl, common.PacketKindVideo}, kinds)
+}
+
+func TestReplayCaptureConnections(t *testing.T) {
+	records := handshakeRecords("Cy5gwipn7Bui8L7z", 1)
+	// The first connection is closed mid-packet
+	records = append(records,
+		common.CaptureRecord{Event: common.CaptureEventRead, Data: frame(common.FRAME_TYPE_VIDEO, 1, []byte("first"))},
+		common.CaptureRecord{Event: common.CaptureEventRead, Data: frame(common.FRAME_TYPE_VIDEO, 2, []byte("lost"))[:5]},
+	)
+	records = append(records, handshakeRecords("Kz3oepxv5Jcq6T5h", 2)...)
+	records = append(records,
+		common.CaptureRecord{Event: common.CaptureEventRead, Offset: 200 * time.Millisecond, Data: frame(common.FRAME_TYPE_VIDEO, 1, []byte("second"))},
+	)
+	data := writeCapture(t, false, records...)
+
+	var video bytes.Buffer
+	start := time.Now()
+	assert.Equal(t, common.ReplayCapture(context.Background(), bytes.NewReader(data), &video, common.ReplayOptions{Speed: 1}), nil)
+	assert.Equal(t, "firstsecond", video.String())
+	assert.Equal(t, time.Since(start) >= 200*time.Millisecond, true)
+
+	// Accelerated replays keep the order but not the timing
+	video.Reset()
+	start = time.Now()
+	assert.Equal(t, common.ReplayCapture(context.Background(), bytes.NewReader(data), &video, common.ReplayOptions{Speed: 0}), nil)
+	assert.Equal(t, "firstsecond", video.String())
+	assert.Equal(t, time.Since(start) < 200*time.Millisecond, true)
+}
+
+func TestReplayCaptureUnframed(t *testing.T) {
+	// A connection captured from a server that does not frame the stream, then a framed one
+	records := handshakeRecords("Cy5gwipn7Bui8L7z", 1)
+	records = append(records,
+		common.CaptureRecord{Event: common.CaptureEventRead, Data: tsPackets(2)},
+		common.CaptureRecord{Event: common.CaptureEventWrite, Data: common.FRAMES_KEEPALIVE},
+		common.CaptureRecord{Event: common.CaptureEventRead, Data: tsPackets(3)},
+	)
+	records = append(records, handshakeRecords("Kz3oepxv5Jcq6T5h", 2)...)
+	records = append(records,
+		common.CaptureRecord{Event: common.CaptureEventRead, Data: frame(common.FRAME_TYPE_VIDEO, 1, []byte("framed"))},
+	)
+	data := writeCapture(t, false, records...)
+
+	var video bytes.Buffer
+	assert.Equal(t, common.ReplayCapture(context.Background(), bytes.NewReader(data), &video, common.ReplayOptions{}), nil)
+	assert.Equal(t, append(tsPackets(5), "framed"...), video.Bytes())
+}
+
+func TestReplayCaptureCancel(t *testing.T) {
+	data := writeCapture(t, false,
+		common.CaptureRecord{Event: common.CaptureEventRead, Offset: time.Hour, Data: frame(common.FRAME_TYPE_VIDEO, 1, []byte("late"))},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var video bytes.Buffer
+	assert.Equal(t, common.ReplayCapture(ctx, bytes.NewReader(data), &video, common.ReplayOptions{Speed: 1}), nil)
+	assert.Equal(t, video.Len(), 0)
+}
@@ -0,0 +1,212 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OAUTH_URL is the default base URL for the Blink OAuth service
+var OAUTH_URL = "https://api.oauth.blink.com"
+
+// REST_URL overrides the REST API base URL of every client that does not set RestUrl (e.g. a local emulator). Optional
+var REST_URL = ""
+
+// HTTP_TIMEOUT is the default timeout for each request made by a BlinkClient
+var HTTP_TIMEOUT = 10 * time.Second
+
+// DOWNLOAD_TIMEOUT is the timeout for media downloads (e.g. thumbnails, clips)
+var DOWNLOAD_TIMEOUT = 10 * time.Minute
+
+type BlinkClient struct {
+	// API auth token to use for the API requests
+	Token string
+	// Region to use for the API URL (e.g. "u011")
+	Region string
+	// Account ID that the client is operating on
+	AccountId int
+	// The HTTP client used for every request. Shared to allow connection reuse
+	HTTPClient *http.Client
+	// The User-Agent header to send with each request. Optional
+	UserAgent string
+	// Overrides the REST API base URL derived from the region. Optional
+	RestUrl string
+	// Overrides the OAuth base URL. Optional
+	OAuthUrl string
+	// Supplies and refreshes the access token. Takes precedence over Token when set. Optional
+	Tokens *TokenSource
+	// The retry policy for idempotent requests and liveview starts. Nil disables retries
+	Retry *RetryPolicy
+}
+
+// NewBlinkClient creates a BlinkClient with a shared HTTP client and the default Blink hosts
+//
+// token: the API token to use for the requests. May be empty before login
+//
+// region: the Blink API region (e.g. "u011"). May be empty before login
+//
+// accountId: the Blink account ID. May be zero before login
+//
+// Example: NewBlinkClient("api-token-here", "u011", 1234)
+func NewBlinkClient(token string, region string, accountId int) *BlinkClient {
+	return &BlinkClient{
+		Token:      token,
+		Region:     region,
+		AccountId:  accountId,
+		HTTPClient: &http.Client{Timeout: HTTP_TIMEOUT, Transport: DefaultTransport},
+		Retry:      DefaultRetryPolicy(),
+	}
+}
+
+// ApiUrl returns the REST API base URL for the client
+// The RestUrl override is used when set, then REST_URL, otherwise the URL is derived from the region
+//
+// Example: ApiUrl() = "https://rest-u011.immedia-semi.com"
+func (c *BlinkClient) ApiUrl() string {
+	if c.RestUrl != "" {
+		return strings.TrimSuffix(c.RestUrl, "/")
+	}
+	if REST_URL != "" {
+		return strings.TrimSuffix(REST_URL, "/")
+	}
+
+	return GetApiUrl(c.Region)
+}
+
+// OAuthBaseUrl returns the OAuth base URL for the client
+// The OAuthUrl override is used when set, otherwise OAUTH_URL is returned
+//
+// Example: OAuthBaseUrl() = "https://api.oauth.blink.com"
+func (c *BlinkClient) OAuthBaseUrl() string {
+	if c.OAuthUrl != "" {
+		return strings.TrimSuffix(c.OAuthUrl, "/")
+	}
+
+	return OAUTH_URL
+}
+
+// withTimeout returns a shallow copy of the client whose HTTP client uses the timeout.
+// The transport is shared, so connections are still reused.
+//
+// timeout: the timeout for each request
+//
+// Example: withTimeout(time.Minute).do(req)
+func (c *BlinkClient) withTimeout(timeout time.Duration) *BlinkClient {
+	clone := *c
+	httpClient := http.Client{Transport: DefaultTransport}
+	if c.HTTPClient != nil {
+		httpClient = *c.HTTPClient
+	}
+	httpClient.Timeout = timeout
+	clone.HTTPClient = &httpClient
+
+	return &clone
+}
+
+// newRequest builds a request with the default Blink API headers applied
+//
+// ctx: the context of the request. Cancelling it aborts the request, including any token refresh
+//
+// method: the HTTP method to use
+//
+// url: the full URL to send the request to
+//
+// body: the request body. Optional
+//
+// Example: newRequest(ctx, "GET", "https://example.com", nil)
+func (c *BlinkClient) newRequest(ctx context.Context, method string, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := c.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	SetRequestHeaders(req, token)
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	return req, nil
+}
+
+// token returns the access token to use for the next request
+//
+// Example: token(ctx) = "api-token-here", nil
+func (c *BlinkClient) token(ctx context.Context) (string, error) {
+	if c.Tokens == nil {
+		return c.Token, nil
+	}
+
+	return c.Tokens.Token(ctx, c)
+}
+
+// do sends the request using the client's HTTP client.
+// Authenticated requests rejected with a 401 are retried once after refreshing the token.
+// The token is only refreshed if it has not already been refreshed since the request was sent.
+//
+// req: the request to send
+//
+// Example: do(req) = &http.Response{}, nil
+func (c *BlinkClient) do(req *http.Request) (*http.Response, error) {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: HTTP_TIMEOUT, Transport: DefaultTransport}
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if c.Tokens == nil || req.Header.Get("Authorization") == "" {
+		return resp, nil
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	apiErr := newAPIError(resp)
+	resp.Body.Close()
+	rejected := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if err := c.Tokens.RefreshIfCurrent(req.Context(), c, rejected); err != nil {
+		return nil, fmt.Errorf("%w: %w", apiErr, err)
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+
+	token, err := c.token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	retry.Header.Set("Authorization", "Bearer "+token)
+
+	return httpClient.Do(retry)
+}
+
+// decodeBody reads the response body and unmarshals it into the result
+//
+// resp: the response to read
+//
+// result: a pointer to the value to unmarshal into
+//
+// Example: decodeBody(resp, &LiveviewResponse{}) = nil
+func decodeBody(resp *http.Response, result any) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, result)
+}
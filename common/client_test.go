@@ -0,0 +1,116 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestNewBlinkClient(t *testing.T) {
+	client := common.NewBlinkClient("xyz-auth-token", "u011", 1234)
+
+	assert.Equal(t, "xyz-auth-token", client.Token)
+	assert.Equal(t, "u011", client.Region)
+	assert.Equal(t, 1234, client.AccountId)
+	assert.Equal(t, common.HTTP_TIMEOUT, client.HTTPClient.Timeout)
+}
+
+func TestBlinkClientApiUrlRegion(t *testing.T) {
+	client := common.NewBlinkClient("", "u014", 0)
+
+	assert.Equal(t, "https://rest-u014.immedia-semi.com", client.ApiUrl())
+}
+
+func TestBlinkClientApiUrlOverride(t *testing.T) {
+	client := common.NewBlinkClient("", "u014", 0)
+	client.RestUrl = "http://localhost:9000/"
+
+	assert.Equal(t, "http://localhost:9000", client.ApiUrl())
+}
+
+func TestBlinkClientOAuthBaseUrl(t *testing.T) {
+	client := common.NewBlinkClient("", "", 0)
+	assert.Equal(t, common.OAUTH_URL, client.OAuthBaseUrl())
+
+	client.OAuthUrl = "http://localhost:9001"
+	assert.Equal(t, "http://localhost:9001", client.OAuthBaseUrl())
+}
+
+func TestBlinkClientLoginOverride(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/oauth/token", r.URL.Path)
+		assert.Equal(t, "mock-agent", r.Header.Get("User-Agent"))
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"access_token":"xyz-auth-token","expires_in":3600}`))
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("", "", 0)
+	client.OAuthUrl = mockServer.URL
+	client.UserAgent = "mock-agent"
+
+	resp, err := client.Login(context.Background(), "email", "password", "", &common.Fingerprint{Value: "mock-fingerprint"})
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "xyz-auth-token", resp.AccessToken)
+}
+
+func TestBlinkClientGetTierInfoOverride(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/users/tier_info", r.URL.Path)
+		assert.Equal(t, "Bearer xyz-auth-token", r.Header.Get("Authorization"))
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"tier": "u011", "account_id": 1234}`))
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 0)
+	client.RestUrl = mockServer.URL
+
+	resp, err := client.GetTierInfo(context.Background())
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "u011", resp.Tier)
+	assert.Equal(t, 1234, resp.AccountId)
+}
+
+func TestBlinkClientSharedTransport(t *testing.T) {
+	var requests int
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"networks": []}`))
+	}))
+	defer mockServer.Close()
+
+	var trips int
+	client := common.NewBlinkClient("xyz-auth-token", "", 0)
+	client.HTTPClient = &http.Client{
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			trips++
+			return http.DefaultTransport.RoundTrip(r)
+		}),
+	}
+
+	client.Homescreen(context.Background(), mockServer.URL)
+	client.Homescreen(context.Background(), mockServer.URL)
+
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, 2, trips)
+}
+
+func TestBlinkClientHttpClientError(t *testing.T) {
+	client := common.NewBlinkClient("xyz-auth-token", "", 0)
+	client.RestUrl = "http://127.0.0.1:1"
+
+	resp, err := client.GetTierInfo(context.Background())
+
+	assert.Equal(t, (*common.TierInfoResponse)(nil), resp)
+	assert.NotEqual(t, nil, err)
+}
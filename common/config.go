@@ -0,0 +1,298 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+)
+
+// VIDEO_QUALITIES are the video quality presets accepted by every device family
+var VIDEO_QUALITIES = []string{"saver", "standard", "best"}
+
+// NIGHT_VISION_MODES are the night vision modes accepted by Minis and doorbells
+var NIGHT_VISION_MODES = []string{"off", "auto", "on"}
+
+// LED_STATES are the status LED states accepted by Minis and doorbells
+var LED_STATES = []string{"off", "on"}
+
+// DeviceConfig is the configuration of a single device family.
+// Unset (nil) fields are omitted from updates and left unchanged.
+type DeviceConfig interface {
+	// Validate reports the first setting that is out of range
+	Validate() error
+}
+
+// CameraConfig is the configuration of classic and XT cameras ("camera")
+type CameraConfig struct {
+	// Motion sensitivity, from 1 (least sensitive) to 9
+	MotionSensitivity *int `json:"motion_sensitivity,omitempty"`
+	// The clip length in seconds
+	VideoLength *int `json:"video_length,omitempty"`
+	// The retrigger time in seconds
+	AlertInterval *int `json:"alert_interval,omitempty"`
+	// The IR illuminator (night vision) mode. 0 = off, 1 = on, 2 = auto
+	IlluminatorEnable *int `json:"illuminator_enable,omitempty"`
+	// The IR illuminator intensity, from 1 to 7
+	IlluminatorIntensity *int `json:"illuminator_intensity,omitempty"`
+	// The video quality preset (saver, standard, best)
+	VideoQuality *string `json:"video_quality,omitempty"`
+	// Whether audio is recorded with clips
+	RecordAudioEnable *bool `json:"record_audio_enable,omitempty"`
+}
+
+// OwlConfig is the configuration of Mini cameras ("owl", "hawk")
+type OwlConfig struct {
+	// Motion sensitivity, from 1 (least sensitive) to 9
+	MotionSensitivity *int `json:"motion_sensitivity,omitempty"`
+	// The clip length in seconds
+	VideoLength *int `json:"video_length,omitempty"`
+	// The retrigger time in seconds
+	RetriggerTime *int `json:"retrigger_time,omitempty"`
+	// The night vision mode (off, auto, on)
+	NightVision *string `json:"night_vision,omitempty"`
+	// The video quality preset (saver, standard, best)
+	VideoQuality *string `json:"video_quality,omitempty"`
+	// Whether audio is recorded with clips
+	RecordAudioEnable *bool `json:"record_audio_enable,omitempty"`
+	// The status LED state (off, on)
+	LedState *string `json:"led_state,omitempty"`
+}
+
+// DoorbellConfig is the configuration of doorbells ("doorbell", "lotus")
+type DoorbellConfig struct {
+	// Motion sensitivity, from 1 (least sensitive) to 9
+	MotionSensitivity *int `json:"motion_sensitivity,omitempty"`
+	// The clip length in seconds
+	VideoLength *int `json:"video_length,omitempty"`
+	// The retrigger time in seconds
+	RetriggerTime *int `json:"retrigger_time,omitempty"`
+	// The night vision mode (off, auto, on)
+	NightVision *string `json:"night_vision,omitempty"`
+	// The IR illuminator intensity, from 1 to 7
+	IlluminatorIntensity *int `json:"illuminator_intensity,omitempty"`
+	// The video quality preset (saver, standard, best)
+	VideoQuality *string `json:"video_quality,omitempty"`
+	// Whether audio is recorded with clips
+	RecordAudioEnable *bool `json:"record_audio_enable,omitempty"`
+	// The button LED state (off, on)
+	LedState *string `json:"led_state,omitempty"`
+}
+
+// Validate reports the first setting that is out of range
+//
+// Example: (&CameraConfig{MotionSensitivity: &ten}).Validate() = error
+func (cfg *CameraConfig) Validate() error {
+	return firstError(
+		validateRange("motion_sensitivity", cfg.MotionSensitivity, 1, 9),
+		validateRange("video_length", cfg.VideoLength, 1, 60),
+		validateRange("alert_interval", cfg.AlertInterval, 10, 60),
+		validateRange("illuminator_enable", cfg.IlluminatorEnable, 0, 2),
+		validateRange("illuminator_intensity", cfg.IlluminatorIntensity, 1, 7),
+		validateOneOf("video_quality", cfg.VideoQuality, VIDEO_QUALITIES),
+	)
+}
+
+// Validate reports the first setting that is out of range
+//
+// Example: (&OwlConfig{NightVision: &mode}).Validate() = nil
+func (cfg *OwlConfig) Validate() error {
+	return firstError(
+		validateRange("motion_sensitivity", cfg.MotionSensitivity, 1, 9),
+		validateRange("video_length", cfg.VideoLength, 1, 30),
+		validateRange("retrigger_time", cfg.RetriggerTime, 10, 60),
+		validateOneOf("night_vision", cfg.NightVision, NIGHT_VISION_MODES),
+		validateOneOf("video_quality", cfg.VideoQuality, VIDEO_QUALITIES),
+		validateOneOf("led_state", cfg.LedState, LED_STATES),
+	)
+}
+
+// Validate reports the first setting that is out of range
+//
+// Example: (&DoorbellConfig{LedState: &state}).Validate() = nil
+func (cfg *DoorbellConfig) Validate() error {
+	return firstError(
+		validateRange("motion_sensitivity", cfg.MotionSensitivity, 1, 9),
+		validateRange("video_length", cfg.VideoLength, 1, 30),
+		validateRange("retrigger_time", cfg.RetriggerTime, 10, 60),
+		validateOneOf("night_vision", cfg.NightVision, NIGHT_VISION_MODES),
+		validateRange("illuminator_intensity", cfg.IlluminatorIntensity, 1, 7),
+		validateOneOf("video_quality", cfg.VideoQuality, VIDEO_QUALITIES),
+		validateOneOf("led_state", cfg.LedState, LED_STATES),
+	)
+}
+
+// NewDeviceConfig returns an empty config of the family matching the device type
+//
+// deviceType: the type of device (e.g. camera, owl, doorbell)
+//
+// Example: NewDeviceConfig("hawk") = &OwlConfig{}, nil
+func NewDeviceConfig(deviceType string) (DeviceConfig, error) {
+	switch deviceType {
+	case "camera":
+		return &CameraConfig{}, nil
+	case "owl", "hawk":
+		return &OwlConfig{}, nil
+	case "doorbell", "lotus":
+		return &DoorbellConfig{}, nil
+	}
+
+	return nil, fmt.Errorf("no config available for unknown device type: %s", deviceType)
+}
+
+// ParseDeviceConfig decodes a JSON config for the device type.
+// Unknown settings are rejected so typos do not silently do nothing.
+//
+// deviceType: the type of device (e.g. camera, owl, doorbell)
+//
+// data: the JSON config
+//
+// Example: ParseDeviceConfig("owl", []byte(`{"motion_sensitivity": 5}`)) = &OwlConfig{...}, nil
+func ParseDeviceConfig(deviceType string, data []byte) (DeviceConfig, error) {
+	cfg, err := NewDeviceConfig(deviceType)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("invalid %s config: %w", deviceType, err)
+	}
+
+	return cfg, nil
+}
+
+// GetDeviceConfig reads the current configuration of a single device
+//
+// ctx: the context to use for the request
+//
+// deviceType: the type of device (e.g. camera, owl, doorbell)
+//
+// networkId: the network ID that the device is on
+//
+// deviceId: the ID of the device to read
+//
+// Example: client.GetDeviceConfig(ctx, "owl", 5678, 9012) = &OwlConfig{...}, nil
+func (c *BlinkClient) GetDeviceConfig(ctx context.Context, deviceType string, networkId int, deviceId int) (DeviceConfig, error) {
+	configPath, err := GetConfigPath(deviceType)
+	if err != nil {
+		return nil, fmt.Errorf("error getting config path: %w", err)
+	}
+
+	cfg, err := NewDeviceConfig(deviceType)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.newRequest(ctx, "GET", fmt.Sprintf(configPath, c.ApiUrl(), c.AccountId, networkId, deviceId), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.doRetry(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	// Classic cameras wrap the config in a single element list
+	if deviceType == "camera" {
+		var result struct {
+			Camera []*CameraConfig `json:"camera"`
+		}
+		if err := decodeBody(resp, &result); err != nil {
+			return nil, err
+		}
+		if len(result.Camera) == 0 {
+			return nil, fmt.Errorf("config response did not include camera %d", deviceId)
+		}
+
+		return result.Camera[0], nil
+	}
+
+	if err := decodeBody(resp, cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// UpdateDeviceConfig changes the configuration of a single device and waits for the command to complete
+//
+// ctx: the context to use for the update request and while waiting for the command
+//
+// deviceType: the type of device (e.g. camera, owl, doorbell)
+//
+// networkId: the network ID that the device is on
+//
+// deviceId: the ID of the device to update
+//
+// cfg: the settings to change. Must match the device family
+//
+// Example: client.UpdateDeviceConfig(ctx, "owl", 5678, 9012, &OwlConfig{...}) = &CommandResponse{Complete: true}, nil
+func (c *BlinkClient) UpdateDeviceConfig(ctx context.Context, deviceType string, networkId int, deviceId int, cfg DeviceConfig) (*CommandResponse, error) {
+	expected, err := NewDeviceConfig(deviceType)
+	if err != nil {
+		return nil, err
+	}
+	if reflect.TypeOf(expected) != reflect.TypeOf(cfg) {
+		return nil, fmt.Errorf("cannot apply %T to device type %s", cfg, deviceType)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s config: %w", deviceType, err)
+	}
+
+	updatePath, err := GetConfigUpdatePath(deviceType)
+	if err != nil {
+		return nil, fmt.Errorf("error getting config update path: %w", err)
+	}
+
+	cmd, err := c.startCommand(ctx, fmt.Sprintf(updatePath, c.ApiUrl(), c.AccountId, networkId, deviceId), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error sending config update command: %w", err)
+	}
+
+	result, err := c.WaitForCommand(ctx, c.commandUrl(networkId, cmd.Id), COMMAND_POLL_INTERVAL)
+	if err != nil {
+		return nil, fmt.Errorf("error waiting for config update command: %w", err)
+	}
+
+	return result, nil
+}
+
+// validateRange reports whether an optional setting is outside [min, max]
+func validateRange(name string, value *int, min int, max int) error {
+	if value != nil && (*value < min || *value > max) {
+		return fmt.Errorf("%s must be between %d and %d, got %d", name, min, max, *value)
+	}
+
+	return nil
+}
+
+// validateOneOf reports whether an optional setting is not one of the allowed values
+func validateOneOf(name string, value *string, allowed []string) error {
+	if value != nil && !slices.Contains(allowed, *value) {
+		return fmt.Errorf("%s must be one of %v, got %q", name, allowed, *value)
+	}
+
+	return nil
+}
+
+// firstError returns the first non-nil error
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
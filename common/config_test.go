@@ -0,0 +1,135 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestNewDeviceConfig(t *testing.T) {
+	camera, err := common.NewDeviceConfig("camera")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, &common.CameraConfig{}, camera)
+
+	owl, err := common.NewDeviceConfig("hawk")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, &common.OwlConfig{}, owl)
+
+	doorbell, err := common.NewDeviceConfig("lotus")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, &common.DoorbellConfig{}, doorbell)
+
+	_, err = common.NewDeviceConfig("unknown")
+	assert.NotEqual(t, nil, err)
+}
+
+func TestParseDeviceConfig(t *testing.T) {
+	cfg, err := common.ParseDeviceConfig("owl", []byte(`{"motion_sensitivity": 5, "night_vision": "auto"}`))
+	assert.Equal(t, nil, err)
+
+	owl := cfg.(*common.OwlConfig)
+	assert.Equal(t, 5, *owl.MotionSensitivity)
+	assert.Equal(t, "auto", *owl.NightVision)
+	assert.Equal(t, true, owl.VideoLength == nil)
+
+	_, err = common.ParseDeviceConfig("owl", []byte(`{"alert_interval": 10}`))
+	assert.Equal(t, `invalid owl config: json: unknown field "alert_interval"`, err.Error())
+}
+
+func TestDeviceConfigValidate(t *testing.T) {
+	sensitivity := 10
+	assert.Equal(t, "motion_sensitivity must be between 1 and 9, got 10", (&common.CameraConfig{MotionSensitivity: &sensitivity}).Validate().Error())
+
+	quality := "ultra"
+	assert.Equal(t, `video_quality must be one of [saver standard best], got "ultra"`, (&common.DoorbellConfig{VideoQuality: &quality}).Validate().Error())
+
+	led := "on"
+	assert.Equal(t, nil, (&common.OwlConfig{LedState: &led}).Validate())
+}
+
+func TestGetDeviceConfigCamera(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/network/2/camera/3/config", r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"camera": [{"motion_sensitivity": 5, "video_length": 10, "video_quality": "best", "name": "Garage"}]}`))
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	client.RestUrl = mockServer.URL
+
+	cfg, err := client.GetDeviceConfig(context.Background(), "camera", 2, 3)
+	assert.Equal(t, nil, err)
+
+	camera := cfg.(*common.CameraConfig)
+	assert.Equal(t, 5, *camera.MotionSensitivity)
+	assert.Equal(t, 10, *camera.VideoLength)
+	assert.Equal(t, "best", *camera.VideoQuality)
+}
+
+func TestGetDeviceConfigOwl(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/accounts/1/networks/2/owls/3/config", r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"retrigger_time": 30, "led_state": "off"}`))
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	client.RestUrl = mockServer.URL
+
+	cfg, err := client.GetDeviceConfig(context.Background(), "owl", 2, 3)
+	assert.Equal(t, nil, err)
+
+	owl := cfg.(*common.OwlConfig)
+	assert.Equal(t, 30, *owl.RetriggerTime)
+	assert.Equal(t, "off", *owl.LedState)
+}
+
+func TestUpdateDeviceConfigNominal(t *testing.T) {
+	var body map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /network/2/camera/3/update", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": 999, "network_id": 2}`))
+	})
+	mux.HandleFunc("GET /network/2/command/999", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"complete": true}`))
+	})
+	mockServer := httptest.NewServer(mux)
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	client.RestUrl = mockServer.URL
+
+	cfg, _ := common.ParseDeviceConfig("camera", []byte(`{"record_audio_enable": false}`))
+	result, err := client.UpdateDeviceConfig(context.Background(), "camera", 2, 3, cfg)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, result.Complete)
+	assert.Equal(t, map[string]interface{}{"record_audio_enable": false}, body)
+}
+
+func TestUpdateDeviceConfigWrongFamily(t *testing.T) {
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+
+	_, err := client.UpdateDeviceConfig(context.Background(), "owl", 2, 3, &common.CameraConfig{})
+
+	assert.Equal(t, "cannot apply *common.CameraConfig to device type owl", err.Error())
+}
+
+func TestUpdateDeviceConfigInvalid(t *testing.T) {
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	retrigger := 5
+
+	_, err := client.UpdateDeviceConfig(context.Background(), "doorbell", 2, 3, &common.DoorbellConfig{RetriggerTime: &retrigger})
+
+	assert.Equal(t, "invalid doorbell config: retrigger_time must be between 10 and 60, got 5", err.Error())
+}
@@ -0,0 +1,321 @@
+package common
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CREDENTIAL_FORMAT identifies an encrypted credential file
+const CREDENTIAL_FORMAT = "blink-credential"
+
+// CREDENTIAL_VERSION is the version of the encrypted credential format written by this build
+const CREDENTIAL_VERSION = 1
+
+// CREDENTIAL_KDF is the key derivation function used by CREDENTIAL_VERSION
+const CREDENTIAL_KDF = "pbkdf2-sha256"
+
+// CREDENTIAL_ITERATIONS is the PBKDF2 iteration count used for new files.
+// Files sealed with fewer iterations are re-sealed the next time they are read.
+var CREDENTIAL_ITERATIONS = 600_000
+
+// CREDENTIAL_MAX_ITERATIONS_FACTOR bounds the PBKDF2 iteration count read from a file to this multiple of
+// CREDENTIAL_ITERATIONS, so a tampered file cannot stall every read
+var CREDENTIAL_MAX_ITERATIONS_FACTOR = 10
+
+// CREDENTIAL_PASSPHRASE_ENV is the environment variable holding the credential passphrase
+var CREDENTIAL_PASSPHRASE_ENV = "BLINK_PASSPHRASE"
+
+// DefaultCredentialStore encrypts the fingerprint file and the profiles at rest.
+// Files are stored in plaintext when nil.
+var DefaultCredentialStore *CredentialStore
+
+// ErrPassphraseRequired is returned when reading an encrypted file without a passphrase
+var ErrPassphraseRequired = errors.New("credential file is encrypted and no passphrase was provided")
+
+// ErrNotSealed is returned when reading a plaintext file while a passphrase is set.
+// Plaintext files are only encrypted by an explicit migration (e.g. the credentials passphrase command).
+var ErrNotSealed = errors.New("credential file is not encrypted. Encrypt it with the credentials passphrase command")
+
+// ErrWrongPassphrase is returned when an encrypted file cannot be decrypted with the passphrase
+var ErrWrongPassphrase = errors.New("incorrect passphrase or corrupted credential file")
+
+type sealedCredential struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// CredentialStore seals credential files with AES-256-GCM, using a key derived from a passphrase.
+// A nil store reads and writes plaintext files.
+type CredentialStore struct {
+	passphrase []byte
+
+	mu   sync.Mutex
+	salt []byte
+	keys map[string][]byte
+}
+
+// NewCredentialStore creates a store that seals files with the passphrase
+//
+// passphrase: the passphrase to derive the keys from
+//
+// Example: NewCredentialStore("correct horse battery staple")
+func NewCredentialStore(passphrase string) *CredentialStore {
+	return &CredentialStore{
+		passphrase: []byte(passphrase),
+		keys:       make(map[string][]byte),
+	}
+}
+
+// CredentialStoreFromEnv creates a store from CREDENTIAL_PASSPHRASE_ENV, or returns nil when it is not set
+//
+// Example: CredentialStoreFromEnv() = &CredentialStore{...}
+func CredentialStoreFromEnv() *CredentialStore {
+	passphrase := os.Getenv(CREDENTIAL_PASSPHRASE_ENV)
+	if passphrase == "" {
+		return nil
+	}
+
+	return NewCredentialStore(passphrase)
+}
+
+// IsSealed reports whether the data is an encrypted credential file
+//
+// data: the file contents
+//
+// Example: IsSealed([]byte(`{"format": "blink-credential", ...}`)) = true
+func IsSealed(data []byte) bool {
+	var sealed sealedCredential
+
+	return json.Unmarshal(data, &sealed) == nil && sealed.Format == CREDENTIAL_FORMAT
+}
+
+// Seal encrypts the plaintext. A nil store returns the plaintext unchanged.
+//
+// plaintext: the data to encrypt
+//
+// Example: store.Seal([]byte("secret")) = []byte(`{"format": "blink-credential", ...}`), nil
+func (s *CredentialStore) Seal(plaintext []byte) ([]byte, error) {
+	if s == nil {
+		return plaintext, nil
+	}
+
+	s.mu.Lock()
+	if s.salt == nil {
+		s.salt = make([]byte, 16)
+		rand.Read(s.salt)
+	}
+	salt := s.salt
+	s.mu.Unlock()
+
+	sealed := sealedCredential{
+		Format:     CREDENTIAL_FORMAT,
+		Version:    CREDENTIAL_VERSION,
+		KDF:        CREDENTIAL_KDF,
+		Iterations: CREDENTIAL_ITERATIONS,
+		Salt:       salt,
+	}
+
+	aead, err := s.aead(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed.Nonce = make([]byte, aead.NonceSize())
+	rand.Read(sealed.Nonce)
+	sealed.Ciphertext = aead.Seal(nil, sealed.Nonce, plaintext, sealed.additionalData())
+
+	return json.MarshalIndent(sealed, "", "  ")
+}
+
+// Open decrypts a sealed credential file. A nil store returns plaintext data unchanged,
+// and a store rejects it with ErrNotSealed, as it may have been planted.
+//
+// data: the file contents
+//
+// Example: store.Open(sealed) = []byte("secret"), nil
+func (s *CredentialStore) Open(data []byte) ([]byte, error) {
+	if !IsSealed(data) {
+		if s != nil {
+			return nil, ErrNotSealed
+		}
+		return data, nil
+	}
+	if s == nil {
+		return nil, fmt.Errorf("%w. Set %s", ErrPassphraseRequired, CREDENTIAL_PASSPHRASE_ENV)
+	}
+
+	var sealed sealedCredential
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, err
+	}
+	if sealed.Version != CREDENTIAL_VERSION || sealed.KDF != CREDENTIAL_KDF {
+		return nil, fmt.Errorf("unsupported credential file version %d (%s)", sealed.Version, sealed.KDF)
+	}
+
+	aead, err := s.aead(sealed)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed.Nonce) != aead.NonceSize() {
+		return nil, ErrWrongPassphrase
+	}
+
+	plaintext, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, sealed.additionalData())
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	return plaintext, nil
+}
+
+// ReadFile reads and decrypts a credential file.
+// Files sealed with outdated parameters are re-sealed with the store's current key.
+//
+// path: the file to read
+//
+// Example: store.ReadFile("profile.json") = []byte("{...}"), nil
+func (s *CredentialStore) ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := s.Open(data)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting %s: %w", filepath.Base(path), err)
+	}
+
+	if s.needsRotation(data) {
+		// Best effort. The file is still readable if the upgrade fails
+		if err := s.WriteFile(path, plaintext); err != nil {
+			log.Printf("error re-encrypting %s: %v\n", filepath.Base(path), err)
+		}
+	}
+
+	return plaintext, nil
+}
+
+// WriteFile encrypts the data and writes it to the path, readable by the current user only.
+// The file is replaced atomically so a crash never leaves a truncated file behind.
+//
+// path: the file to write
+//
+// plaintext: the data to write
+//
+// Example: store.WriteFile("profile.json", data) = nil
+func (s *CredentialStore) WriteFile(path string, plaintext []byte) error {
+	data, err := s.Seal(plaintext)
+	if err != nil {
+		return err
+	}
+
+	// CreateTemp creates the file with 0600 permissions
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+// Rekey re-encrypts a credential file from this store to another store (e.g. a new passphrase).
+// Plaintext files are accepted, so this is also the explicit step encrypting them.
+//
+// path: the file to re-encrypt
+//
+// to: the store to re-encrypt the file with. Nil to decrypt the file to plaintext
+//
+// Example: oldStore.Rekey("profile.json", newStore) = nil
+func (s *CredentialStore) Rekey(path string, to *CredentialStore) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	plaintext := data
+	if IsSealed(data) {
+		if plaintext, err = s.Open(data); err != nil {
+			return fmt.Errorf("error decrypting %s: %w", filepath.Base(path), err)
+		}
+	}
+
+	return to.WriteFile(path, plaintext)
+}
+
+// needsRotation reports whether the file should be re-sealed with the store's current parameters
+func (s *CredentialStore) needsRotation(data []byte) bool {
+	if s == nil {
+		return false
+	}
+
+	var sealed sealedCredential
+	if json.Unmarshal(data, &sealed) != nil || sealed.Format != CREDENTIAL_FORMAT {
+		return false
+	}
+
+	return sealed.Iterations < CREDENTIAL_ITERATIONS
+}
+
+// aead derives the key for the sealed file's parameters and returns the cipher
+func (s *CredentialStore) aead(sealed sealedCredential) (cipher.AEAD, error) {
+	if sealed.Iterations <= 0 || len(sealed.Salt) == 0 {
+		return nil, fmt.Errorf("invalid key derivation parameters")
+	}
+	if sealed.Iterations > CREDENTIAL_MAX_ITERATIONS_FACTOR*CREDENTIAL_ITERATIONS {
+		return nil, fmt.Errorf("iteration count %d exceeds the maximum of %d", sealed.Iterations, CREDENTIAL_MAX_ITERATIONS_FACTOR*CREDENTIAL_ITERATIONS)
+	}
+
+	cacheKey := fmt.Sprintf("%x/%d", sealed.Salt, sealed.Iterations)
+
+	s.mu.Lock()
+	key, ok := s.keys[cacheKey]
+	s.mu.Unlock()
+
+	if !ok {
+		var err error
+		key, err = pbkdf2.Key(sha256.New, string(s.passphrase), sealed.Salt, sealed.Iterations, 32)
+		if err != nil {
+			return nil, fmt.Errorf("error deriving key: %w", err)
+		}
+
+		s.mu.Lock()
+		s.keys[cacheKey] = key
+		s.mu.Unlock()
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// additionalData binds the header to the ciphertext so it cannot be changed undetected
+func (sealed sealedCredential) additionalData() []byte {
+	return fmt.Appendf(nil, "%s/%d/%s/%d/%x", sealed.Format, sealed.Version, sealed.KDF, sealed.Iterations, sealed.Salt)
+}
@@ -0,0 +1,185 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+// useCredentialStore sets the default credential store for the duration of the test.
+// The iteration count is lowered to keep the tests fast.
+func useCredentialStore(t *testing.T, store *common.CredentialStore) {
+	iterations := common.CREDENTIAL_ITERATIONS
+	common.CREDENTIAL_ITERATIONS = 1000
+	common.DefaultCredentialStore = store
+	t.Cleanup(func() {
+		common.CREDENTIAL_ITERATIONS = iterations
+		common.DefaultCredentialStore = nil
+	})
+}
+
+func TestCredentialStoreRoundTrip(t *testing.T) {
+	useCredentialStore(t, nil)
+	store := common.NewCredentialStore("passphrase")
+
+	sealed, err := store.Seal([]byte("secret-token"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, common.IsSealed(sealed))
+	assert.Equal(t, false, strings.Contains(string(sealed), "secret-token"))
+
+	plaintext, err := store.Open(sealed)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "secret-token", string(plaintext))
+
+	_, err = common.NewCredentialStore("wrong").Open(sealed)
+	assert.Equal(t, true, errors.Is(err, common.ErrWrongPassphrase))
+
+	var nilStore *common.CredentialStore
+	_, err = nilStore.Open(sealed)
+	assert.Equal(t, true, errors.Is(err, common.ErrPassphraseRequired))
+}
+
+func TestCredentialStoreTamperedHeader(t *testing.T) {
+	useCredentialStore(t, nil)
+	store := common.NewCredentialStore("passphrase")
+
+	sealed, _ := store.Seal([]byte("secret-token"))
+	tampered := strings.Replace(string(sealed), `"iterations": 1000`, `"iterations": 1001`, 1)
+
+	_, err := store.Open([]byte(tampered))
+	assert.Equal(t, true, errors.Is(err, common.ErrWrongPassphrase))
+}
+
+func TestCredentialStorePlaintext(t *testing.T) {
+	var store *common.CredentialStore
+
+	data, err := store.Seal([]byte("plain"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "plain", string(data))
+
+	data, err = store.Open([]byte("plain"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "plain", string(data))
+
+	// Plaintext may have been planted, so it is rejected once a passphrase is set
+	_, err = common.NewCredentialStore("passphrase").Open([]byte("plain"))
+	assert.Equal(t, true, errors.Is(err, common.ErrNotSealed))
+}
+
+func TestCredentialStoreReadFileRotates(t *testing.T) {
+	useCredentialStore(t, nil)
+	path := filepath.Join(t.TempDir(), "fingerprint.txt")
+	os.WriteFile(path, []byte("plain-fingerprint"), 0600)
+
+	store := common.NewCredentialStore("passphrase")
+	_, err := store.ReadFile(path)
+	assert.Equal(t, true, errors.Is(err, common.ErrNotSealed))
+
+	// Plaintext files are only encrypted by an explicit rekey
+	var plaintextStore *common.CredentialStore
+	assert.Equal(t, nil, plaintextStore.Rekey(path, store))
+	raw, _ := os.ReadFile(path)
+	assert.Equal(t, true, common.IsSealed(raw))
+
+	data, err := store.ReadFile(path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "plain-fingerprint", string(data))
+
+	// Files sealed with fewer iterations are re-sealed
+	common.CREDENTIAL_ITERATIONS = 2000
+	store.ReadFile(path)
+	raw, _ = os.ReadFile(path)
+	assert.Equal(t, true, strings.Contains(string(raw), `"iterations": 2000`))
+}
+
+func TestCredentialStoreIterationLimit(t *testing.T) {
+	useCredentialStore(t, nil)
+	store := common.NewCredentialStore("passphrase")
+
+	sealed, err := store.Seal([]byte("secret"))
+	assert.Equal(t, nil, err)
+
+	// A tampered iteration count above the limit is rejected before deriving a key
+	tampered := strings.Replace(string(sealed), `"iterations": 1000`, `"iterations": 2000000000`, 1)
+	start := time.Now()
+	_, err = store.Open([]byte(tampered))
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, true, time.Since(start) < time.Second)
+}
+
+func TestFingerprintEncrypted(t *testing.T) {
+	useCredentialStore(t, common.NewCredentialStore("passphrase"))
+	path := filepath.Join(t.TempDir(), common.FINGERPRINT_FILE)
+
+	fingerprint, _ := common.GetFingerprint(path)
+	assert.Equal(t, nil, fingerprint.Store())
+
+	raw, _ := os.ReadFile(path)
+	assert.Equal(t, true, common.IsSealed(raw))
+
+	info, _ := os.Stat(path)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := common.GetFingerprint(path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, fingerprint.Value, loaded.Value)
+}
+
+func TestRekeyProfiles(t *testing.T) {
+	oldStore := common.NewCredentialStore("old")
+	newStore := common.NewCredentialStore("new")
+	useCredentialStore(t, oldStore)
+	dir := t.TempDir()
+
+	profile := &common.Profile{Name: "default", AccessToken: "access"}
+	profile.Save(dir)
+
+	names, err := common.RekeyProfiles(dir, oldStore, newStore)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"default"}, names)
+
+	_, err = common.LoadProfile(dir, "default")
+	assert.Equal(t, true, errors.Is(err, common.ErrWrongPassphrase))
+
+	common.DefaultCredentialStore = newStore
+	loaded, err := common.LoadProfile(dir, "default")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "access", loaded.AccessToken)
+}
+
+func TestExportImportProfiles(t *testing.T) {
+	useCredentialStore(t, common.NewCredentialStore("local"))
+	exportStore := common.NewCredentialStore("export")
+	source, target := t.TempDir(), t.TempDir()
+
+	(&common.Profile{Name: "default", AccessToken: "access", AccountId: 1}).Save(source)
+	(&common.Profile{Name: "work", AccessToken: "work-access", AccountId: 2}).Save(source)
+	(&common.Profile{Name: "work", AccessToken: "existing", AccountId: 3}).Save(target)
+
+	data, err := common.ExportProfiles(source, nil, exportStore)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, common.IsSealed(data))
+
+	_, err = common.ImportProfiles(target, data, common.NewCredentialStore("wrong"), false)
+	assert.Equal(t, true, errors.Is(err, common.ErrWrongPassphrase))
+
+	imported, err := common.ImportProfiles(target, data, exportStore, false)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"default"}, imported)
+
+	existing, _ := common.LoadProfile(target, "work")
+	assert.Equal(t, "existing", existing.AccessToken)
+
+	imported, err = common.ImportProfiles(target, data, exportStore, true)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"default", "work"}, imported)
+
+	replaced, _ := common.LoadProfile(target, "work")
+	assert.Equal(t, "work-access", replaced.AccessToken)
+}
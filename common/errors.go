@@ -0,0 +1,138 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// API_CODE_BUSY is the Blink error code returned when the system or device is busy
+const API_CODE_BUSY = 307
+
+type APIError struct {
+	// The HTTP status code of the response
+	StatusCode int
+	// The Blink error code from the response body (if any)
+	Code int
+	// The Blink error message from the response body (if any)
+	Message string
+	// The URL of the request that failed
+	Url string
+	// How long Blink asked us to wait before retrying. Zero if not provided
+	RetryAfter time.Duration
+}
+
+// Error returns a description of the API error
+//
+// Example: Error() = "HTTP Status Code 409. API Code 307 with message System is busy"
+func (e *APIError) Error() string {
+	if e.StatusCode >= 200 && e.StatusCode < 300 {
+		return fmt.Sprintf("API Code %d with message %s", e.Code, e.Message)
+	}
+
+	msg := fmt.Sprintf("HTTP Status Code %d", e.StatusCode)
+	if e.Code != 0 || e.Message != "" {
+		msg += fmt.Sprintf(". API Code %d with message %s", e.Code, e.Message)
+	}
+
+	return msg
+}
+
+// newAPIError builds an APIError from an unsuccessful response.
+// The response body is consumed but not closed.
+//
+// resp: the unsuccessful response
+//
+// Example: newAPIError(resp) = &APIError{StatusCode: 500}
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+	if resp.Request != nil && resp.Request.URL != nil {
+		apiErr.Url = resp.Request.URL.String()
+	}
+
+	var body struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if data, err := io.ReadAll(resp.Body); err == nil && json.Unmarshal(data, &body) == nil {
+		apiErr.Code = body.Code
+		apiErr.Message = body.Message
+	}
+
+	return apiErr
+}
+
+// parseRetryAfter parses a Retry-After header in either seconds or HTTP date form
+//
+// value: the header value
+//
+// Example: parseRetryAfter("5") = 5 * time.Second
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+
+	return 0
+}
+
+// AsAPIError returns the APIError wrapped in err, if any
+//
+// err: the error to inspect
+//
+// Example: AsAPIError(err) = &APIError{StatusCode: 401}, true
+func AsAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+
+	return nil, false
+}
+
+// IsUnauthorized reports whether the error is a Blink API 401 response
+//
+// err: the error to inspect
+//
+// Example: IsUnauthorized(err) = true
+func IsUnauthorized(err error) bool {
+	apiErr, ok := AsAPIError(err)
+
+	return ok && apiErr.StatusCode == http.StatusUnauthorized
+}
+
+// IsRateLimited reports whether the error is a Blink API 429 response
+//
+// err: the error to inspect
+//
+// Example: IsRateLimited(err) = true
+func IsRateLimited(err error) bool {
+	apiErr, ok := AsAPIError(err)
+
+	return ok && apiErr.StatusCode == http.StatusTooManyRequests
+}
+
+// IsDeviceBusy reports whether the error indicates that the camera or sync module is busy
+//
+// err: the error to inspect
+//
+// Example: IsDeviceBusy(err) = true
+func IsDeviceBusy(err error) bool {
+	apiErr, ok := AsAPIError(err)
+
+	return ok && (apiErr.StatusCode == http.StatusConflict || apiErr.Code == API_CODE_BUSY)
+}
@@ -0,0 +1,113 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestAPIErrorMessage(t *testing.T) {
+	assert.Equal(t, "HTTP Status Code 500", (&common.APIError{StatusCode: 500}).Error())
+	assert.Equal(t, "HTTP Status Code 409. API Code 307 with message busy", (&common.APIError{StatusCode: 409, Code: 307, Message: "busy"}).Error())
+	assert.Equal(t, "API Code 800 with message Some error", (&common.APIError{StatusCode: 200, Code: 800, Message: "Some error"}).Error())
+}
+
+func TestAPIErrorFromResponse(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"code": 101, "message": "Too many requests"}`))
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 0)
+	client.Retry = nil
+
+	_, err := client.Homescreen(context.Background(), mockServer.URL+"/homescreen")
+	apiErr, ok := common.AsAPIError(err)
+
+	assert.Equal(t, true, ok)
+	assert.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode)
+	assert.Equal(t, 101, apiErr.Code)
+	assert.Equal(t, "Too many requests", apiErr.Message)
+	assert.Equal(t, mockServer.URL+"/homescreen", apiErr.Url)
+	assert.Equal(t, 7*time.Second, apiErr.RetryAfter)
+	assert.Equal(t, true, common.IsRateLimited(err))
+}
+
+func TestAPIErrorWrapped(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"code": 307, "message": "System is busy, please wait"}`))
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 0)
+	client.Retry = nil
+
+	_, err := client.BeginLiveview(context.Background(), mockServer.URL)
+
+	assert.Equal(t, "error starting liveview. HTTP Status Code 409. API Code 307 with message System is busy, please wait", err.Error())
+	assert.Equal(t, true, common.IsDeviceBusy(err))
+	assert.Equal(t, false, common.IsUnauthorized(err))
+}
+
+func TestAPIErrorStopCommandCode(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"code": 307, "message": "busy"}`))
+	}))
+	defer mockServer.Close()
+
+	err := common.StopCommand(context.Background(), mockServer.URL, "xyz-auth-token")
+	apiErr, ok := common.AsAPIError(err)
+
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 307, apiErr.Code)
+	assert.Equal(t, true, common.IsDeviceBusy(err))
+}
+
+func TestAPIErrorUnauthorized(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 0)
+	client.RestUrl = mockServer.URL
+	_, err := client.GetTierInfo(context.Background())
+
+	assert.Equal(t, true, common.IsUnauthorized(err))
+}
+
+func TestAPIErrorLoginRejected(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("", "", 0)
+	client.OAuthUrl = mockServer.URL
+	_, err := client.Login(context.Background(), "email", "password", "", &common.Fingerprint{Value: "mock-fingerprint"})
+
+	assert.Equal(t, true, errors.Is(err, common.ErrLoginRejected))
+	assert.Equal(t, true, common.IsUnauthorized(err))
+}
+
+func TestAPIErrorNotAPIError(t *testing.T) {
+	err := fmt.Errorf("some other error")
+
+	_, ok := common.AsAPIError(err)
+
+	assert.Equal(t, false, ok)
+	assert.Equal(t, false, common.IsUnauthorized(err))
+	assert.Equal(t, false, common.IsRateLimited(err))
+	assert.Equal(t, false, common.IsDeviceBusy(err))
+}
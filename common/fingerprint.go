@@ -21,6 +21,7 @@ var FINGERPRINT_FILE string = "fingerprint.txt"
 
 // GetFingerprint returns the fingerprint from the fingerprint file.
 // If the file does not exist, it will be created and a new fingerprint will be generated.
+// Encrypted files are decrypted with DefaultCredentialStore.
 //
 // filename: the filename to use for the fingerprint file. Optional.
 //
@@ -30,7 +31,7 @@ func GetFingerprint(filename string) (*Fingerprint, error) {
 		filename = FINGERPRINT_FILE
 	}
 
-	file, err := os.ReadFile(filename)
+	file, err := DefaultCredentialStore.ReadFile(filename)
 	if errors.Is(err, os.ErrNotExist) {
 		return generateNew(filename), nil
 	} else if err != nil {
@@ -65,7 +66,8 @@ func (f *Fingerprint) Destroy() error {
 	return os.Remove(f.Filename)
 }
 
-// Store writes the fingerprint to the filesystem if it is new
+// Store writes the fingerprint to the filesystem if it is new.
+// The file is encrypted when DefaultCredentialStore is set.
 //
 // Example: Store() = nil
 func (f *Fingerprint) Store() error {
@@ -76,7 +78,7 @@ func (f *Fingerprint) Store() error {
 		return fmt.Errorf("fingerprint filename is empty")
 	}
 
-	return os.WriteFile(f.Filename, []byte(f.Value), 0644)
+	return DefaultCredentialStore.WriteFile(f.Filename, []byte(f.Value))
 }
 
 // String returns the fingerprint value as a string
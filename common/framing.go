@@ -0,0 +1,192 @@
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// FRAME_HEADER_SIZE is the size of the header that wraps every message on the liveview TCP stream:
+// a message type byte, a 4 byte sequence number and a 4 byte payload length (big endian).
+// The layout comes from the keep-alive frame the original client sent (type 0x12, sequence 1000,
+// 24 byte payload, see FRAMES_KEEPALIVE) and from the liveview client of the blinkpy project
+// (blinkpy/livestream.py), which reads the same header before every payload.
+// It has not been checked against every stream server, so the Demuxer reports ErrUnknownFraming
+// when the first header is not valid, and the stream is then passed through unframed.
+const FRAME_HEADER_SIZE = 9
+
+// FRAME_MAX_PAYLOAD is the largest payload accepted from the stream. Larger lengths mean the stream is out of sync
+var FRAME_MAX_PAYLOAD = 1 << 20
+
+// The message types observed on the liveview TCP stream
+const (
+	// MPEG-TS video (and muxed audio) payload
+	FRAME_TYPE_VIDEO byte = 0x00
+	// Audio payload sent outside of the transport stream
+	FRAME_TYPE_AUDIO byte = 0x01
+	// Keep-alive, sent by the client and acknowledged by the server with the same type
+	FRAME_TYPE_KEEPALIVE byte = 0x12
+	// Session metadata (e.g. stream state) sent by the server
+	FRAME_TYPE_METADATA byte = 0x13
+)
+
+// FRAME_KINDS maps the message types to the kind of packet they carry.
+// Types missing from the map are emitted as PacketKindUnknown.
+var FRAME_KINDS = map[byte]PacketKind{
+	FRAME_TYPE_VIDEO:     PacketKindVideo,
+	FRAME_TYPE_AUDIO:     PacketKindAudio,
+	FRAME_TYPE_KEEPALIVE: PacketKindControl,
+	FRAME_TYPE_METADATA:  PacketKindControl,
+}
+
+// ErrFrameTooLarge is returned when a frame header announces a payload larger than FRAME_MAX_PAYLOAD
+var ErrFrameTooLarge = errors.New("frame payload exceeds the maximum size")
+
+// ErrUnknownFraming is returned when the first header of the stream has an unknown message type or
+// an oversized payload, meaning the server does not use the frame format. See Demuxer.Raw
+var ErrUnknownFraming = errors.New("stream does not use the liveview frame format")
+
+type PacketKind int
+
+const (
+	PacketKindUnknown PacketKind = iota
+	PacketKindVideo
+	PacketKindAudio
+	PacketKindControl
+)
+
+// String returns the name of the packet kind
+//
+// Example: PacketKindVideo.String() = "video"
+func (k PacketKind) String() string {
+	switch k {
+	case PacketKindVideo:
+		return "video"
+	case PacketKindAudio:
+		return "audio"
+	case PacketKindControl:
+		return "control"
+	}
+
+	return "unknown"
+}
+
+type FrameHeader struct {
+	// The message type (e.g. FRAME_TYPE_VIDEO)
+	Type byte
+	// The sequence number of the message
+	Sequence uint32
+	// The length of the payload following the header
+	Length uint32
+}
+
+type StreamPacket struct {
+	// The kind of payload, derived from the message type
+	Kind PacketKind
+	// The raw message type
+	Type byte
+	// The sequence number of the message
+	Sequence uint32
+	// The payload without the header. Only valid until the next call to Demuxer.Next
+	Payload []byte
+}
+
+// ParseFrameHeader decodes a frame header
+//
+// data: at least FRAME_HEADER_SIZE bytes
+//
+// Example: ParseFrameHeader([]byte{0x12, 0x00, 0x00, 0x03, 0xe8, 0x00, 0x00, 0x00, 0x18}) = FrameHeader{Type: 0x12, Sequence: 1000, Length: 24}, nil
+func ParseFrameHeader(data []byte) (FrameHeader, error) {
+	if len(data) < FRAME_HEADER_SIZE {
+		return FrameHeader{}, fmt.Errorf("frame header too short: %d bytes", len(data))
+	}
+
+	return FrameHeader{
+		Type:     data[0],
+		Sequence: binary.BigEndian.Uint32(data[1:5]),
+		Length:   binary.BigEndian.Uint32(data[5:9]),
+	}, nil
+}
+
+// Marshal encodes the frame header
+//
+// Example: FrameHeader{Type: 0x12, Sequence: 1000, Length: 24}.Marshal() = []byte{0x12, 0x00, 0x00, 0x03, 0xe8, 0x00, 0x00, 0x00, 0x18}
+func (h FrameHeader) Marshal() []byte {
+	data := make([]byte, FRAME_HEADER_SIZE)
+	data[0] = h.Type
+	binary.BigEndian.PutUint32(data[1:5], h.Sequence)
+	binary.BigEndian.PutUint32(data[5:9], h.Length)
+
+	return data
+}
+
+// Demuxer splits the liveview TCP stream into typed packets
+type Demuxer struct {
+	reader  *bufio.Reader
+	header  [FRAME_HEADER_SIZE]byte
+	payload []byte
+	// Set once a valid packet was read. Unknown message types are only accepted afterwards
+	synced bool
+}
+
+// NewDemuxer creates a demuxer reading frames from the stream
+//
+// reader: the liveview stream, positioned after the authentication frames
+//
+// Example: NewDemuxer(conn)
+func NewDemuxer(reader io.Reader) *Demuxer {
+	return &Demuxer{reader: bufio.NewReaderSize(reader, 64*1024)}
+}
+
+// Next reads the next packet from the stream.
+// Returns io.EOF when the stream ends between packets, and io.ErrUnexpectedEOF when it ends mid-packet.
+// Returns ErrUnknownFraming when the first header is not valid.
+//
+// Example: demuxer.Next() = &StreamPacket{Kind: PacketKindVideo, ...}, nil
+func (d *Demuxer) Next() (*StreamPacket, error) {
+	if _, err := io.ReadFull(d.reader, d.header[:]); err != nil {
+		return nil, err
+	}
+
+	header, _ := ParseFrameHeader(d.header[:])
+	if _, known := FRAME_KINDS[header.Type]; !known && !d.synced {
+		return nil, fmt.Errorf("%w: unknown message type 0x%02x", ErrUnknownFraming, header.Type)
+	}
+	if header.Length > uint32(FRAME_MAX_PAYLOAD) {
+		err := fmt.Errorf("%w: type 0x%02x, %d bytes", ErrFrameTooLarge, header.Type, header.Length)
+		if !d.synced {
+			err = fmt.Errorf("%w: %w", ErrUnknownFraming, err)
+		}
+		return nil, err
+	}
+
+	if cap(d.payload) < int(header.Length) {
+		d.payload = make([]byte, header.Length)
+	}
+	payload := d.payload[:header.Length]
+	if _, err := io.ReadFull(d.reader, payload); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	d.synced = true
+
+	return &StreamPacket{
+		Kind:     FRAME_KINDS[header.Type],
+		Type:     header.Type,
+		Sequence: header.Sequence,
+		Payload:  payload,
+	}, nil
+}
+
+// Raw returns the rest of the stream without demuxing it, starting with the header rejected by Next.
+// Used to pass the stream through unframed after ErrUnknownFraming.
+//
+// Example: io.Copy(writer, demuxer.Raw())
+func (d *Demuxer) Raw() io.Reader {
+	return io.MultiReader(bytes.NewReader(bytes.Clone(d.header[:])), d.reader)
+}
@@ -0,0 +1,135 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/go-playground/assert/v2"
+)
+
+// frame builds a framed message
+func frame(messageType byte, sequence uint32, payload []byte) []byte {
+	header := common.FrameHeader{Type: messageType, Sequence: sequence, Length: uint32(len(payload))}
+
+	return append(header.Marshal(), payload...)
+}
+
+// tsPackets builds an unframed MPEG-TS of null packets, as the stream was passed through before the framing was decoded
+func tsPackets(count int) []byte {
+	packet := make([]byte, 188)
+	packet[0], packet[1], packet[2], packet[3] = 0x47, 0x1f, 0xff, 0x10
+
+	return bytes.Repeat(packet, count)
+}
+
+func TestParseFrameHeader(t *testing.T) {
+	header, err := common.ParseFrameHeader(common.FRAMES_KEEPALIVE)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, common.FRAME_TYPE_KEEPALIVE, header.Type)
+	assert.Equal(t, uint32(1000), header.Sequence)
+	assert.Equal(t, uint32(24), header.Length)
+	assert.Equal(t, common.FRAMES_KEEPALIVE[:common.FRAME_HEADER_SIZE], header.Marshal())
+}
+
+func TestParseFrameHeaderTooShort(t *testing.T) {
+	_, err := common.ParseFrameHeader([]byte{0x00, 0x01})
+
+	assert.Equal(t, "frame header too short: 2 bytes", err.Error())
+}
+
+func TestDemuxerPacketKinds(t *testing.T) {
+	stream := bytes.Join([][]byte{
+		frame(common.FRAME_TYPE_VIDEO, 1, []byte{0x47, 0x01, 0x02}),
+		frame(common.FRAME_TYPE_KEEPALIVE, 2, make([]byte, 24)),
+		frame(common.FRAME_TYPE_AUDIO, 3, []byte{0xff, 0xf1}),
+		frame(0x7f, 4, []byte{0x01}),
+		frame(common.FRAME_TYPE_VIDEO, 5, []byte{}),
+	}, nil)
+
+	// Read one byte at a time to cover frames split across reads
+	demuxer := common.NewDemuxer(iotest.OneByteReader(bytes.NewReader(stream)))
+
+	expected := []struct {
+		kind     common.PacketKind
+		sequence uint32
+		length   int
+	}{
+		{common.PacketKindVideo, 1, 3},
+		{common.PacketKindControl, 2, 24},
+		{common.PacketKindAudio, 3, 2},
+		{common.PacketKindUnknown, 4, 1},
+		{common.PacketKindVideo, 5, 0},
+	}
+	for _, want := range expected {
+		packet, err := demuxer.Next()
+		assert.Equal(t, nil, err)
+		assert.Equal(t, want.kind, packet.Kind)
+		assert.Equal(t, want.sequence, packet.Sequence)
+		assert.Equal(t, want.length, len(packet.Payload))
+	}
+
+	_, err := demuxer.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestDemuxerTruncatedPayload(t *testing.T) {
+	stream := frame(common.FRAME_TYPE_VIDEO, 1, []byte{0x47, 0x01, 0x02})
+
+	_, err := common.NewDemuxer(bytes.NewReader(stream[:len(stream)-1])).Next()
+
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestDemuxerTruncatedHeader(t *testing.T) {
+	stream := frame(common.FRAME_TYPE_VIDEO, 1, []byte{0x47})
+
+	_, err := common.NewDemuxer(bytes.NewReader(stream[:4])).Next()
+
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestDemuxerFrameTooLarge(t *testing.T) {
+	header := common.FrameHeader{Type: common.FRAME_TYPE_VIDEO, Length: uint32(common.FRAME_MAX_PAYLOAD) + 1}
+
+	_, err := common.NewDemuxer(bytes.NewReader(header.Marshal())).Next()
+
+	assert.Equal(t, true, errors.Is(err, common.ErrFrameTooLarge))
+}
+
+func TestDemuxerUnknownFraming(t *testing.T) {
+	stream := tsPackets(3)
+	demuxer := common.NewDemuxer(bytes.NewReader(stream))
+
+	_, err := demuxer.Next()
+	assert.Equal(t, true, errors.Is(err, common.ErrUnknownFraming))
+
+	// The rejected header is passed through with the rest of the stream
+	raw, err := io.ReadAll(demuxer.Raw())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, stream, raw)
+}
+
+func TestDemuxerFrameTooLargeAfterSync(t *testing.T) {
+	header := common.FrameHeader{Type: common.FRAME_TYPE_VIDEO, Length: uint32(common.FRAME_MAX_PAYLOAD) + 1}
+	demuxer := common.NewDemuxer(bytes.NewReader(append(frame(common.FRAME_TYPE_VIDEO, 1, []byte{0x47}), header.Marshal()...)))
+
+	_, err := demuxer.Next()
+	assert.Equal(t, nil, err)
+
+	// Once synced, invalid headers mean the stream is out of sync
+	_, err = demuxer.Next()
+	assert.Equal(t, true, errors.Is(err, common.ErrFrameTooLarge))
+	assert.Equal(t, false, errors.Is(err, common.ErrUnknownFraming))
+}
+
+func TestPacketKindString(t *testing.T) {
+	assert.Equal(t, "video", common.PacketKindVideo.String())
+	assert.Equal(t, "audio", common.PacketKindAudio.String())
+	assert.Equal(t, "control", common.PacketKindControl.String())
+	assert.Equal(t, "unknown", common.PacketKindUnknown.String())
+}
@@ -0,0 +1,224 @@
+package common
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// HANDSHAKE_HEADER_LENGTH is the value of the length field that opens the handshake
+const HANDSHAKE_HEADER_LENGTH = 0x28
+
+// CONNECTION_ID_LENGTH is the length of the connection IDs issued by Blink
+const CONNECTION_ID_LENGTH = 16
+
+// KEEPALIVE_SEQUENCE is the sequence number sent with every keep-alive
+const KEEPALIVE_SEQUENCE = 1000
+
+// KEEPALIVE_PAYLOAD_SIZE is the size of the keep-alive payload following the frame header
+const KEEPALIVE_PAYLOAD_SIZE = 24
+
+// The sizes of the fixed handshake frames
+const (
+	handshakeHeaderSize     = 24
+	handshakeClientIdSize   = 4
+	handshakeConnectionSize = 74
+	handshakeStartSize      = 13
+)
+
+// Handshake is the sequence of frames that authenticates a client on the liveview TCP stream.
+// Fields without a known meaning keep the values observed from the official apps.
+type Handshake struct {
+	// The length field of the first frame. Always HANDSHAKE_HEADER_LENGTH
+	HeaderLength uint32
+	// The client ID from the liveview connection string
+	ClientId uint32
+	// The two bytes opening the connection frame. Meaning unknown, observed as 0x01 0x08
+	ConnectionFlags [2]byte
+	// The announced length of the connection ID. Always CONNECTION_ID_LENGTH
+	ConnectionIdLength uint16
+	// The connection ID from the liveview connection string
+	ConnectionId string
+	// The first field of the closing frame. Meaning unknown, observed as 1
+	StartValue uint32
+	// The second field of the closing frame. Meaning unknown, observed as 0x0a
+	StartFlags byte
+}
+
+// NewHandshake creates the handshake for a liveview connection, with the observed values for the unknown fields
+//
+// connectionId: the connection ID from the liveview connection string
+//
+// clientId: the client ID from the liveview connection string
+//
+// Example: NewHandshake("Cy5gwipn7Bui8L7z", 123)
+func NewHandshake(connectionId string, clientId int) *Handshake {
+	return &Handshake{
+		HeaderLength:       HANDSHAKE_HEADER_LENGTH,
+		ClientId:           uint32(clientId),
+		ConnectionFlags:    [2]byte{0x01, 0x08},
+		ConnectionIdLength: CONNECTION_ID_LENGTH,
+		ConnectionId:       connectionId,
+		StartValue:         1,
+		StartFlags:         0x0a,
+	}
+}
+
+// Frames encodes the handshake as the five frames sent by the client, in order
+//
+// Example: NewHandshake("Cy5gwipn7Bui8L7z", 123).Frames() = [][]byte{{0x00, 0x00, 0x00, 0x28, ...}, ...}
+func (h *Handshake) Frames() [][]byte {
+	header := make([]byte, handshakeHeaderSize)
+	binary.BigEndian.PutUint32(header[0:4], h.HeaderLength)
+
+	clientId := make([]byte, handshakeClientIdSize)
+	binary.BigEndian.PutUint32(clientId, h.ClientId)
+
+	connection := make([]byte, handshakeConnectionSize)
+	copy(connection[0:2], h.ConnectionFlags[:])
+	binary.BigEndian.PutUint16(connection[handshakeConnectionSize-2:], h.ConnectionIdLength)
+
+	start := make([]byte, handshakeStartSize)
+	binary.BigEndian.PutUint32(start[0:4], h.StartValue)
+	start[4] = h.StartFlags
+
+	return [][]byte{header, clientId, connection, []byte(h.ConnectionId), start}
+}
+
+// Marshal encodes the handshake as the bytes sent on the wire
+//
+// Example: NewHandshake("Cy5gwipn7Bui8L7z", 123).Marshal() = []byte{0x00, 0x00, 0x00, 0x28, ...}
+func (h *Handshake) Marshal() []byte {
+	return bytes.Join(h.Frames(), nil)
+}
+
+// Unmarshal decodes a complete handshake. Trailing bytes are rejected.
+//
+// data: the handshake bytes
+//
+// Example: handshake.Unmarshal(data) = nil
+func (h *Handshake) Unmarshal(data []byte) error {
+	reader := bytes.NewReader(data)
+	handshake, err := ReadHandshake(reader)
+	if err != nil {
+		return err
+	}
+	if reader.Len() > 0 {
+		return fmt.Errorf("unexpected %d bytes after the handshake", reader.Len())
+	}
+
+	*h = *handshake
+
+	return nil
+}
+
+// Validate reports the first field that differs from what Blink expects
+//
+// Example: NewHandshake("ABC", 123).Validate() = error
+func (h *Handshake) Validate() error {
+	expected := NewHandshake(h.ConnectionId, int(h.ClientId))
+
+	switch {
+	case h.HeaderLength != expected.HeaderLength:
+		return fmt.Errorf("unexpected handshake header length 0x%02x", h.HeaderLength)
+	case h.ClientId == 0:
+		return fmt.Errorf("missing client ID")
+	case h.ConnectionFlags != expected.ConnectionFlags:
+		return fmt.Errorf("unexpected connection flags %x", h.ConnectionFlags)
+	case h.ConnectionIdLength != expected.ConnectionIdLength:
+		return fmt.Errorf("unexpected connection ID length %d", h.ConnectionIdLength)
+	case len(h.ConnectionId) != CONNECTION_ID_LENGTH:
+		return fmt.Errorf("connection ID must be %d characters, got %d", CONNECTION_ID_LENGTH, len(h.ConnectionId))
+	case h.StartValue != expected.StartValue || h.StartFlags != expected.StartFlags:
+		return fmt.Errorf("unexpected start frame %d/0x%02x", h.StartValue, h.StartFlags)
+	}
+
+	return nil
+}
+
+// ReadHandshake reads a handshake from the start of a client connection (e.g. in a fake liveview server).
+// The connection ID is read using the announced length.
+//
+// reader: the client connection
+//
+// Example: ReadHandshake(conn) = &Handshake{ClientId: 123, ...}, nil
+func ReadHandshake(reader io.Reader) (*Handshake, error) {
+	fixed := make([]byte, handshakeHeaderSize+handshakeClientIdSize+handshakeConnectionSize)
+	if _, err := io.ReadFull(reader, fixed); err != nil {
+		return nil, fmt.Errorf("error reading handshake: %w", err)
+	}
+
+	header := fixed[:handshakeHeaderSize]
+	clientId := fixed[handshakeHeaderSize : handshakeHeaderSize+handshakeClientIdSize]
+	connection := fixed[handshakeHeaderSize+handshakeClientIdSize:]
+
+	h := &Handshake{
+		HeaderLength:       binary.BigEndian.Uint32(header[0:4]),
+		ClientId:           binary.BigEndian.Uint32(clientId),
+		ConnectionFlags:    [2]byte{connection[0], connection[1]},
+		ConnectionIdLength: binary.BigEndian.Uint16(connection[handshakeConnectionSize-2:]),
+	}
+
+	rest := make([]byte, int(h.ConnectionIdLength)+handshakeStartSize)
+	if _, err := io.ReadFull(reader, rest); err != nil {
+		return nil, fmt.Errorf("error reading handshake: %w", err)
+	}
+
+	h.ConnectionId = string(rest[:h.ConnectionIdLength])
+	start := rest[h.ConnectionIdLength:]
+	h.StartValue = binary.BigEndian.Uint32(start[0:4])
+	h.StartFlags = start[4]
+
+	return h, nil
+}
+
+// Keepalive is the keep-alive message sent by the client and acknowledged by the server
+type Keepalive struct {
+	// The sequence number in the frame header. Always KEEPALIVE_SEQUENCE for the official apps
+	Sequence uint32
+	// The value at the end of the payload. Meaning unknown, observed as 1
+	Value uint32
+}
+
+// NewKeepalive creates a keep-alive with the observed values
+//
+// Example: NewKeepalive() = &Keepalive{Sequence: 1000, Value: 1}
+func NewKeepalive() *Keepalive {
+	return &Keepalive{Sequence: KEEPALIVE_SEQUENCE, Value: 1}
+}
+
+// Marshal encodes the keep-alive as a framed message
+//
+// Example: NewKeepalive().Marshal() = []byte{0x12, 0x00, 0x00, 0x03, 0xe8, 0x00, 0x00, 0x00, 0x18, ...}
+func (k *Keepalive) Marshal() []byte {
+	header := FrameHeader{Type: FRAME_TYPE_KEEPALIVE, Sequence: k.Sequence, Length: KEEPALIVE_PAYLOAD_SIZE}
+
+	payload := make([]byte, KEEPALIVE_PAYLOAD_SIZE)
+	binary.BigEndian.PutUint32(payload[18:22], k.Value)
+
+	return append(header.Marshal(), payload...)
+}
+
+// Unmarshal decodes a framed keep-alive message
+//
+// data: the frame header and payload
+//
+// Example: keepalive.Unmarshal(FRAMES_KEEPALIVE) = nil
+func (k *Keepalive) Unmarshal(data []byte) error {
+	header, err := ParseFrameHeader(data)
+	if err != nil {
+		return err
+	}
+	if header.Type != FRAME_TYPE_KEEPALIVE {
+		return fmt.Errorf("unexpected message type 0x%02x for a keep-alive", header.Type)
+	}
+	if header.Length != KEEPALIVE_PAYLOAD_SIZE || len(data) != FRAME_HEADER_SIZE+KEEPALIVE_PAYLOAD_SIZE {
+		return fmt.Errorf("unexpected keep-alive length %d", header.Length)
+	}
+
+	k.Sequence = header.Sequence
+	k.Value = binary.BigEndian.Uint32(data[FRAME_HEADER_SIZE+18 : FRAME_HEADER_SIZE+22])
+
+	return nil
+}
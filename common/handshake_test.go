@@ -0,0 +1,81 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestHandshakeRoundTrip(t *testing.T) {
+	handshake := common.NewHandshake("Cy5gwipn7Bui8L7z", 123)
+	assert.Equal(t, handshake.Marshal(), bytes.Join(common.GetTCPAuthFrames("Cy5gwipn7Bui8L7z", 123), nil))
+	assert.Equal(t, handshake.Validate(), nil)
+
+	var decoded common.Handshake
+	assert.Equal(t, decoded.Unmarshal(handshake.Marshal()), nil)
+	assert.Equal(t, &decoded, handshake)
+
+	// Fake servers read the handshake from the connection in small chunks
+	read, err := common.ReadHandshake(iotest.OneByteReader(bytes.NewReader(handshake.Marshal())))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, read, handshake)
+}
+
+func TestHandshakeVariedFields(t *testing.T) {
+	handshake := common.NewHandshake("ABCDEFGH", 987654)
+	handshake.ConnectionIdLength = 8
+	handshake.StartFlags = 0x0b
+
+	var decoded common.Handshake
+	assert.Equal(t, decoded.Unmarshal(handshake.Marshal()), nil)
+	assert.Equal(t, decoded.ClientId, uint32(987654))
+	assert.Equal(t, decoded.ConnectionId, "ABCDEFGH")
+	assert.Equal(t, decoded.StartFlags, byte(0x0b))
+	assert.NotEqual(t, decoded.Validate(), nil)
+}
+
+func TestHandshakeValidate(t *testing.T) {
+	assert.NotEqual(t, common.NewHandshake("ABC", 123).Validate(), nil)
+	assert.NotEqual(t, common.NewHandshake("Cy5gwipn7Bui8L7z", 0).Validate(), nil)
+
+	handshake := common.NewHandshake("Cy5gwipn7Bui8L7z", 123)
+	handshake.HeaderLength = 0x29
+	assert.NotEqual(t, handshake.Validate(), nil)
+}
+
+func TestHandshakeTruncated(t *testing.T) {
+	data := common.NewHandshake("Cy5gwipn7Bui8L7z", 123).Marshal()
+
+	_, err := common.ReadHandshake(bytes.NewReader(data[:len(data)-1]))
+	assert.Equal(t, errors.Is(err, io.ErrUnexpectedEOF), true)
+
+	var decoded common.Handshake
+	assert.NotEqual(t, decoded.Unmarshal(append(data, 0x00)), nil)
+}
+
+func TestKeepaliveRoundTrip(t *testing.T) {
+	var keepalive common.Keepalive
+	assert.Equal(t, keepalive.Unmarshal(common.FRAMES_KEEPALIVE), nil)
+	assert.Equal(t, keepalive, *common.NewKeepalive())
+
+	varied := common.Keepalive{Sequence: 1001, Value: 7}
+	assert.Equal(t, keepalive.Unmarshal(varied.Marshal()), nil)
+	assert.Equal(t, keepalive, varied)
+
+	header, err := common.ParseFrameHeader(varied.Marshal())
+	assert.Equal(t, err, nil)
+	assert.Equal(t, header.Type, byte(common.FRAME_TYPE_KEEPALIVE))
+	assert.Equal(t, header.Sequence, uint32(1001))
+}
+
+func TestKeepaliveInvalid(t *testing.T) {
+	var keepalive common.Keepalive
+	assert.NotEqual(t, keepalive.Unmarshal(frame(common.FRAME_TYPE_VIDEO, 1000, make([]byte, 24))), nil)
+	assert.NotEqual(t, keepalive.Unmarshal(frame(common.FRAME_TYPE_KEEPALIVE, 1000, make([]byte, 23))), nil)
+	assert.NotEqual(t, keepalive.Unmarshal(common.FRAMES_KEEPALIVE[:5]), nil)
+}
@@ -0,0 +1,366 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HEALTH_POLL_INTERVAL is the default interval between homescreen polls of the health monitor
+var HEALTH_POLL_INTERVAL = 5 * time.Minute
+
+type HealthThresholds struct {
+	// Alert when the battery level drops below this many bars. 0 disables the check
+	MinBattery int `json:"min_battery"`
+	// Alert when the Wi-Fi signal drops below this many bars. 0 disables the check
+	MinWifi int `json:"min_wifi"`
+	// Alert when the sync module (LFR) signal drops below this many bars. 0 disables the check
+	MinLfr int `json:"min_lfr"`
+	// Alert when the temperature drops below this value in degrees Fahrenheit
+	MinTemperature int `json:"min_temperature"`
+	// Alert when the temperature rises above this value in degrees Fahrenheit
+	MaxTemperature int `json:"max_temperature"`
+}
+
+// DefaultHealthThresholds returns thresholds matching the operating range of Blink cameras
+//
+// Example: DefaultHealthThresholds() = HealthThresholds{MinBattery: 2, ...}
+func DefaultHealthThresholds() HealthThresholds {
+	return HealthThresholds{
+		MinBattery:     2,
+		MinWifi:        2,
+		MinLfr:         2,
+		MinTemperature: -4,
+		MaxTemperature: 113,
+	}
+}
+
+type DeviceHealth struct {
+	DeviceType string `json:"device_type"`
+	DeviceId   int    `json:"device_id"`
+	Name       string `json:"name"`
+	NetworkId  int    `json:"network_id"`
+	Online     bool   `json:"online"`
+	// The battery state reported by Blink (e.g. ok, low). Empty for wired devices
+	Battery string `json:"battery,omitempty"`
+	// The signal levels in bars. Zero when not reported by the device
+	BatteryLevel int `json:"battery_level"`
+	Wifi         int `json:"wifi"`
+	Lfr          int `json:"lfr"`
+	// The temperature in degrees Fahrenheit. Nil for devices without a sensor
+	Temperature *int `json:"temperature,omitempty"`
+	// The alerts currently active on the device
+	Alerts []string `json:"alerts"`
+}
+
+type HealthEvent struct {
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event"`
+	DeviceType string    `json:"device_type"`
+	DeviceId   int       `json:"device_id"`
+	Name       string    `json:"name"`
+	NetworkId  int       `json:"network_id"`
+	Previous   any       `json:"previous,omitempty"`
+	Current    any       `json:"current,omitempty"`
+	// True when the current value breaches a threshold
+	Alert   bool   `json:"alert"`
+	Message string `json:"message"`
+}
+
+type HealthStatus struct {
+	UpdatedAt time.Time      `json:"updated_at"`
+	Error     string         `json:"error,omitempty"`
+	Devices   []DeviceHealth `json:"devices"`
+}
+
+type HealthMonitor struct {
+	Client     *BlinkClient
+	Interval   time.Duration
+	Thresholds HealthThresholds
+	// Called for every change event. Optional
+	OnEvent func(HealthEvent)
+
+	mu        sync.RWMutex
+	devices   map[string]DeviceHealth
+	updatedAt time.Time
+	lastErr   error
+}
+
+// NewHealthMonitor creates a monitor that polls the homescreen of the client's account
+//
+// client: the Blink API client holding the token, account ID and region
+//
+// interval: the interval between polls. Defaults to HEALTH_POLL_INTERVAL when zero
+//
+// thresholds: the thresholds that raise alerts
+//
+// Example: NewHealthMonitor(client, time.Minute, DefaultHealthThresholds())
+func NewHealthMonitor(client *BlinkClient, interval time.Duration, thresholds HealthThresholds) *HealthMonitor {
+	if interval <= 0 {
+		interval = HEALTH_POLL_INTERVAL
+	}
+
+	return &HealthMonitor{
+		Client:     client,
+		Interval:   interval,
+		Thresholds: thresholds,
+	}
+}
+
+// Run polls the homescreen until the context is cancelled.
+// Transient errors are kept for Status, but an invalid token stops the monitor.
+//
+// ctx: the context to use for the monitor
+//
+// Example: monitor.Run(ctx) = nil
+func (m *HealthMonitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := m.Poll(ctx); IsUnauthorized(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fetches the homescreen once, updates the tracked state and returns the change events.
+// The first poll only reports devices that already breach a threshold.
+//
+// ctx: the context to use for the request
+//
+// Example: monitor.Poll(ctx) = []HealthEvent{{Event: "battery", ...}}, nil
+func (m *HealthMonitor) Poll(ctx context.Context) ([]HealthEvent, error) {
+	homescreen, err := m.Client.Homescreen(ctx, m.Client.HomescreenUrl())
+	if err != nil {
+		m.mu.Lock()
+		m.lastErr = err
+		m.mu.Unlock()
+
+		return nil, fmt.Errorf("error getting homescreen: %w", err)
+	}
+
+	now := time.Now()
+	next := m.collect(homescreen)
+
+	m.mu.Lock()
+	events := diffHealth(m.devices, next, now)
+	m.devices = next
+	m.updatedAt = now
+	m.lastErr = nil
+	m.mu.Unlock()
+
+	if m.OnEvent != nil {
+		for _, event := range events {
+			m.OnEvent(event)
+		}
+	}
+
+	return events, nil
+}
+
+// Status returns the latest state of every device, sorted by network and device
+//
+// Example: monitor.Status() = HealthStatus{Devices: []DeviceHealth{...}}
+func (m *HealthMonitor) Status() HealthStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := HealthStatus{
+		UpdatedAt: m.updatedAt,
+		Devices:   make([]DeviceHealth, 0, len(m.devices)),
+	}
+	if m.lastErr != nil {
+		status.Error = m.lastErr.Error()
+	}
+
+	for _, device := range m.devices {
+		status.Devices = append(status.Devices, device)
+	}
+	sort.Slice(status.Devices, func(i, j int) bool {
+		a, b := status.Devices[i], status.Devices[j]
+		if a.NetworkId != b.NetworkId {
+			return a.NetworkId < b.NetworkId
+		}
+		if a.DeviceType != b.DeviceType {
+			return a.DeviceType < b.DeviceType
+		}
+		return a.DeviceId < b.DeviceId
+	})
+
+	return status
+}
+
+// collect builds the health of every device in the homescreen
+func (m *HealthMonitor) collect(homescreen *HomescreenResponse) map[string]DeviceHealth {
+	devices := make(map[string]DeviceHealth)
+
+	add := func(deviceType string, list []BaseCameraDevice) {
+		for _, device := range list {
+			health := DeviceHealth{
+				DeviceType:   deviceType,
+				DeviceId:     device.Id,
+				Name:         device.Name,
+				NetworkId:    device.NetworkId,
+				Online:       device.Status != "offline",
+				Battery:      device.Battery,
+				BatteryLevel: device.Signals.Battery,
+				Wifi:         device.Signals.Wifi,
+				Lfr:          device.Signals.Lfr,
+				Temperature:  device.Signals.Temperature,
+			}
+			health.Alerts = m.Thresholds.alerts(health)
+			devices[healthKey(health)] = health
+		}
+	}
+	add("camera", homescreen.Cameras)
+	add("owl", homescreen.Owls)
+	add("doorbell", homescreen.Doorbells)
+
+	for _, syncModule := range homescreen.SyncModules {
+		health := DeviceHealth{
+			DeviceType: "sync_module",
+			DeviceId:   syncModule.Id,
+			Name:       syncModule.Name,
+			NetworkId:  syncModule.NetworkId,
+			Online:     syncModule.Status != "offline",
+		}
+		health.Alerts = m.Thresholds.alerts(health)
+		devices[healthKey(health)] = health
+	}
+
+	return devices
+}
+
+// alerts returns the checks that the device currently fails
+func (t HealthThresholds) alerts(health DeviceHealth) []string {
+	alerts := []string{}
+	if !health.Online {
+		alerts = append(alerts, "online")
+	}
+	if health.Battery == "low" || (health.BatteryLevel > 0 && health.BatteryLevel < t.MinBattery) {
+		alerts = append(alerts, "battery")
+	}
+	if health.Wifi > 0 && health.Wifi < t.MinWifi {
+		alerts = append(alerts, "wifi")
+	}
+	if health.Lfr > 0 && health.Lfr < t.MinLfr {
+		alerts = append(alerts, "lfr")
+	}
+	if health.Temperature != nil && (*health.Temperature < t.MinTemperature || *health.Temperature > t.MaxTemperature) {
+		alerts = append(alerts, "temperature")
+	}
+
+	return alerts
+}
+
+// diffHealth compares two polls and returns the change events.
+// When prev is nil (the first poll), only breached thresholds are reported.
+func diffHealth(prev map[string]DeviceHealth, next map[string]DeviceHealth, now time.Time) []HealthEvent {
+	var events []HealthEvent
+
+	keys := make([]string, 0, len(next))
+	for key := range next {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		current := next[key]
+		previous, seen := prev[key]
+
+		if prev != nil && !seen {
+			events = append(events, newHealthEvent(now, "added", current, nil, nil, false, "device added"))
+		}
+
+		checks := []struct {
+			name     string
+			changed  bool
+			previous any
+			current  any
+		}{
+			{"online", previous.Online != current.Online, previous.Online, current.Online},
+			{"battery", previous.BatteryLevel != current.BatteryLevel || previous.Battery != current.Battery, previous.BatteryLevel, current.BatteryLevel},
+			{"wifi", previous.Wifi != current.Wifi, previous.Wifi, current.Wifi},
+			{"lfr", previous.Lfr != current.Lfr, previous.Lfr, current.Lfr},
+			{"temperature", derefInt(previous.Temperature) != derefInt(current.Temperature), derefInt(previous.Temperature), derefInt(current.Temperature)},
+		}
+		for _, check := range checks {
+			alert := slices.Contains(current.Alerts, check.name)
+			if seen && !check.changed {
+				continue
+			}
+			if !seen && !alert {
+				continue
+			}
+
+			var previousValue any
+			if seen {
+				previousValue = check.previous
+			}
+
+			events = append(events, newHealthEvent(now, check.name, current, previousValue, check.current, alert, healthMessage(check.name, alert)))
+		}
+	}
+
+	removed := make([]string, 0)
+	for key := range prev {
+		if _, ok := next[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(removed)
+	for _, key := range removed {
+		events = append(events, newHealthEvent(now, "removed", prev[key], nil, nil, false, "device removed"))
+	}
+
+	return events
+}
+
+// newHealthEvent builds a change event for the device
+func newHealthEvent(now time.Time, event string, device DeviceHealth, previous any, current any, alert bool, message string) HealthEvent {
+	return HealthEvent{
+		Time:       now,
+		Event:      event,
+		DeviceType: device.DeviceType,
+		DeviceId:   device.DeviceId,
+		Name:       device.Name,
+		NetworkId:  device.NetworkId,
+		Previous:   previous,
+		Current:    current,
+		Alert:      alert,
+		Message:    message,
+	}
+}
+
+// healthMessage describes a change of the check
+func healthMessage(check string, alert bool) string {
+	if alert {
+		return fmt.Sprintf("%s check failed", check)
+	}
+
+	return fmt.Sprintf("%s changed", check)
+}
+
+// healthKey identifies a device across polls
+func healthKey(health DeviceHealth) string {
+	return fmt.Sprintf("%s/%d", health.DeviceType, health.DeviceId)
+}
+
+// derefInt returns the value of an optional int, or nil
+func derefInt(value *int) any {
+	if value == nil {
+		return nil
+	}
+
+	return *value
+}
@@ -0,0 +1,126 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestHealthMonitorPoll(t *testing.T) {
+	responses := []string{
+		`{"owls": [{"id": 3, "network_id": 2, "name": "Porch", "status": "online", "battery": "ok", "signals": {"wifi": 4, "battery": 1, "temp": 70}}],
+		  "sync_modules": [{"id": 4, "network_id": 2, "name": "Sync", "status": "online"}]}`,
+		`{"owls": [{"id": 3, "network_id": 2, "name": "Porch", "status": "offline", "battery": "ok", "signals": {"wifi": 1, "battery": 1, "temp": 70}}],
+		  "cameras": [{"id": 5, "network_id": 2, "name": "Garage", "status": "done", "signals": {"wifi": 3, "lfr": 3, "battery": 3, "temp": 50}}]}`,
+	}
+	var poll int
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v4/accounts/1/homescreen", r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(responses[poll]))
+		poll++
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	client.RestUrl = mockServer.URL
+
+	var received []common.HealthEvent
+	monitor := common.NewHealthMonitor(client, time.Minute, common.DefaultHealthThresholds())
+	monitor.OnEvent = func(event common.HealthEvent) {
+		received = append(received, event)
+	}
+
+	// The first poll only reports breached thresholds
+	events, err := monitor.Poll(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, "battery", events[0].Event)
+	assert.Equal(t, true, events[0].Alert)
+	assert.Equal(t, nil, events[0].Previous)
+	assert.Equal(t, 1, events[0].Current)
+
+	events, err = monitor.Poll(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 4, len(events))
+
+	assert.Equal(t, "added", events[0].Event)
+	assert.Equal(t, 5, events[0].DeviceId)
+
+	assert.Equal(t, "online", events[1].Event)
+	assert.Equal(t, true, events[1].Alert)
+	assert.Equal(t, true, events[1].Previous)
+	assert.Equal(t, false, events[1].Current)
+
+	assert.Equal(t, "wifi", events[2].Event)
+	assert.Equal(t, true, events[2].Alert)
+	assert.Equal(t, 4, events[2].Previous)
+	assert.Equal(t, 1, events[2].Current)
+
+	assert.Equal(t, "removed", events[3].Event)
+	assert.Equal(t, "sync_module", events[3].DeviceType)
+
+	assert.Equal(t, 5, len(received))
+
+	status := monitor.Status()
+	assert.Equal(t, "", status.Error)
+	assert.Equal(t, 2, len(status.Devices))
+	assert.Equal(t, "camera", status.Devices[0].DeviceType)
+	assert.Equal(t, []string{}, status.Devices[0].Alerts)
+	assert.Equal(t, "owl", status.Devices[1].DeviceType)
+	assert.Equal(t, []string{"online", "battery", "wifi"}, status.Devices[1].Alerts)
+}
+
+func TestHealthMonitorNoTemperatureSensor(t *testing.T) {
+	responses := []string{
+		`{"owls": [{"id": 3, "network_id": 2, "name": "Porch", "status": "online", "signals": {"wifi": 4, "battery": 3}}]}`,
+		`{"owls": [{"id": 3, "network_id": 2, "name": "Porch", "status": "online", "signals": {"wifi": 4, "battery": 3, "temp": 200}}]}`,
+	}
+	var poll int
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(responses[poll]))
+		poll++
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	client.RestUrl = mockServer.URL
+	monitor := common.NewHealthMonitor(client, time.Minute, common.DefaultHealthThresholds())
+
+	// Devices without a sensor have no temperature, rather than 0
+	events, err := monitor.Poll(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(events))
+	assert.Equal(t, true, monitor.Status().Devices[0].Temperature == nil)
+
+	events, err = monitor.Poll(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, "temperature", events[0].Event)
+	assert.Equal(t, nil, events[0].Previous)
+	assert.Equal(t, 200, events[0].Current)
+	assert.Equal(t, true, events[0].Alert)
+}
+
+func TestHealthMonitorPollError(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	client.RestUrl = mockServer.URL
+
+	monitor := common.NewHealthMonitor(client, 0, common.DefaultHealthThresholds())
+	_, err := monitor.Poll(context.Background())
+
+	assert.Equal(t, true, common.IsUnauthorized(err))
+	assert.Equal(t, common.HEALTH_POLL_INTERVAL, monitor.Interval)
+	assert.Equal(t, "HTTP Status Code 401", monitor.Status().Error)
+}
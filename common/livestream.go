@@ -22,7 +22,8 @@ type AccountDetails struct {
 }
 
 // Livestream coordinates the liveview process for a Blink (Immedia Semiconductor) camera.
-// It starts a liveview session, polls the liveview command to keep the connection alive, and connects to the liveview server.
+// It starts a liveview session, supervises the liveview command to keep the connection alive, and connects to the liveview server.
+// The stream is cancelled when Blink marks the command as complete or polling fails, and the command is always stopped on return.
 // Returns an error if any of the steps fail. The connection is closed when the context is cancelled.
 // The io.Writer must be closed by the caller when the stream is finished.
 //
@@ -36,35 +37,82 @@ type AccountDetails struct {
 //
 // Example: Livestream("u011", "example_token", "camera", 1234, 5678, 9012) -> nil
 func Livestream(ctx context.Context, account AccountDetails, writer io.Writer) error {
-	baseUrl := GetApiUrl(account.Region)
-	liveViewPath, err := GetLiveviewPath(account.DeviceType)
+	client := NewBlinkClient(account.Token, account.Region, account.AccountId)
+
+	return client.Livestream(ctx, account.DeviceType, account.NetworkId, account.CameraId, writer)
+}
+
+// Livestream coordinates the liveview process for a camera on the client's account.
+// Refer to the package-level Livestream for details.
+//
+// ctx: the context to use for the liveview session, including cancellation
+//
+// deviceType: the type of device to use for the liveview path
+//
+// networkId: the network ID that the camera is on
+//
+// cameraId: the ID of the camera to start the liveview session for
+//
+// writer: the pipe to write the stream data to
+//
+// Example: client.Livestream(ctx, "owl", 5678, 9012, pipe) -> nil
+func (c *BlinkClient) Livestream(ctx context.Context, deviceType string, networkId int, cameraId int, writer io.Writer) error {
+	return c.LivestreamWithOptions(ctx, deviceType, networkId, cameraId, writer, StreamOptions{})
+}
+
+// LivestreamWithOptions coordinates the liveview process like Livestream, passing the options to the TCP stream
+//
+// ctx: the context to use for the liveview session, including cancellation
+//
+// deviceType: the type of device to use for the liveview path
+//
+// networkId: the network ID that the camera is on
+//
+// cameraId: the ID of the camera to start the liveview session for
+//
+// writer: the pipe to write the stream data to
+//
+// opts: the stream options
+//
+// Example: client.LivestreamWithOptions(ctx, "owl", 5678, 9012, pipe, StreamOptions{...}) -> nil
+func (c *BlinkClient) LivestreamWithOptions(ctx context.Context, deviceType string, networkId int, cameraId int, writer io.Writer, opts StreamOptions) error {
+	baseUrl := c.ApiUrl()
+	liveViewPath, err := GetLiveviewPath(deviceType)
 	if err != nil {
 		return fmt.Errorf("error getting liveview path: %w", err)
 	}
 
 	// Tell Blink we want to start a liveview session
-	resp, err := BeginLiveview(fmt.Sprintf(liveViewPath, baseUrl, account.AccountId, account.NetworkId, account.CameraId), account.Token)
+	resp, err := c.BeginLiveview(ctx, fmt.Sprintf(liveViewPath, baseUrl, c.AccountId, networkId, cameraId))
 	if err != nil {
 		return fmt.Errorf("error starting liveview session: %w", err)
 	} else if resp == nil || resp.CommandId == 0 {
 		return fmt.Errorf("error sending liveview command: %v", resp)
 	}
 
-	// Poll the liveview command to keep the connection alive
-	go PollCommand(ctx, fmt.Sprintf("%s/network/%d/command/%d", baseUrl, account.NetworkId, resp.CommandId), account.Token, resp.PollingInterval)
-	defer StopCommand(fmt.Sprintf("%s/network/%d/command/%d/done", baseUrl, account.NetworkId, resp.CommandId), account.Token)
+	// Keep the liveview command alive. The stream ends as soon as Blink completes the command or polling fails
+	supervisor := c.NewCommandSupervisor(networkId, resp.CommandId, resp.PollingInterval)
+	ctx = supervisor.Start(ctx)
+	defer supervisor.Stop()
 
 	// Get the connection details
 	connectionDetails, err := ParseConnectionString(resp.Server)
 	if err != nil {
-		return fmt.Errorf("connection string parsing error: %w", err)
+		return fmt.Errorf("%w: %w", ErrInvalidConnectionString, err)
 	}
 
 	// Connect to the liveview server
-	if err := TCPStream(ctx, *connectionDetails, writer); err != nil {
+	if err := TCPStreamWithOptions(ctx, *connectionDetails, writer, opts); err != nil {
+		if supervisorErr := supervisor.Err(); supervisorErr != nil {
+			return fmt.Errorf("liveview command ended: %w", supervisorErr)
+		}
 		return fmt.Errorf("TCPStream error: %w", err)
 	}
 
 	<-ctx.Done()
+	if err := supervisor.Err(); err != nil {
+		return fmt.Errorf("liveview command ended: %w", err)
+	}
+
 	return nil
 }
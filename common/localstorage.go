@@ -0,0 +1,133 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type LocalStorageClip struct {
+	Id         string    `json:"id"`
+	Size       string    `json:"size"`
+	CameraName string    `json:"camera_name"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type LocalStorageManifest struct {
+	Version    string             `json:"version"`
+	ManifestId string             `json:"manifest_id"`
+	Clips      []LocalStorageClip `json:"clips"`
+}
+
+// localStorageUrl returns the base URL of the local storage API for a sync module
+//
+// networkId: the network ID that the sync module is on
+//
+// syncModuleId: the ID of the sync module
+//
+// Example: localStorageUrl(1234, 5678) = "https://rest-u011.immedia-semi.com/api/v1/accounts/1/networks/1234/sync_modules/5678/local_storage"
+func (c *BlinkClient) localStorageUrl(networkId int, syncModuleId int) string {
+	return fmt.Sprintf("%s/api/v1/accounts/%d/networks/%d/sync_modules/%d/local_storage", c.ApiUrl(), c.AccountId, networkId, syncModuleId)
+}
+
+// LocalStorageManifest asks the sync module to build a manifest of the clips on its USB storage,
+// waits for the command to complete and returns the manifest
+//
+// ctx: the context to use while waiting for the command
+//
+// networkId: the network ID that the sync module is on
+//
+// syncModuleId: the ID of the sync module
+//
+// Example: client.LocalStorageManifest(ctx, 1234, 5678) = &LocalStorageManifest{ManifestId: "4321"}, nil
+func (c *BlinkClient) LocalStorageManifest(ctx context.Context, networkId int, syncModuleId int) (*LocalStorageManifest, error) {
+	requestUrl := c.localStorageUrl(networkId, syncModuleId) + "/manifest/request"
+
+	cmd, err := c.startCommand(ctx, requestUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting local storage manifest: %w", err)
+	}
+
+	if _, err := c.WaitForCommand(ctx, c.commandUrl(networkId, cmd.Id), COMMAND_POLL_INTERVAL); err != nil {
+		return nil, fmt.Errorf("error waiting for local storage manifest: %w", err)
+	}
+
+	req, err := c.newRequest(ctx, "GET", fmt.Sprintf("%s/%d", requestUrl, cmd.Id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.doRetry(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	var result LocalStorageManifest
+	if err := decodeBody(resp, &result); err != nil {
+		return nil, err
+	}
+	if result.ManifestId == "" {
+		return nil, fmt.Errorf("local storage manifest response did not include a manifest ID")
+	}
+
+	return &result, nil
+}
+
+// DownloadLocalStorageClip asks the sync module to upload a clip from its USB storage,
+// waits for the command to complete and streams the MP4 to the writer
+//
+// ctx: the context to use while waiting for the command
+//
+// networkId: the network ID that the sync module is on
+//
+// syncModuleId: the ID of the sync module
+//
+// manifestId: the ID of the manifest the clip was listed in
+//
+// clipId: the ID of the clip to download
+//
+// writer: the writer to copy the MP4 to
+//
+// Example: client.DownloadLocalStorageClip(ctx, 1234, 5678, "4321", "866333964", file) = 1048576, nil
+func (c *BlinkClient) DownloadLocalStorageClip(ctx context.Context, networkId int, syncModuleId int, manifestId string, clipId string, writer io.Writer) (int64, error) {
+	clipUrl := fmt.Sprintf("%s/manifest/%s/clip/request/%s", c.localStorageUrl(networkId, syncModuleId), url.PathEscape(manifestId), url.PathEscape(clipId))
+
+	cmd, err := c.startCommand(ctx, clipUrl, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error requesting local storage clip: %w", err)
+	}
+
+	if _, err := c.WaitForCommand(ctx, c.commandUrl(networkId, cmd.Id), COMMAND_POLL_INTERVAL); err != nil {
+		return 0, fmt.Errorf("error waiting for local storage clip: %w", err)
+	}
+
+	n, err := c.Download(ctx, clipUrl, writer)
+	if err != nil {
+		return n, fmt.Errorf("error downloading local storage clip: %w", err)
+	}
+
+	return n, nil
+}
+
+// FindClip returns the clip with the given ID from the manifest
+//
+// clipId: the ID of the clip to find
+//
+// Example: manifest.FindClip("866333964") = &LocalStorageClip{Id: "866333964"}, true
+func (m *LocalStorageManifest) FindClip(clipId string) (*LocalStorageClip, bool) {
+	for i := range m.Clips {
+		if m.Clips[i].Id == clipId {
+			return &m.Clips[i], true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,115 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestLocalStorageManifestNominal(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/accounts/1/networks/2/sync_modules/3/local_storage/manifest/request", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": 999, "network_id": 2}`))
+	})
+	mux.HandleFunc("GET /network/2/command/999", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"complete": true}`))
+	})
+	mux.HandleFunc("GET /api/v1/accounts/1/networks/2/sync_modules/3/local_storage/manifest/request/999", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"version": "1.0", "manifest_id": "4321", "clips": [
+			{"id": "866333964", "size": "234", "camera_name": "Back", "created_at": "2025-07-13T17:30:23+00:00"}
+		]}`))
+	})
+	mockServer := httptest.NewServer(mux)
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	client.RestUrl = mockServer.URL
+
+	manifest, err := client.LocalStorageManifest(context.Background(), 2, 3)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "4321", manifest.ManifestId)
+	assert.Equal(t, 1, len(manifest.Clips))
+	assert.Equal(t, "Back", manifest.Clips[0].CameraName)
+
+	clip, ok := manifest.FindClip("866333964")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "234", clip.Size)
+
+	_, ok = manifest.FindClip("1")
+	assert.Equal(t, false, ok)
+}
+
+func TestLocalStorageManifestBusy(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"code": 307, "message": "System is busy, please wait"}`))
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	client.RestUrl = mockServer.URL
+
+	_, err := client.LocalStorageManifest(context.Background(), 2, 3)
+
+	assert.Equal(t, true, common.IsDeviceBusy(err))
+}
+
+func TestDownloadLocalStorageClipNominal(t *testing.T) {
+	var uploadRequested bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/accounts/1/networks/2/sync_modules/3/local_storage/manifest/4321/clip/request/866333964", func(w http.ResponseWriter, r *http.Request) {
+		uploadRequested = true
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": 1000, "network_id": 2}`))
+	})
+	mux.HandleFunc("GET /network/2/command/1000", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"complete": true}`))
+	})
+	mux.HandleFunc("GET /api/v1/accounts/1/networks/2/sync_modules/3/local_storage/manifest/4321/clip/request/866333964", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("mp4-data"))
+	})
+	mockServer := httptest.NewServer(mux)
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	client.RestUrl = mockServer.URL
+
+	var clip bytes.Buffer
+	n, err := client.DownloadLocalStorageClip(context.Background(), 2, 3, "4321", "866333964", &clip)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, uploadRequested)
+	assert.Equal(t, int64(8), n)
+	assert.Equal(t, "mp4-data", clip.String())
+}
+
+func TestDownloadLocalStorageClipEscapesIds(t *testing.T) {
+	var paths []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	client.RestUrl = mockServer.URL
+	client.Retry = nil
+
+	// IDs cannot change which endpoint is called
+	_, err := client.DownloadLocalStorageClip(context.Background(), 2, 3, "4321", "../../../media?x#y", io.Discard)
+
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, []string{"/api/v1/accounts/1/networks/2/sync_modules/3/local_storage/manifest/4321/clip/request/..%2F..%2F..%2Fmedia%3Fx%23y"}, paths)
+}
@@ -0,0 +1,186 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrLoginRejected is returned when the Blink API rejects the credentials or verification code
+var ErrLoginRejected = errors.New("login rejected")
+
+// ErrInvalidCode is returned by LoginFlow.SubmitCode when the verification code is incorrect
+var ErrInvalidCode = errors.New("invalid verification code")
+
+// MAX_CODE_ATTEMPTS is the number of incorrect verification codes accepted before the flow fails
+var MAX_CODE_ATTEMPTS = 5
+
+type LoginState int
+
+const (
+	// The flow has not contacted the Blink API yet
+	LoginStateStart LoginState = iota
+	// A verification code was sent and must be submitted with SubmitCode
+	LoginStateAwaitingCode
+	// The login finished and LoginFlow.Response holds the tokens
+	LoginStateComplete
+	// The login cannot continue. A new LoginFlow is required
+	LoginStateFailed
+)
+
+// String returns the name of the login state
+//
+// Example: LoginStateAwaitingCode.String() = "awaiting_code"
+func (s LoginState) String() string {
+	switch s {
+	case LoginStateStart:
+		return "start"
+	case LoginStateAwaitingCode:
+		return "awaiting_code"
+	case LoginStateComplete:
+		return "complete"
+	case LoginStateFailed:
+		return "failed"
+	}
+
+	return "unknown"
+}
+
+type LoginFlow struct {
+	// The current state of the flow
+	State LoginState
+	// The verification method requested by Blink (e.g. "sms", "email"). Empty if not required
+	Method string
+	// The masked phone number the SMS code was sent to (if applicable)
+	Phone string
+	// The earliest time a new verification code can be requested
+	ResendAfter time.Time
+	// The number of incorrect verification codes submitted
+	Attempts int
+	// The final OAuth response. Only set once the state is LoginStateComplete
+	Response *LoginResponse
+
+	client      *BlinkClient
+	email       string
+	password    string
+	fingerprint *Fingerprint
+}
+
+// NewLoginFlow creates a login state machine for the provided credentials
+//
+// client: the client used to reach the OAuth endpoint
+//
+// email: the Blink account email address
+//
+// password: the Blink account password
+//
+// fp: the fingerprint to identify this client with
+//
+// Example: NewLoginFlow(client, "x", "y", fingerprint)
+func NewLoginFlow(client *BlinkClient, email string, password string, fp *Fingerprint) *LoginFlow {
+	return &LoginFlow{
+		State:       LoginStateStart,
+		client:      client,
+		email:       email,
+		password:    password,
+		fingerprint: fp,
+	}
+}
+
+// Start submits the credentials and moves the flow to LoginStateAwaitingCode or LoginStateComplete
+//
+// ctx: the context to use for the request
+//
+// Example: Start(ctx) = LoginStateAwaitingCode, nil
+func (f *LoginFlow) Start(ctx context.Context) (LoginState, error) {
+	if f.State != LoginStateStart {
+		return f.State, fmt.Errorf("cannot start login in state %s", f.State)
+	}
+
+	if err := f.submit(ctx, ""); err != nil {
+		f.State = LoginStateFailed
+		return f.State, err
+	}
+
+	return f.State, nil
+}
+
+// SubmitCode submits the verification code sent by Blink.
+// An incorrect code leaves the flow awaiting a code and returns ErrInvalidCode.
+//
+// ctx: the context to use for the request
+//
+// code: the verification code to submit
+//
+// Example: SubmitCode(ctx, "123456") = LoginStateComplete, nil
+func (f *LoginFlow) SubmitCode(ctx context.Context, code string) (LoginState, error) {
+	if f.State != LoginStateAwaitingCode {
+		return f.State, fmt.Errorf("cannot submit a code in state %s", f.State)
+	}
+	if code == "" {
+		return f.State, ErrInvalidCode
+	}
+
+	err := f.submit(ctx, code)
+	if errors.Is(err, ErrLoginRejected) {
+		f.Attempts++
+		if f.Attempts >= MAX_CODE_ATTEMPTS {
+			f.State = LoginStateFailed
+			return f.State, fmt.Errorf("too many incorrect verification codes: %w", ErrInvalidCode)
+		}
+
+		return f.State, ErrInvalidCode
+	} else if err != nil {
+		f.State = LoginStateFailed
+		return f.State, err
+	}
+
+	if f.State != LoginStateComplete {
+		f.State = LoginStateFailed
+		return f.State, fmt.Errorf("verification code accepted but no access token was returned")
+	}
+
+	return f.State, nil
+}
+
+// Resend requests a new verification code from Blink
+//
+// ctx: the context to use for the request
+//
+// Example: Resend(ctx) = nil
+func (f *LoginFlow) Resend(ctx context.Context) error {
+	if f.State != LoginStateAwaitingCode {
+		return fmt.Errorf("cannot resend a code in state %s", f.State)
+	}
+	if wait := time.Until(f.ResendAfter); wait > 0 {
+		return fmt.Errorf("a new code can be requested in %d seconds", int(wait.Seconds())+1)
+	}
+
+	return f.submit(ctx, "")
+}
+
+// submit sends the credentials (and code) and updates the state from the response
+func (f *LoginFlow) submit(ctx context.Context, code string) error {
+	resp, err := f.client.Login(ctx, f.email, f.password, code, f.fingerprint)
+	if err != nil {
+		return err
+	}
+
+	if resp.AccessToken != "" {
+		f.State = LoginStateComplete
+		f.Response = resp
+		return nil
+	}
+
+	if resp.TwoStepVerification == "" {
+		return fmt.Errorf("login response did not include an access token or verification state")
+	}
+
+	f.State = LoginStateAwaitingCode
+	f.Method = resp.TwoStepVerification
+	f.Phone = resp.Phone
+	f.ResendAfter = time.Now().Add(time.Duration(resp.NextTimeInSeconds) * time.Second)
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+// newLoginServer returns a mock OAuth server that requires the provided code (if any)
+func newLoginServer(t *testing.T, method string, validCode string) (*httptest.Server, *int) {
+	var requests int
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		code := r.Header.Get("2fa-code")
+
+		if method != "" && code == "" {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			w.Write([]byte(`{"tsv_state": "` + method + `", "phone": "+1******1234", "next_time_in_seconds": 0}`))
+			return
+		}
+		if method != "" && code != validCode {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"access_token":"xyz-auth-token","expires_in":3600,"refresh_token":"r1"}`))
+	}))
+	t.Cleanup(mockServer.Close)
+
+	return mockServer, &requests
+}
+
+func newLoginFlow(serverUrl string) *common.LoginFlow {
+	client := common.NewBlinkClient("", "", 0)
+	client.OAuthUrl = serverUrl
+
+	return common.NewLoginFlow(client, "email", "password", &common.Fingerprint{Value: "mock-fingerprint"})
+}
+
+func TestLoginFlowNoVerification(t *testing.T) {
+	mockServer, _ := newLoginServer(t, "", "")
+	flow := newLoginFlow(mockServer.URL)
+
+	state, err := flow.Start(context.Background())
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, common.LoginStateComplete, state)
+	assert.Equal(t, "xyz-auth-token", flow.Response.AccessToken)
+}
+
+func TestLoginFlowSms(t *testing.T) {
+	mockServer, _ := newLoginServer(t, "sms", "123456")
+	flow := newLoginFlow(mockServer.URL)
+
+	state, err := flow.Start(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, common.LoginStateAwaitingCode, state)
+	assert.Equal(t, "sms", flow.Method)
+	assert.Equal(t, "+1******1234", flow.Phone)
+
+	state, err = flow.SubmitCode(context.Background(), "123456")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, common.LoginStateComplete, state)
+	assert.Equal(t, "r1", flow.Response.RefreshToken)
+}
+
+func TestLoginFlowEmail(t *testing.T) {
+	mockServer, _ := newLoginServer(t, "email", "654321")
+	flow := newLoginFlow(mockServer.URL)
+
+	flow.Start(context.Background())
+	assert.Equal(t, "email", flow.Method)
+
+	state, err := flow.SubmitCode(context.Background(), "654321")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, common.LoginStateComplete, state)
+}
+
+func TestLoginFlowWrongCode(t *testing.T) {
+	mockServer, _ := newLoginServer(t, "sms", "123456")
+	flow := newLoginFlow(mockServer.URL)
+	flow.Start(context.Background())
+
+	state, err := flow.SubmitCode(context.Background(), "000000")
+	assert.Equal(t, true, errors.Is(err, common.ErrInvalidCode))
+	assert.Equal(t, common.LoginStateAwaitingCode, state)
+	assert.Equal(t, 1, flow.Attempts)
+
+	state, err = flow.SubmitCode(context.Background(), "123456")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, common.LoginStateComplete, state)
+}
+
+func TestLoginFlowTooManyAttempts(t *testing.T) {
+	mockServer, _ := newLoginServer(t, "sms", "123456")
+	flow := newLoginFlow(mockServer.URL)
+	flow.Start(context.Background())
+
+	var state common.LoginState
+	var err error
+	for i := 0; i < common.MAX_CODE_ATTEMPTS; i++ {
+		state, err = flow.SubmitCode(context.Background(), "000000")
+	}
+
+	assert.Equal(t, true, errors.Is(err, common.ErrInvalidCode))
+	assert.Equal(t, common.LoginStateFailed, state)
+}
+
+func TestLoginFlowResend(t *testing.T) {
+	mockServer, requests := newLoginServer(t, "sms", "123456")
+	flow := newLoginFlow(mockServer.URL)
+	flow.Start(context.Background())
+
+	err := flow.Resend(context.Background())
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, *requests)
+	assert.Equal(t, common.LoginStateAwaitingCode, flow.State)
+}
+
+func TestLoginFlowBadPassword(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer mockServer.Close()
+	flow := newLoginFlow(mockServer.URL)
+
+	state, err := flow.Start(context.Background())
+
+	assert.Equal(t, true, errors.Is(err, common.ErrLoginRejected))
+	assert.Equal(t, common.LoginStateFailed, state)
+}
+
+func TestLoginFlowInvalidTransition(t *testing.T) {
+	flow := newLoginFlow("http://127.0.0.1:1")
+
+	_, err := flow.SubmitCode(context.Background(), "123456")
+	assert.Equal(t, "cannot submit a code in state start", err.Error())
+
+	err = flow.Resend(context.Background())
+	assert.Equal(t, "cannot resend a code in state start", err.Error())
+}
@@ -0,0 +1,186 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MEDIA_MAX_PAGES is the maximum number of media pages fetched by ListAllMedia
+var MEDIA_MAX_PAGES = 100
+
+// ErrMediaTruncated is returned by ListAllMedia when more media remains after MEDIA_MAX_PAGES pages
+var ErrMediaTruncated = errors.New("media list truncated")
+
+type MediaClip struct {
+	Id          int       `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Deleted     bool      `json:"deleted"`
+	Device      string    `json:"device"`
+	DeviceId    int       `json:"device_id"`
+	DeviceName  string    `json:"device_name"`
+	NetworkId   int       `json:"network_id"`
+	NetworkName string    `json:"network_name"`
+	Type        string    `json:"type"`
+	Source      string    `json:"source"`
+	Watched     bool      `json:"watched"`
+	Media       string    `json:"media"`
+	Thumbnail   string    `json:"thumbnail"`
+}
+
+type MediaResponse struct {
+	Limit        int         `json:"limit"`
+	PurgeId      int         `json:"purge_id"`
+	RefreshCount int         `json:"refresh_count"`
+	Media        []MediaClip `json:"media"`
+}
+
+// ListMedia retrieves a single page of media (clips) changed since the given time
+//
+// ctx: the context to use for the request
+//
+// since: only media changed after this time is returned
+//
+// page: the page number to retrieve, starting at 1
+//
+// Example: client.ListMedia(ctx, time.Now().Add(-24*time.Hour), 1) = &MediaResponse{...}, nil
+func (c *BlinkClient) ListMedia(ctx context.Context, since time.Time, page int) (*MediaResponse, error) {
+	query := url.Values{}
+	query.Set("since", since.UTC().Format(time.RFC3339))
+	query.Set("page", fmt.Sprint(page))
+
+	req, err := c.newRequest(ctx, "GET", fmt.Sprintf("%s/api/v1/accounts/%d/media/changed?%s", c.ApiUrl(), c.AccountId, query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.doRetry(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	var result MediaResponse
+	if err := decodeBody(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// ListAllMedia retrieves every page of media created between since and until.
+// Deleted media is skipped. The pages are ordered newest first, so paging stops at the first page
+// with only media created before since.
+// Returns the media listed so far and ErrMediaTruncated when media remains after MEDIA_MAX_PAGES pages.
+//
+// ctx: the context to use for the requests
+//
+// since: only media created after this time is returned
+//
+// until: only media created before this time is returned. A zero value disables the filter
+//
+// Example: client.ListAllMedia(ctx, since, time.Time{}) = []MediaClip{...}, nil
+func (c *BlinkClient) ListAllMedia(ctx context.Context, since time.Time, until time.Time) ([]MediaClip, error) {
+	var clips []MediaClip
+	for page := 1; ; page++ {
+		resp, err := c.ListMedia(ctx, since, page)
+		if err != nil {
+			return nil, fmt.Errorf("error listing media page %d: %w", page, err)
+		}
+		if len(resp.Media) == 0 {
+			return clips, nil
+		}
+
+		recent := false
+		var pageClips []MediaClip
+		for _, clip := range resp.Media {
+			if clip.CreatedAt.Before(since) {
+				continue
+			}
+			recent = true
+
+			if clip.Deleted || (!until.IsZero() && clip.CreatedAt.After(until)) {
+				continue
+			}
+
+			pageClips = append(pageClips, clip)
+		}
+
+		if !recent {
+			return clips, nil
+		} else if page > MEDIA_MAX_PAGES {
+			return clips, fmt.Errorf("%w: more than %d pages of media. Narrow the time range", ErrMediaTruncated, MEDIA_MAX_PAGES)
+		}
+		clips = append(clips, pageClips...)
+	}
+}
+
+// DownloadClip streams the MP4 of a media clip to the writer
+//
+// ctx: the context to use for the request
+//
+// clip: the clip to download
+//
+// writer: the writer to copy the MP4 to
+//
+// Example: client.DownloadClip(ctx, clip, file) = 1048576, nil
+func (c *BlinkClient) DownloadClip(ctx context.Context, clip MediaClip, writer io.Writer) (int64, error) {
+	if clip.Media == "" {
+		return 0, fmt.Errorf("clip %d has no media path", clip.Id)
+	}
+
+	return c.Download(ctx, c.ApiUrl()+clip.Media, writer)
+}
+
+// ClipPath returns the relative path to store a clip at, organised by network, camera and date
+//
+// clip: the clip to build the path for
+//
+// Example: ClipPath(clip) = "Home/Front Door/2025-07-13/183211_1234.mp4"
+func ClipPath(clip MediaClip) string {
+	created := clip.CreatedAt.UTC()
+
+	return filepath.Join(
+		sanitizePathSegment(clip.NetworkName, clip.NetworkId),
+		sanitizePathSegment(clip.DeviceName, clip.DeviceId),
+		created.Format("2006-01-02"),
+		fmt.Sprintf("%s_%d.mp4", created.Format("150405"), clip.Id),
+	)
+}
+
+// sanitizePathSegment makes a name safe to use as a single path segment
+//
+// name: the name to sanitize
+//
+// fallbackId: the ID to use when the name is empty
+//
+// Example: sanitizePathSegment("Front/Door", 1) = "Front_Door"
+func sanitizePathSegment(name string, fallbackId int) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		if r < 0x20 {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+
+	if name == "" || name == "." || name == ".." {
+		return fmt.Sprint(fallbackId)
+	}
+
+	return name
+}
@@ -0,0 +1,158 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestListAllMediaPagination(t *testing.T) {
+	var pages []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/accounts/1/media/changed", r.URL.Path)
+		assert.Equal(t, "2025-07-01T00:00:00Z", r.URL.Query().Get("since"))
+		pages = append(pages, r.URL.Query().Get("page"))
+
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Query().Get("page") {
+		case "1":
+			w.Write([]byte(`{"media": [
+				{"id": 1, "created_at": "2025-07-02T10:00:00+00:00", "media": "/api/v2/accounts/1/media/clip/1.mp4"},
+				{"id": 2, "created_at": "2025-07-02T11:00:00+00:00", "deleted": true}
+			]}`))
+		case "2":
+			w.Write([]byte(`{"media": [
+				{"id": 3, "created_at": "2025-07-03T10:00:00+00:00"},
+				{"id": 4, "created_at": "2025-07-05T10:00:00+00:00"}
+			]}`))
+		default:
+			w.Write([]byte(`{"media": []}`))
+		}
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	client.RestUrl = mockServer.URL
+
+	since := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2025, 7, 4, 0, 0, 0, 0, time.UTC)
+	clips, err := client.ListAllMedia(context.Background(), since, until)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"1", "2", "3"}, pages)
+	assert.Equal(t, 2, len(clips))
+	assert.Equal(t, 1, clips[0].Id)
+	assert.Equal(t, 3, clips[1].Id)
+}
+
+func TestListAllMediaStopsBeforeSince(t *testing.T) {
+	var pages []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages = append(pages, r.URL.Query().Get("page"))
+
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Query().Get("page") {
+		case "1":
+			w.Write([]byte(`{"media": [
+				{"id": 2, "created_at": "2025-07-02T10:00:00+00:00"},
+				{"id": 1, "created_at": "2025-06-30T10:00:00+00:00"}
+			]}`))
+		default:
+			// Changed media created before since
+			w.Write([]byte(`{"media": [{"id": 0, "created_at": "2025-06-29T10:00:00+00:00"}]}`))
+		}
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	client.RestUrl = mockServer.URL
+
+	clips, err := client.ListAllMedia(context.Background(), time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), time.Time{})
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"1", "2"}, pages)
+	assert.Equal(t, 1, len(clips))
+	assert.Equal(t, 2, clips[0].Id)
+}
+
+func TestListAllMediaTruncated(t *testing.T) {
+	maxPages := common.MEDIA_MAX_PAGES
+	common.MEDIA_MAX_PAGES = 2
+	defer func() { common.MEDIA_MAX_PAGES = maxPages }()
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"media": [{"id": ` + r.URL.Query().Get("page") + `, "created_at": "2025-07-02T10:00:00+00:00"}]}`))
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	client.RestUrl = mockServer.URL
+
+	clips, err := client.ListAllMedia(context.Background(), time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), time.Time{})
+
+	assert.Equal(t, true, errors.Is(err, common.ErrMediaTruncated))
+	assert.Equal(t, 2, len(clips))
+}
+
+func TestListMediaHttpError(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	client.RestUrl = mockServer.URL
+
+	_, err := client.ListAllMedia(context.Background(), time.Time{}, time.Time{})
+
+	assert.Equal(t, true, common.IsUnauthorized(err))
+}
+
+func TestDownloadClip(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2/accounts/1/media/clip/1.mp4", r.URL.Path)
+		assert.Equal(t, "Bearer xyz-auth-token", r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("mp4-data"))
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	client.RestUrl = mockServer.URL
+
+	var clip bytes.Buffer
+	n, err := client.DownloadClip(context.Background(), common.MediaClip{Id: 1, Media: "/api/v2/accounts/1/media/clip/1.mp4"}, &clip)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(8), n)
+	assert.Equal(t, "mp4-data", clip.String())
+
+	_, err = client.DownloadClip(context.Background(), common.MediaClip{Id: 2}, &clip)
+	assert.Equal(t, "clip 2 has no media path", err.Error())
+}
+
+func TestClipPath(t *testing.T) {
+	clip := common.MediaClip{
+		Id:          1234,
+		CreatedAt:   time.Date(2025, 7, 13, 18, 32, 11, 0, time.UTC),
+		NetworkId:   2,
+		NetworkName: "Home",
+		DeviceId:    3,
+		DeviceName:  "Front/Door",
+	}
+
+	assert.Equal(t, filepath.Join("Home", "Front_Door", "2025-07-13", "183211_1234.mp4"), common.ClipPath(clip))
+
+	clip.NetworkName = ".."
+	clip.DeviceName = ""
+	assert.Equal(t, filepath.Join("2", "3", "2025-07-13", "183211_1234.mp4"), common.ClipPath(clip))
+}
@@ -0,0 +1,64 @@
+package common
+
+import (
+	"context"
+	"fmt"
+)
+
+type MotionInput struct {
+	Enabled bool `json:"enabled"`
+}
+
+type MotionResponse struct {
+	// The type of device that was updated
+	DeviceType string `json:"device_type"`
+	// The ID of the device that was updated
+	DeviceId int `json:"device_id"`
+	// The new motion detection state of the device
+	Enabled bool `json:"enabled"`
+	// The final state of the command
+	Command *CommandResponse `json:"command"`
+}
+
+// SetMotionDetection enables or disables motion detection on a single device and waits for the command to complete
+//
+// ctx: the context to use while waiting for the command
+//
+// deviceType: the type of device (e.g. camera, owl, doorbell)
+//
+// networkId: the network ID that the device is on
+//
+// deviceId: the ID of the device to update
+//
+// enabled: true to enable motion detection, false to disable it
+//
+// Example: client.SetMotionDetection(ctx, "owl", 5678, 9012, true) = &MotionResponse{Enabled: true}, nil
+func (c *BlinkClient) SetMotionDetection(ctx context.Context, deviceType string, networkId int, deviceId int, enabled bool) (*MotionResponse, error) {
+	motionPath, err := GetMotionPath(deviceType, enabled)
+	if err != nil {
+		return nil, fmt.Errorf("error getting motion path: %w", err)
+	}
+
+	// Mini cameras toggle motion detection through their config
+	var body any
+	if deviceType == "owl" || deviceType == "hawk" {
+		body = &MotionInput{Enabled: enabled}
+	}
+
+	cmd, err := c.startCommand(ctx, fmt.Sprintf(motionPath, c.ApiUrl(), c.AccountId, networkId, deviceId), body)
+	if err != nil {
+		return nil, fmt.Errorf("error sending motion detection command: %w", err)
+	}
+
+	result, err := c.WaitForCommand(ctx, c.commandUrl(networkId, cmd.Id), COMMAND_POLL_INTERVAL)
+	if err != nil {
+		return nil, fmt.Errorf("error waiting for motion detection command: %w", err)
+	}
+
+	return &MotionResponse{
+		DeviceType: deviceType,
+		DeviceId:   deviceId,
+		Enabled:    enabled,
+		Command:    result,
+	}, nil
+}
@@ -0,0 +1,88 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func newMotionServer(t *testing.T, path string, onRequest func(r *http.Request)) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+path, func(w http.ResponseWriter, r *http.Request) {
+		onRequest(r)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": 999, "network_id": 2}`))
+	})
+	mux.HandleFunc("GET /network/2/command/999", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"complete": true, "status_msg": "Command succeeded"}`))
+	})
+	mockServer := httptest.NewServer(mux)
+	t.Cleanup(mockServer.Close)
+
+	return mockServer
+}
+
+func TestSetMotionDetectionCamera(t *testing.T) {
+	var called bool
+	mockServer := newMotionServer(t, "/network/2/camera/3/disable", func(r *http.Request) {
+		called = true
+	})
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	client.RestUrl = mockServer.URL
+
+	result, err := client.SetMotionDetection(context.Background(), "camera", 2, 3, false)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, called)
+	assert.Equal(t, false, result.Enabled)
+	assert.Equal(t, 3, result.DeviceId)
+	assert.Equal(t, "Command succeeded", result.Command.StatusMessage)
+}
+
+func TestSetMotionDetectionOwl(t *testing.T) {
+	var input common.MotionInput
+	mockServer := newMotionServer(t, "/api/v1/accounts/1/networks/2/owls/3/config", func(r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&input)
+	})
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	client.RestUrl = mockServer.URL
+
+	result, err := client.SetMotionDetection(context.Background(), "owl", 2, 3, true)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, input.Enabled)
+	assert.Equal(t, true, result.Enabled)
+	assert.Equal(t, "owl", result.DeviceType)
+}
+
+func TestSetMotionDetectionDoorbell(t *testing.T) {
+	var called bool
+	mockServer := newMotionServer(t, "/api/v1/accounts/1/networks/2/doorbells/3/enable", func(r *http.Request) {
+		called = true
+	})
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	client.RestUrl = mockServer.URL
+
+	_, err := client.SetMotionDetection(context.Background(), "doorbell", 2, 3, true)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, called)
+}
+
+func TestSetMotionDetectionUnknownDevice(t *testing.T) {
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+
+	result, err := client.SetMotionDetection(context.Background(), "unknown", 2, 3, true)
+
+	assert.Equal(t, (*common.MotionResponse)(nil), result)
+	assert.Equal(t, "error getting motion path: cannot build path for unknown device type: unknown", err.Error())
+}
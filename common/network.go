@@ -0,0 +1,42 @@
+package common
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetArmPath returns the path used to arm or disarm a network
+//
+// armed: true to arm the network, false to disarm it
+//
+// Example: GetArmPath(true) = "%s/api/v1/accounts/%d/networks/%d/state/arm"
+func GetArmPath(armed bool) string {
+	if armed {
+		return "%s/api/v1/accounts/%d/networks/%d/state/arm"
+	}
+
+	return "%s/api/v1/accounts/%d/networks/%d/state/disarm"
+}
+
+// SetNetworkArmed arms or disarms a network and waits for the command to complete
+//
+// ctx: the context to use while waiting for the command
+//
+// networkId: the ID of the network to arm or disarm
+//
+// armed: true to arm the network, false to disarm it
+//
+// Example: client.SetNetworkArmed(ctx, 1234, true) = &CommandResponse{Complete: true}, nil
+func (c *BlinkClient) SetNetworkArmed(ctx context.Context, networkId int, armed bool) (*CommandResponse, error) {
+	cmd, err := c.startCommand(ctx, fmt.Sprintf(GetArmPath(armed), c.ApiUrl(), c.AccountId, networkId), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error sending arm command: %w", err)
+	}
+
+	result, err := c.WaitForCommand(ctx, c.commandUrl(networkId, cmd.Id), COMMAND_POLL_INTERVAL)
+	if err != nil {
+		return nil, fmt.Errorf("error waiting for arm command: %w", err)
+	}
+
+	return result, nil
+}
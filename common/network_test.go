@@ -0,0 +1,109 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestGetArmPath(t *testing.T) {
+	assert.Equal(t, "%s/api/v1/accounts/%d/networks/%d/state/arm", common.GetArmPath(true))
+	assert.Equal(t, "%s/api/v1/accounts/%d/networks/%d/state/disarm", common.GetArmPath(false))
+}
+
+func TestSetNetworkArmedNominal(t *testing.T) {
+	var polls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/accounts/1234/networks/5678/state/arm", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": 999, "network_id": 5678, "command": "arm", "state": "new"}`))
+	})
+	mux.HandleFunc("GET /network/5678/command/999", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		if polls.Add(1) < 2 {
+			w.Write([]byte(`{"complete": false}`))
+			return
+		}
+		w.Write([]byte(`{"complete": true, "status": 0, "status_msg": "Command succeeded"}`))
+	})
+	mockServer := httptest.NewServer(mux)
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1234)
+	client.RestUrl = mockServer.URL
+
+	result, err := client.SetNetworkArmed(context.Background(), 5678, true)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, result.Complete)
+	assert.Equal(t, "Command succeeded", result.StatusMessage)
+	assert.Equal(t, int32(2), polls.Load())
+}
+
+func TestSetNetworkArmedHttpError(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code": 100, "message": "Invalid network"}`))
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1234)
+	client.RestUrl = mockServer.URL
+
+	result, err := client.SetNetworkArmed(context.Background(), 5678, false)
+
+	assert.Equal(t, (*common.CommandResponse)(nil), result)
+	assert.Equal(t, "error sending arm command: HTTP Status Code 400. API Code 100 with message Invalid network", err.Error())
+}
+
+func TestSetNetworkArmedMissingCommandId(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{}`))
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1234)
+	client.RestUrl = mockServer.URL
+
+	_, err := client.SetNetworkArmed(context.Background(), 5678, true)
+
+	assert.Equal(t, "error sending arm command: command response did not include a command ID", err.Error())
+}
+
+func TestWaitForCommandCancel(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"complete": false}`))
+	}))
+	defer mockServer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+
+	result, err := common.NewBlinkClient("xyz-auth-token", "", 0).WaitForCommand(ctx, mockServer.URL, 1)
+
+	assert.Equal(t, (*common.CommandResponse)(nil), result)
+	assert.Equal(t, "command did not complete: context deadline exceeded", err.Error())
+}
+
+func TestWaitForCommandDefaultInterval(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"complete": true}`))
+	}))
+	defer mockServer.Close()
+
+	for _, pollInterval := range []int{0, -1} {
+		result, err := common.NewBlinkClient("xyz-auth-token", "", 0).WaitForCommand(context.Background(), mockServer.URL, pollInterval)
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, true, result.Complete)
+	}
+}
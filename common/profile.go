@@ -0,0 +1,402 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PROFILE_DIR_NAME is the directory created in the user's config directory to hold the profiles
+var PROFILE_DIR_NAME = "blink-liveview-middleware"
+
+// DEFAULT_PROFILE is the profile used when no profile name is given
+var DEFAULT_PROFILE = "default"
+
+// ErrProfileNotFound is returned when the requested profile does not exist
+var ErrProfileNotFound = errors.New("profile not found")
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+type Profile struct {
+	// The name of the profile
+	Name string `json:"name"`
+	// The email address used to log in. Informational only
+	Email string `json:"email,omitempty"`
+	// The OAuth access token
+	AccessToken string `json:"access_token"`
+	// The OAuth refresh token
+	RefreshToken string `json:"refresh_token"`
+	// When the access token expires
+	ExpiresAt time.Time `json:"expires_at"`
+	// The Blink account ID
+	AccountId int `json:"account_id"`
+	// The Blink API tier/region (e.g. u011)
+	Region string `json:"region"`
+	// The fingerprint (hardware ID) the tokens were issued to
+	HardwareId string `json:"hardware_id"`
+	// The proxy used with the profile, in the --proxy format. Overridden by --proxy and PROXY_ENV
+	Proxy string `json:"proxy,omitempty"`
+
+	// The directory the profile was loaded from or saved to
+	dir string
+}
+
+// DefaultProfileDir returns the directory holding the profiles in the user's config directory
+//
+// Example: DefaultProfileDir() = "/home/user/.config/blink-liveview-middleware/profiles", nil
+func DefaultProfileDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding the user config directory: %w", err)
+	}
+
+	return filepath.Join(configDir, PROFILE_DIR_NAME, "profiles"), nil
+}
+
+// NewProfile creates an empty profile with a new fingerprint
+//
+// name: the name of the profile
+//
+// Example: NewProfile("default") = &Profile{Name: "default", HardwareId: "..."}, nil
+func NewProfile(name string) (*Profile, error) {
+	if err := validateProfileName(name); err != nil {
+		return nil, err
+	}
+
+	return &Profile{Name: name, HardwareId: uuid.New().String()}, nil
+}
+
+// NewProfileFromFingerprint creates an empty profile with the fingerprint of the fingerprint file, if any,
+// so the first profile of an upgraded install is not seen as a new device by Blink.
+// Returns the fingerprint read from the file, to Destroy once the profile is saved.
+//
+// name: the name of the profile
+//
+// filename: the fingerprint file. Defaults to FINGERPRINT_FILE when empty
+//
+// Example: NewProfileFromFingerprint("default", "") = &Profile{Name: "default", HardwareId: "..."}, &Fingerprint{...}, nil
+func NewProfileFromFingerprint(name string, filename string) (*Profile, *Fingerprint, error) {
+	profile, err := NewProfile(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fingerprint, err := GetFingerprint(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !fingerprint.New {
+		profile.HardwareId = fingerprint.Value
+	}
+
+	return profile, fingerprint, nil
+}
+
+// LoadProfile reads a profile from the profile directory.
+// Encrypted profiles are decrypted with DefaultCredentialStore.
+//
+// dir: the profile directory. Defaults to DefaultProfileDir when empty
+//
+// name: the name of the profile
+//
+// Example: LoadProfile("", "default") = &Profile{Name: "default"}, nil
+func LoadProfile(dir string, name string) (*Profile, error) {
+	path, dir, err := profilePath(dir, name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := DefaultCredentialStore.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading profile: %w", err)
+	}
+
+	var profile Profile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("error decoding profile %s: %w", name, err)
+	}
+	profile.Name = name
+	profile.dir = dir
+
+	return &profile, nil
+}
+
+// ListProfiles returns the names of the saved profiles
+//
+// dir: the profile directory. Defaults to DefaultProfileDir when empty
+//
+// Example: ListProfiles("") = []string{"default", "work"}, nil
+func ListProfiles(dir string) ([]string, error) {
+	if dir == "" {
+		var err error
+		if dir, err = DefaultProfileDir(); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading profile directory: %w", err)
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".json"); ok && !entry.IsDir() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// DeleteProfile removes a profile from the profile directory
+//
+// dir: the profile directory. Defaults to DefaultProfileDir when empty
+//
+// name: the name of the profile
+//
+// Example: DeleteProfile("", "default") = nil
+func DeleteProfile(dir string, name string) error {
+	path, _, err := profilePath(dir, name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	} else if err != nil {
+		return fmt.Errorf("error removing profile: %w", err)
+	}
+
+	return nil
+}
+
+// Save writes the profile to the profile directory, readable by the current user only.
+// The file is encrypted when DefaultCredentialStore is set.
+//
+// dir: the profile directory. Defaults to the directory the profile was loaded from, then DefaultProfileDir
+//
+// Example: Save("") = nil
+func (p *Profile) Save(dir string) error {
+	if dir == "" {
+		dir = p.dir
+	}
+
+	path, dir, err := profilePath(dir, p.Name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("error creating profile directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := DefaultCredentialStore.WriteFile(path, data); err != nil {
+		return fmt.Errorf("error saving profile: %w", err)
+	}
+
+	p.dir = dir
+
+	return nil
+}
+
+// Update stores the tokens from an OAuth login or refresh response
+//
+// resp: the OAuth response
+//
+// Example: profile.Update(loginResp)
+func (p *Profile) Update(resp *LoginResponse) {
+	p.AccessToken = resp.AccessToken
+	if resp.RefreshToken != "" {
+		p.RefreshToken = resp.RefreshToken
+	}
+
+	p.ExpiresAt = time.Time{}
+	if resp.ExpiresIn > 0 {
+		p.ExpiresAt = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+}
+
+// Fingerprint returns the fingerprint the profile's tokens were issued to
+//
+// Example: profile.Fingerprint() = &Fingerprint{Value: "..."}
+func (p *Profile) Fingerprint() *Fingerprint {
+	return &Fingerprint{Value: p.HardwareId}
+}
+
+// Client builds a Blink API client from the profile.
+// Refreshed tokens are saved back to the profile.
+//
+// Example: profile.Client() = &BlinkClient{...}
+func (p *Profile) Client() *BlinkClient {
+	client := NewBlinkClient(p.AccessToken, p.Region, p.AccountId)
+	if p.RefreshToken != "" {
+		client.Tokens = NewTokenSourceWithExpiry(p.AccessToken, p.RefreshToken, p.ExpiresAt, p.HardwareId)
+		client.Tokens.OnRefresh = func(resp *LoginResponse) {
+			p.Update(resp)
+			if err := p.Save(""); err != nil {
+				log.Println("error saving refreshed tokens to profile", p.Name, err)
+			}
+		}
+	}
+
+	return client
+}
+
+type ProfileBundle struct {
+	Version  int        `json:"version"`
+	Profiles []*Profile `json:"profiles"`
+}
+
+// RekeyProfiles re-encrypts every saved profile from one credential store to another.
+// Used to change the passphrase, or to encrypt or decrypt existing profiles.
+//
+// dir: the profile directory. Defaults to DefaultProfileDir when empty
+//
+// from: the store the profiles are currently encrypted with. Nil for plaintext
+//
+// to: the store to re-encrypt the profiles with. Nil for plaintext
+//
+// Example: RekeyProfiles("", oldStore, newStore) = []string{"default"}, nil
+func RekeyProfiles(dir string, from *CredentialStore, to *CredentialStore) ([]string, error) {
+	names, err := ListProfiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		path, _, err := profilePath(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		if err := from.Rekey(path, to); err != nil {
+			return nil, fmt.Errorf("error re-encrypting profile %s: %w", name, err)
+		}
+	}
+
+	return names, nil
+}
+
+// ExportProfiles bundles saved profiles into a single file encrypted with the export store
+//
+// dir: the profile directory. Defaults to DefaultProfileDir when empty
+//
+// names: the profiles to export. Every profile is exported when empty
+//
+// to: the store to encrypt the bundle with
+//
+// Example: ExportProfiles("", []string{"default"}, exportStore) = []byte(`{"format": "blink-credential", ...}`), nil
+func ExportProfiles(dir string, names []string, to *CredentialStore) ([]byte, error) {
+	if len(names) == 0 {
+		var err error
+		if names, err = ListProfiles(dir); err != nil {
+			return nil, err
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no profiles to export")
+	}
+
+	bundle := ProfileBundle{Version: 1}
+	for _, name := range names {
+		profile, err := LoadProfile(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Profiles = append(bundle.Profiles, profile)
+	}
+
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	return to.Seal(data)
+}
+
+// ImportProfiles saves the profiles from an exported bundle
+//
+// dir: the profile directory. Defaults to DefaultProfileDir when empty
+//
+// data: the exported bundle
+//
+// from: the store the bundle was encrypted with
+//
+// overwrite: replace existing profiles with the same name. Existing profiles are skipped otherwise
+//
+// Example: ImportProfiles("", data, exportStore, false) = []string{"default"}, nil
+func ImportProfiles(dir string, data []byte, from *CredentialStore, overwrite bool) ([]string, error) {
+	plaintext, err := from.Open(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var bundle ProfileBundle
+	if err := json.Unmarshal(plaintext, &bundle); err != nil {
+		return nil, fmt.Errorf("error decoding profile bundle: %w", err)
+	}
+	if bundle.Version != 1 {
+		return nil, fmt.Errorf("unsupported profile bundle version %d", bundle.Version)
+	}
+
+	imported := []string{}
+	for _, profile := range bundle.Profiles {
+		if err := validateProfileName(profile.Name); err != nil {
+			return imported, err
+		}
+		if _, err := LoadProfile(dir, profile.Name); err == nil && !overwrite {
+			continue
+		}
+
+		if err := profile.Save(dir); err != nil {
+			return imported, err
+		}
+		imported = append(imported, profile.Name)
+	}
+
+	return imported, nil
+}
+
+// profilePath returns the path of the profile file and the resolved profile directory
+func profilePath(dir string, name string) (string, string, error) {
+	if err := validateProfileName(name); err != nil {
+		return "", "", err
+	}
+
+	if dir == "" {
+		var err error
+		if dir, err = DefaultProfileDir(); err != nil {
+			return "", "", err
+		}
+	}
+
+	return filepath.Join(dir, name+".json"), dir, nil
+}
+
+// validateProfileName rejects names that cannot be used as a file name
+func validateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q. Use letters, numbers, '-' and '_' only", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestProfileSaveAndLoad(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "profiles")
+
+	profile, err := common.NewProfile("work")
+	assert.Equal(t, nil, err)
+	assert.NotEqual(t, "", profile.HardwareId)
+
+	profile.AccountId = 1234
+	profile.Region = "u011"
+	profile.Proxy = "socks5h://10.0.0.1:1080"
+	profile.Update(&common.LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 3600})
+	assert.Equal(t, nil, profile.Save(dir))
+
+	info, err := os.Stat(filepath.Join(dir, "work.json"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := common.LoadProfile(dir, "work")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "access", loaded.AccessToken)
+	assert.Equal(t, "refresh", loaded.RefreshToken)
+	assert.Equal(t, 1234, loaded.AccountId)
+	assert.Equal(t, "u011", loaded.Region)
+	assert.Equal(t, "socks5h://10.0.0.1:1080", loaded.Proxy)
+	assert.Equal(t, profile.HardwareId, loaded.HardwareId)
+	assert.Equal(t, true, loaded.ExpiresAt.After(time.Now()))
+
+	names, err := common.ListProfiles(dir)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"work"}, names)
+}
+
+func TestNewProfileFromFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, common.FINGERPRINT_FILE)
+	os.WriteFile(path, []byte("existing-fingerprint"), 0600)
+
+	// The fingerprint file of an upgraded install is reused
+	profile, fingerprint, err := common.NewProfileFromFingerprint("default", path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "existing-fingerprint", profile.HardwareId)
+	assert.Equal(t, nil, profile.Save(filepath.Join(dir, "profiles")))
+
+	// and removed once the profile is saved
+	assert.Equal(t, nil, fingerprint.Destroy())
+	_, err = os.Stat(path)
+	assert.Equal(t, true, errors.Is(err, os.ErrNotExist))
+
+	profile, _, err = common.NewProfileFromFingerprint("work", path)
+	assert.Equal(t, nil, err)
+	assert.NotEqual(t, "", profile.HardwareId)
+	assert.NotEqual(t, "existing-fingerprint", profile.HardwareId)
+
+	_, err = os.Stat(path)
+	assert.Equal(t, true, errors.Is(err, os.ErrNotExist))
+}
+
+func TestProfileDelete(t *testing.T) {
+	dir := t.TempDir()
+
+	profile, _ := common.NewProfile("default")
+	profile.Save(dir)
+
+	assert.Equal(t, nil, common.DeleteProfile(dir, "default"))
+
+	_, err := common.LoadProfile(dir, "default")
+	assert.Equal(t, true, errors.Is(err, common.ErrProfileNotFound))
+
+	err = common.DeleteProfile(dir, "default")
+	assert.Equal(t, true, errors.Is(err, common.ErrProfileNotFound))
+}
+
+func TestProfileInvalidName(t *testing.T) {
+	_, err := common.NewProfile("../etc")
+	assert.Equal(t, `invalid profile name "../etc". Use letters, numbers, '-' and '_' only`, err.Error())
+
+	_, err = common.LoadProfile(t.TempDir(), "")
+	assert.NotEqual(t, nil, err)
+}
+
+func TestListProfilesMissingDir(t *testing.T) {
+	names, err := common.ListProfiles(filepath.Join(t.TempDir(), "missing"))
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{}, names)
+}
+
+func TestProfileClientSavesRefreshedTokens(t *testing.T) {
+	dir := t.TempDir()
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "hardware-id", r.Header.Get("hardware_id"))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"access_token": "new-access", "refresh_token": "new-refresh", "expires_in": 3600}`))
+	}))
+	defer mockServer.Close()
+
+	profile := &common.Profile{Name: "default", AccessToken: "old-access", RefreshToken: "old-refresh", HardwareId: "hardware-id"}
+	profile.Save(dir)
+
+	client := profile.Client()
+	client.OAuthUrl = mockServer.URL
+	assert.Equal(t, nil, client.Tokens.Refresh(context.Background(), client))
+
+	loaded, err := common.LoadProfile(dir, "default")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "new-access", loaded.AccessToken)
+	assert.Equal(t, "new-refresh", loaded.RefreshToken)
+}
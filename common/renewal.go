@@ -0,0 +1,113 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// LIVEVIEW_RENEWAL_GAP is the default pause between a liveview session ending and the next one starting
+var LIVEVIEW_RENEWAL_GAP = time.Second
+
+type RenewalOptions struct {
+	// The maximum number of times the session is renewed. Zero renews until the context is cancelled
+	MaxRenewals int
+	// The pause between two sessions. Defaults to LIVEVIEW_RENEWAL_GAP when zero
+	Gap time.Duration
+	// The maximum total duration of the stream across every session. Zero disables the limit
+	MaxDuration time.Duration
+	// Called before each renewal with the renewal number and the error that ended the previous session. Optional
+	OnRenew func(renewal int, err error)
+	// The options passed to the stream of every session
+	Stream StreamOptions
+}
+
+// ContinuousLivestream streams a camera across multiple liveview sessions.
+// Blink ends a liveview session after a few minutes, so whenever the command completes or the
+// TCP stream ends, a new liveview command is started and the stream reconnected.
+// Every session writes into the same io.Writer, so the reader sees a single stream.
+// Returns nil when the context is cancelled or the maximum duration is reached, and the error
+// without renewing when a new session would fail the same way (see renewable).
+//
+// ctx: the context to use for the liveview sessions, including cancellation
+//
+// deviceType: the type of device to use for the liveview path
+//
+// networkId: the network ID that the camera is on
+//
+// cameraId: the ID of the camera to start the liveview sessions for
+//
+// writer: the pipe to write the stream data to
+//
+// opts: the renewal limits
+//
+// Example: client.ContinuousLivestream(ctx, "owl", 5678, 9012, pipe, RenewalOptions{MaxDuration: time.Hour}) -> nil
+func (c *BlinkClient) ContinuousLivestream(ctx context.Context, deviceType string, networkId int, cameraId int, writer io.Writer, opts RenewalOptions) error {
+	if opts.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.MaxDuration)
+		defer cancel()
+	}
+
+	gap := opts.Gap
+	if gap <= 0 {
+		gap = LIVEVIEW_RENEWAL_GAP
+	}
+
+	for renewal := 0; ; renewal++ {
+		err := c.LivestreamWithOptions(ctx, deviceType, networkId, cameraId, writer, opts.Stream)
+		if ctx.Err() != nil {
+			return nil
+		} else if err != nil && !renewable(err) {
+			return err
+		} else if err == nil {
+			err = fmt.Errorf("liveview session ended")
+		}
+
+		if opts.MaxRenewals > 0 && renewal >= opts.MaxRenewals {
+			return fmt.Errorf("liveview ended after %d renewals: %w", renewal, err)
+		}
+
+		// Wait longer when Blink asks for it (e.g. rate limits)
+		wait := gap
+		if apiErr, ok := AsAPIError(err); ok && apiErr.RetryAfter > wait {
+			wait = apiErr.RetryAfter
+		}
+
+		log.Printf("Liveview session ended (%v). Renewing in %s\n", err, wait)
+		if opts.OnRenew != nil {
+			opts.OnRenew(renewal+1, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// renewable reports whether a new liveview session may succeed after the error.
+// Blink rejections (4xx responses other than 429 and 409), untrusted stream servers,
+// unknown device types and invalid connection strings fail the same way every time.
+//
+// err: the error that ended the session
+//
+// Example: renewable(&APIError{StatusCode: 400}) = false
+func renewable(err error) bool {
+	if apiErr, ok := AsAPIError(err); ok && apiErr.StatusCode >= 400 && apiErr.StatusCode < 500 {
+		return DefaultRetryPolicy().Retryable(&http.Response{StatusCode: apiErr.StatusCode}, nil)
+	}
+
+	// Handshake failures (e.g. timeouts) may be transient, certificate and pin errors are not
+	var handshakeErr *HandshakeError
+	if IsTLSError(err) && !errors.As(err, &handshakeErr) {
+		return false
+	}
+
+	return !errors.Is(err, ErrUnknownDeviceType) && !errors.Is(err, ErrInvalidConnectionString)
+}
@@ -0,0 +1,157 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+// newRenewalServer serves liveview commands whose sessions end immediately
+func newRenewalServer(t *testing.T, liveviewStatus int, starts *atomic.Int32, stops *atomic.Int32) *common.BlinkClient {
+	// Nothing listens on the port, so the session ends before streaming
+	return newRenewalServerFor(t, "127.0.0.1:1", liveviewStatus, starts, stops)
+}
+
+// newRenewalServerFor serves liveview commands pointing at the stream server address
+func newRenewalServerFor(t *testing.T, address string, liveviewStatus int, starts *atomic.Int32, stops *atomic.Int32) *common.BlinkClient {
+	_, port, _ := net.SplitHostPort(address)
+	liveviewPort := common.LIVEVIEW_PORT
+	common.LIVEVIEW_PORT = port
+	t.Cleanup(func() { common.LIVEVIEW_PORT = liveviewPort })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v2/accounts/1234/networks/5678/owls/9012/liveview", func(w http.ResponseWriter, r *http.Request) {
+		starts.Add(1)
+		w.WriteHeader(liveviewStatus)
+		w.Write([]byte(`{"command_id": 75888, "polling_interval": 1, "server": "immis://` + address + `/abc_def?client_id=1"}`))
+	})
+	mux.HandleFunc("GET /network/5678/command/75888", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"complete": false}`))
+	})
+	mux.HandleFunc("POST /network/5678/command/75888/done", func(w http.ResponseWriter, r *http.Request) {
+		stops.Add(1)
+		w.Write([]byte(`{"code": 902}`))
+	})
+
+	mockServer := httptest.NewServer(mux)
+	t.Cleanup(mockServer.Close)
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1234)
+	client.RestUrl = mockServer.URL
+	client.Retry = nil
+
+	return client
+}
+
+func TestContinuousLivestreamMaxRenewals(t *testing.T) {
+	var starts, stops atomic.Int32
+	client := newRenewalServer(t, http.StatusOK, &starts, &stops)
+
+	renewals := []int{}
+	err := client.ContinuousLivestream(context.Background(), "owl", 5678, 9012, io.Discard, common.RenewalOptions{
+		MaxRenewals: 2,
+		Gap:         10 * time.Millisecond,
+		OnRenew: func(renewal int, err error) {
+			renewals = append(renewals, renewal)
+		},
+	})
+
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, true, strings.HasPrefix(err.Error(), "liveview ended after 2 renewals: TCPStream error: unable to initialize stream"))
+	assert.Equal(t, []int{1, 2}, renewals)
+	assert.Equal(t, int32(3), starts.Load())
+	assert.Equal(t, int32(3), stops.Load())
+}
+
+func TestContinuousLivestreamMaxDuration(t *testing.T) {
+	var starts, stops atomic.Int32
+	client := newRenewalServer(t, http.StatusOK, &starts, &stops)
+
+	start := time.Now()
+	err := client.ContinuousLivestream(context.Background(), "owl", 5678, 9012, io.Discard, common.RenewalOptions{
+		Gap:         50 * time.Millisecond,
+		MaxDuration: 300 * time.Millisecond,
+	})
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, time.Since(start) < 5*time.Second)
+	assert.Equal(t, true, starts.Load() > 1)
+}
+
+func TestContinuousLivestreamUnauthorized(t *testing.T) {
+	var starts, stops atomic.Int32
+	client := newRenewalServer(t, http.StatusUnauthorized, &starts, &stops)
+
+	err := client.ContinuousLivestream(context.Background(), "owl", 5678, 9012, io.Discard, common.RenewalOptions{
+		Gap: 10 * time.Millisecond,
+	})
+
+	assert.Equal(t, true, common.IsUnauthorized(err))
+	assert.Equal(t, int32(1), starts.Load())
+}
+
+func TestContinuousLivestreamNotRenewable(t *testing.T) {
+	var starts, stops atomic.Int32
+	client := newRenewalServer(t, http.StatusBadRequest, &starts, &stops)
+
+	err := client.ContinuousLivestream(context.Background(), "owl", 5678, 9012, io.Discard, common.RenewalOptions{
+		Gap: 10 * time.Millisecond,
+	})
+
+	apiErr, ok := common.AsAPIError(err)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, int32(1), starts.Load())
+
+	// Unknown device types fail before starting a command
+	err = client.ContinuousLivestream(context.Background(), "unknown", 5678, 9012, io.Discard, common.RenewalOptions{
+		Gap: 10 * time.Millisecond,
+	})
+	assert.Equal(t, true, errors.Is(err, common.ErrUnknownDeviceType))
+}
+
+func TestContinuousLivestreamPinMismatch(t *testing.T) {
+	listener, cert := newTLSListener(t)
+	go serveFrames(listener, nil, false)
+
+	var starts, stops atomic.Int32
+	client := newRenewalServerFor(t, listener.Addr().String(), http.StatusOK, &starts, &stops)
+
+	opts := trustedBy(cert)
+	opts.Pins = []string{"sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="}
+	err := client.ContinuousLivestream(context.Background(), "owl", 5678, 9012, io.Discard, common.RenewalOptions{
+		Gap:    10 * time.Millisecond,
+		Stream: common.StreamOptions{TLS: opts},
+	})
+
+	var pinErr *common.PinError
+	assert.Equal(t, true, errors.As(err, &pinErr))
+	assert.Equal(t, int32(1), starts.Load())
+	assert.Equal(t, int32(1), stops.Load())
+}
+
+func TestContinuousLivestreamCancel(t *testing.T) {
+	var starts, stops atomic.Int32
+	client := newRenewalServer(t, http.StatusOK, &starts, &stops)
+
+	mockCtx, mockCancel := context.WithCancel(context.Background())
+	err := client.ContinuousLivestream(mockCtx, "owl", 5678, 9012, io.Discard, common.RenewalOptions{
+		Gap: time.Hour,
+		OnRenew: func(renewal int, err error) {
+			mockCancel()
+		},
+	})
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int32(1), starts.Load())
+}
@@ -0,0 +1,124 @@
+package common
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+)
+
+type RetryPolicy struct {
+	// The maximum number of attempts, including the first request
+	MaxAttempts int
+	// The delay before the first retry. Doubled for each subsequent retry
+	BaseDelay time.Duration
+	// The maximum delay between attempts. A longer Retry-After ends the retries
+	MaxDelay time.Duration
+	// The random jitter applied to each delay as a fraction of the delay (e.g. 0.2 = ±20%)
+	Jitter float64
+}
+
+// DefaultRetryPolicy returns the retry policy used by NewBlinkClient.
+// Battery cameras can take several seconds to wake up, so the delays are generous.
+//
+// Example: DefaultRetryPolicy() = &RetryPolicy{MaxAttempts: 4, ...}
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   time.Second,
+		MaxDelay:    15 * time.Second,
+		Jitter:      0.2,
+	}
+}
+
+// Backoff returns the delay before the given retry, including jitter
+//
+// retry: the retry number, starting at 1
+//
+// Example: Backoff(3) = 4s ± jitter
+func (p *RetryPolicy) Backoff(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	if p.Jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(delay))
+	}
+
+	return delay
+}
+
+// Retryable reports whether the request should be retried after the response or error
+//
+// resp: the response received. Nil when err is set
+//
+// err: the transport error, if any
+//
+// Example: Retryable(&http.Response{StatusCode: 429}, nil) = true
+func (p *RetryPolicy) Retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		return errors.As(err, &netErr) && netErr.Timeout()
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusConflict, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// doRetry sends an idempotent request (or a liveview start), retrying according to the client's RetryPolicy.
+// The last response is returned unconsumed when the retries are exhausted.
+//
+// req: the request to send. The body must be replayable via GetBody
+//
+// Example: doRetry(req) = &http.Response{}, nil
+func (c *BlinkClient) doRetry(req *http.Request) (*http.Response, error) {
+	policy := c.Retry
+	if policy == nil || policy.MaxAttempts <= 1 || (req.Body != nil && req.GetBody == nil) {
+		return c.do(req)
+	}
+
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 {
+			attemptReq = req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				attemptReq.Body = body
+			}
+		}
+
+		resp, err := c.do(attemptReq)
+		if attempt >= policy.MaxAttempts || !policy.Retryable(resp, err) {
+			return resp, err
+		}
+
+		delay := policy.Backoff(attempt)
+		if resp != nil {
+			if retryAfter := parseRetryAfter(resp.Header.Get("Retry-After")); retryAfter > 0 {
+				if policy.MaxDelay > 0 && retryAfter > policy.MaxDelay {
+					return resp, nil
+				}
+				delay = retryAfter
+			}
+			resp.Body.Close()
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func newRetryClient(url string) *common.BlinkClient {
+	client := common.NewBlinkClient("xyz-auth-token", "", 0)
+	client.RestUrl = url
+	client.Retry = &common.RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   10 * time.Millisecond,
+		MaxDelay:    100 * time.Millisecond,
+	}
+
+	return client
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := common.RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	assert.Equal(t, time.Second, policy.Backoff(1))
+	assert.Equal(t, 2*time.Second, policy.Backoff(2))
+	assert.Equal(t, 4*time.Second, policy.Backoff(3))
+	assert.Equal(t, 5*time.Second, policy.Backoff(4))
+	assert.Equal(t, 5*time.Second, policy.Backoff(40))
+}
+
+func TestRetryPolicyJitter(t *testing.T) {
+	policy := common.RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second, Jitter: 0.5}
+
+	for i := 0; i < 100; i++ {
+		delay := policy.Backoff(1)
+		assert.Equal(t, true, delay >= 500*time.Millisecond && delay <= 1500*time.Millisecond)
+	}
+}
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	policy := common.DefaultRetryPolicy()
+
+	assert.Equal(t, true, policy.Retryable(&http.Response{StatusCode: http.StatusTooManyRequests}, nil))
+	assert.Equal(t, true, policy.Retryable(&http.Response{StatusCode: http.StatusConflict}, nil))
+	assert.Equal(t, true, policy.Retryable(&http.Response{StatusCode: http.StatusServiceUnavailable}, nil))
+	assert.Equal(t, false, policy.Retryable(&http.Response{StatusCode: http.StatusOK}, nil))
+	assert.Equal(t, false, policy.Retryable(&http.Response{StatusCode: http.StatusInternalServerError}, nil))
+	assert.Equal(t, false, policy.Retryable(&http.Response{StatusCode: http.StatusUnauthorized}, nil))
+}
+
+func TestRetryBeginLiveviewBusy(t *testing.T) {
+	var requests atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"code": 307, "message": "System is busy, please wait"}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"command_id": 75888, "polling_interval": 15, "server": "immis://93.93.93.93:443"}`))
+	}))
+	defer mockServer.Close()
+
+	resp, err := newRetryClient(mockServer.URL).BeginLiveview(context.Background(), mockServer.URL)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 75888, resp.CommandId)
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	var requests atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer mockServer.Close()
+
+	resp, err := newRetryClient(mockServer.URL).Homescreen(context.Background(), mockServer.URL)
+
+	assert.Equal(t, (*common.HomescreenResponse)(nil), resp)
+	assert.Equal(t, true, common.IsRateLimited(err))
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	var requests atomic.Int32
+	var first time.Time
+	var elapsed time.Duration
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		elapsed = time.Since(first)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"tier": "u011", "account_id": 1234}`))
+	}))
+	defer mockServer.Close()
+
+	client := newRetryClient(mockServer.URL)
+	client.Retry.MaxDelay = 2 * time.Second
+	_, err := client.GetTierInfo(context.Background())
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, elapsed >= time.Second)
+}
+
+func TestRetryAfterExceedsMaxDelay(t *testing.T) {
+	var requests atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer mockServer.Close()
+
+	_, err := newRetryClient(mockServer.URL).GetTierInfo(context.Background())
+	apiErr, _ := common.AsAPIError(err)
+
+	assert.Equal(t, int32(1), requests.Load())
+	assert.Equal(t, 60*time.Second, apiErr.RetryAfter)
+}
+
+func TestRetryNotForStopCommand(t *testing.T) {
+	var requests atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer mockServer.Close()
+
+	err := newRetryClient(mockServer.URL).StopCommand(context.Background(), mockServer.URL)
+
+	assert.Equal(t, "cannot stop command. HTTP Status Code 503", err.Error())
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestRetryPollCommand(t *testing.T) {
+	var requests atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"complete": true}`))
+	}))
+	defer mockServer.Close()
+
+	err := newRetryClient(mockServer.URL).PollCommand(context.Background(), mockServer.URL, 1)
+
+	assert.Equal(t, "command marked as complete. Cannot poll further", err.Error())
+	assert.Equal(t, int32(2), requests.Load())
+}
@@ -0,0 +1,146 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// RequestThumbnail asks the device to capture a new thumbnail and waits for the command to complete
+//
+// ctx: the context to use while waiting for the command
+//
+// deviceType: the type of device (e.g. camera, owl, doorbell)
+//
+// networkId: the network ID that the device is on
+//
+// deviceId: the ID of the device to capture the thumbnail on
+//
+// Example: client.RequestThumbnail(ctx, "owl", 5678, 9012) = &CommandResponse{Complete: true}, nil
+func (c *BlinkClient) RequestThumbnail(ctx context.Context, deviceType string, networkId int, deviceId int) (*CommandResponse, error) {
+	thumbnailPath, err := GetThumbnailPath(deviceType)
+	if err != nil {
+		return nil, fmt.Errorf("error getting thumbnail path: %w", err)
+	}
+
+	cmd, err := c.startCommand(ctx, fmt.Sprintf(thumbnailPath, c.ApiUrl(), c.AccountId, networkId, deviceId), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error sending thumbnail command: %w", err)
+	}
+
+	result, err := c.WaitForCommand(ctx, c.commandUrl(networkId, cmd.Id), COMMAND_POLL_INTERVAL)
+	if err != nil {
+		return nil, fmt.Errorf("error waiting for thumbnail command: %w", err)
+	}
+
+	return result, nil
+}
+
+// FindDevice returns the device with the given type and ID from the homescreen response
+//
+// deviceType: the type of device (e.g. camera, owl, doorbell)
+//
+// deviceId: the ID of the device to find
+//
+// Example: resp.FindDevice("owl", 9012) = &BaseCameraDevice{Id: 9012}, true
+func (resp *HomescreenResponse) FindDevice(deviceType string, deviceId int) (*BaseCameraDevice, bool) {
+	var devices []BaseCameraDevice
+	switch deviceType {
+	case "camera":
+		devices = resp.Cameras
+	case "doorbell", "lotus":
+		devices = resp.Doorbells
+	default:
+		devices = resp.Owls
+	}
+
+	for i := range devices {
+		if devices[i].Id == deviceId {
+			return &devices[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// ThumbnailUrl returns the absolute URL of a thumbnail path reported by the homescreen
+//
+// thumbnail: the thumbnail path from BaseCameraDevice.Thumbnail
+//
+// Example: client.ThumbnailUrl("/media/u011/thumb") = "https://rest-u011.immedia-semi.com/media/u011/thumb.jpg"
+func (c *BlinkClient) ThumbnailUrl(thumbnail string) string {
+	if !strings.Contains(thumbnail, "?") && !strings.HasSuffix(thumbnail, ".jpg") {
+		thumbnail += ".jpg"
+	}
+	if strings.HasPrefix(thumbnail, "http://") || strings.HasPrefix(thumbnail, "https://") {
+		return thumbnail
+	}
+
+	return c.ApiUrl() + thumbnail
+}
+
+// Download streams an authenticated media file (e.g. thumbnail, clip) to the writer
+//
+// ctx: the context to use for the request
+//
+// url: the absolute URL of the media file
+//
+// writer: the writer to copy the file to
+//
+// Example: client.Download(ctx, "https://example.com/thumb.jpg", file) = 1024, nil
+func (c *BlinkClient) Download(ctx context.Context, url string, writer io.Writer) (int64, error) {
+	req, err := c.newRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// Media files can be large, so the regular request timeout is too short
+	resp, err := c.withTimeout(DOWNLOAD_TIMEOUT).doRetry(req)
+	if err != nil {
+		return 0, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, newAPIError(resp)
+	}
+
+	return io.Copy(writer, resp.Body)
+}
+
+// Snapshot captures a new thumbnail on the device and downloads the resulting JPEG
+//
+// ctx: the context to use for the requests and while waiting for the thumbnail command
+//
+// deviceType: the type of device (e.g. camera, owl, doorbell)
+//
+// networkId: the network ID that the device is on
+//
+// deviceId: the ID of the device to capture the snapshot on
+//
+// writer: the writer to copy the JPEG to
+//
+// Example: client.Snapshot(ctx, "owl", 5678, 9012, file) = nil
+func (c *BlinkClient) Snapshot(ctx context.Context, deviceType string, networkId int, deviceId int, writer io.Writer) error {
+	if _, err := c.RequestThumbnail(ctx, deviceType, networkId, deviceId); err != nil {
+		return err
+	}
+
+	homescreen, err := c.Homescreen(ctx, c.HomescreenUrl())
+	if err != nil {
+		return fmt.Errorf("error getting homescreen: %w", err)
+	}
+
+	device, ok := homescreen.FindDevice(deviceType, deviceId)
+	if !ok || device.Thumbnail == "" {
+		return fmt.Errorf("no thumbnail found for %s %d", deviceType, deviceId)
+	}
+
+	if _, err := c.Download(ctx, c.ThumbnailUrl(device.Thumbnail), writer); err != nil {
+		return fmt.Errorf("error downloading thumbnail: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestThumbnailUrl(t *testing.T) {
+	client := common.NewBlinkClient("", "u011", 0)
+
+	assert.Equal(t, "https://rest-u011.immedia-semi.com/media/thumb.jpg", client.ThumbnailUrl("/media/thumb"))
+	assert.Equal(t, "https://rest-u011.immedia-semi.com/media/thumb.jpg", client.ThumbnailUrl("/media/thumb.jpg"))
+	assert.Equal(t, "https://rest-u011.immedia-semi.com/media/thumb.jpg?ts=1", client.ThumbnailUrl("/media/thumb.jpg?ts=1"))
+	assert.Equal(t, "https://cdn.example.com/thumb.jpg", client.ThumbnailUrl("https://cdn.example.com/thumb.jpg"))
+}
+
+func TestFindDevice(t *testing.T) {
+	resp := common.HomescreenResponse{
+		Cameras:   []common.BaseCameraDevice{{Id: 1, Name: "Camera"}},
+		Owls:      []common.BaseCameraDevice{{Id: 1, Name: "Owl"}},
+		Doorbells: []common.BaseCameraDevice{{Id: 2, Name: "Doorbell"}},
+	}
+
+	camera, ok := resp.FindDevice("camera", 1)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "Camera", camera.Name)
+
+	owl, ok := resp.FindDevice("owl", 1)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "Owl", owl.Name)
+
+	doorbell, ok := resp.FindDevice("lotus", 2)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "Doorbell", doorbell.Name)
+
+	_, ok = resp.FindDevice("doorbell", 1)
+	assert.Equal(t, false, ok)
+}
+
+func TestSnapshotNominal(t *testing.T) {
+	var thumbnailRequested bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/accounts/1/networks/2/owls/3/thumbnail", func(w http.ResponseWriter, r *http.Request) {
+		thumbnailRequested = true
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": 999, "network_id": 2}`))
+	})
+	mux.HandleFunc("GET /network/2/command/999", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"complete": true}`))
+	})
+	mux.HandleFunc("GET /api/v4/accounts/1/homescreen", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"owls": [{"id": 3, "network_id": 2, "thumbnail": "/media/owl/3/thumb"}]}`))
+	})
+	mux.HandleFunc("GET /media/owl/3/thumb.jpg", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer xyz-auth-token", r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte{0xff, 0xd8, 0xff, 0xd9})
+	})
+	mockServer := httptest.NewServer(mux)
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	client.RestUrl = mockServer.URL
+
+	var image bytes.Buffer
+	err := client.Snapshot(context.Background(), "owl", 2, 3, &image)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, thumbnailRequested)
+	assert.Equal(t, []byte{0xff, 0xd8, 0xff, 0xd9}, image.Bytes())
+}
+
+func TestSnapshotMissingThumbnail(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /network/2/camera/3/thumbnail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": 999, "network_id": 2}`))
+	})
+	mux.HandleFunc("GET /network/2/command/999", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"complete": true}`))
+	})
+	mux.HandleFunc("GET /api/v4/accounts/1/homescreen", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"cameras": []}`))
+	})
+	mockServer := httptest.NewServer(mux)
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1)
+	client.RestUrl = mockServer.URL
+
+	err := client.Snapshot(context.Background(), "camera", 2, 3, &bytes.Buffer{})
+
+	assert.Equal(t, "no thumbnail found for camera 3", err.Error())
+}
+
+func TestDownloadHttpError(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer mockServer.Close()
+
+	n, err := common.NewBlinkClient("xyz-auth-token", "", 1).Download(context.Background(), mockServer.URL, &bytes.Buffer{})
+
+	assert.Equal(t, int64(0), n)
+	assert.Equal(t, "HTTP Status Code 404", err.Error())
+}
@@ -0,0 +1,295 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DisconnectReason classifies why a liveview stream connection ended
+type DisconnectReason string
+
+const (
+	// The stream is still connected, or has not connected yet
+	DisconnectNone DisconnectReason = ""
+	// The context was cancelled (e.g. the user stopped the stream)
+	DisconnectCancelled DisconnectReason = "cancelled"
+	// The connection to the stream server could not be established
+	DisconnectDial DisconnectReason = "dial"
+	// The server closed the connection between two packets
+	DisconnectEOF DisconnectReason = "eof"
+	// The server closed the connection in the middle of a packet
+	DisconnectUnexpectedEOF DisconnectReason = "unexpected_eof"
+	// The framing could not be decoded
+	DisconnectOutOfSync DisconnectReason = "out_of_sync"
+	// The server reset the connection (ECONNRESET)
+	DisconnectReset DisconnectReason = "reset"
+	// No data was read within READ_TIMEOUT
+	DisconnectTimeout DisconnectReason = "timeout"
+	// The video could not be written to the writer
+	DisconnectWriter DisconnectReason = "writer"
+	// Any other connection error, including failed keep-alives
+	DisconnectError DisconnectReason = "error"
+)
+
+// StreamStats records the transport statistics of a liveview stream.
+// Safe for concurrent use, so it can be queried with Snapshot while the stream runs.
+// Reuse the same stats across renewals to count the reconnects.
+type StreamStats struct {
+	mu                     sync.RWMutex
+	startedAt              time.Time
+	connectedAt            time.Time
+	endedAt                time.Time
+	connections            int
+	bytesReceived          int64
+	reads                  int64
+	videoBytes             int64
+	packets                int64
+	keepalivesSent         int64
+	keepalivesAcknowledged int64
+	timeToFirstByte        time.Duration
+	longestReadGap         time.Duration
+	lastRead               time.Time
+	reason                 DisconnectReason
+	lastErr                error
+}
+
+type StreamStatsSnapshot struct {
+	// When the first connection was attempted
+	StartedAt time.Time `json:"started_at"`
+	// When the latest connection was established. Zero before the first connection
+	ConnectedAt time.Time `json:"connected_at"`
+	// When the latest connection ended. Zero while connected
+	EndedAt time.Time `json:"ended_at"`
+	// Whether a connection is currently open
+	Connected bool `json:"connected"`
+	// The number of connections after the first one (e.g. renewed sessions)
+	Reconnects int `json:"reconnects"`
+	// The bytes read from the stream server, including the framing
+	BytesReceived int64 `json:"bytes_received"`
+	// The number of reads from the stream server that returned data
+	Reads int64 `json:"reads"`
+	// The video payload bytes written to the writer
+	VideoBytes int64 `json:"video_bytes"`
+	// The number of packets read, of every kind
+	Packets int64 `json:"packets"`
+	// The keep-alive pings sent to the server
+	KeepalivesSent int64 `json:"keepalives_sent"`
+	// The keep-alive frames received from the server
+	KeepalivesAcknowledged int64 `json:"keepalives_acknowledged"`
+	// The time from the connection attempt to the first byte read, for the latest connection
+	TimeToFirstByte time.Duration `json:"-"`
+	// The longest pause between two reads within a connection
+	LongestReadGap time.Duration `json:"-"`
+	// Why the latest connection ended. Empty while connected
+	DisconnectReason DisconnectReason `json:"disconnect_reason,omitempty"`
+	// The error that ended the latest connection. Empty when it ended without an error
+	Error string `json:"error,omitempty"`
+}
+
+// NewStreamStats creates empty stream statistics
+//
+// Example: NewStreamStats().Snapshot() = StreamStatsSnapshot{}
+func NewStreamStats() *StreamStats {
+	return &StreamStats{}
+}
+
+// Snapshot returns a copy of the statistics
+//
+// Example: Snapshot() = StreamStatsSnapshot{BytesReceived: 1024, Reads: 3, ...}
+func (s *StreamStats) Snapshot() StreamStatsSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := StreamStatsSnapshot{
+		StartedAt:              s.startedAt,
+		ConnectedAt:            s.connectedAt,
+		EndedAt:                s.endedAt,
+		Connected:              !s.connectedAt.IsZero() && s.endedAt.IsZero(),
+		Reconnects:             max(s.connections-1, 0),
+		BytesReceived:          s.bytesReceived,
+		Reads:                  s.reads,
+		VideoBytes:             s.videoBytes,
+		Packets:                s.packets,
+		KeepalivesSent:         s.keepalivesSent,
+		KeepalivesAcknowledged: s.keepalivesAcknowledged,
+		TimeToFirstByte:        s.timeToFirstByte,
+		LongestReadGap:         s.longestReadGap,
+		DisconnectReason:       s.reason,
+	}
+	if s.lastErr != nil {
+		snapshot.Error = s.lastErr.Error()
+	}
+
+	return snapshot
+}
+
+// MarshalJSON encodes the snapshot with the durations in milliseconds
+//
+// Example: MarshalJSON() = `{"bytes_received":1024,"time_to_first_byte_ms":120,...}`, nil
+func (s StreamStatsSnapshot) MarshalJSON() ([]byte, error) {
+	type snapshot StreamStatsSnapshot
+
+	return json.Marshal(struct {
+		snapshot
+		TimeToFirstByteMs int64 `json:"time_to_first_byte_ms"`
+		LongestReadGapMs  int64 `json:"longest_read_gap_ms"`
+	}{
+		snapshot:          snapshot(s),
+		TimeToFirstByteMs: s.TimeToFirstByte.Milliseconds(),
+		LongestReadGapMs:  s.LongestReadGap.Milliseconds(),
+	})
+}
+
+// dialing records a connection attempt. Methods are no-ops on nil stats
+func (s *StreamStats) dialing() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.startedAt.IsZero() {
+		s.startedAt = now
+	}
+	// Time to first byte and read gaps are measured from the attempt
+	s.lastRead = now
+	s.timeToFirstByte = 0
+	s.connectedAt = time.Time{}
+	s.endedAt = time.Time{}
+	s.reason = DisconnectNone
+	s.lastErr = nil
+}
+
+// connected records an established connection
+func (s *StreamStats) connected() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.connections++
+	s.connectedAt = time.Now()
+}
+
+// read records a read of n bytes from the stream server
+func (s *StreamStats) read(n int) {
+	if s == nil || n <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.timeToFirstByte == 0 {
+		s.timeToFirstByte = now.Sub(s.lastRead)
+	} else if gap := now.Sub(s.lastRead); gap > s.longestReadGap {
+		s.longestReadGap = gap
+	}
+	s.lastRead = now
+	s.bytesReceived += int64(n)
+	s.reads++
+}
+
+// packet records a demuxed packet
+func (s *StreamStats) packet(packet *StreamPacket) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.packets++
+	if packet.Kind == PacketKindVideo {
+		s.videoBytes += int64(len(packet.Payload))
+	}
+	if packet.Type == FRAME_TYPE_KEEPALIVE {
+		s.keepalivesAcknowledged++
+	}
+}
+
+// video records video bytes passed through without demuxing
+func (s *StreamStats) video(n int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.videoBytes += int64(n)
+}
+
+// keepaliveSent records a keep-alive ping
+func (s *StreamStats) keepaliveSent() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keepalivesSent++
+}
+
+// disconnected records why the connection ended
+func (s *StreamStats) disconnected(reason DisconnectReason, err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.endedAt = time.Now()
+	s.reason = reason
+	s.lastErr = err
+}
+
+// Conn returns a connection recording the reads into the stats
+//
+// conn: the connection to the stream server
+//
+// Example: stats.Conn(conn).Read(buf)
+func (s *StreamStats) Conn(conn net.Conn) net.Conn {
+	return &statsConn{Conn: conn, stats: s}
+}
+
+type statsConn struct {
+	net.Conn
+	stats *StreamStats
+}
+
+func (c *statsConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.stats.read(n)
+
+	return n, err
+}
+
+// classifyDisconnect describes the read error that ended the stream
+//
+// err: the error returned by the demuxer
+//
+// Example: classifyDisconnect(io.EOF) = DisconnectEOF, "connection closed gracefully by peer: EOF"
+func classifyDisconnect(err error) (DisconnectReason, error) {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, io.EOF):
+		return DisconnectEOF, fmt.Errorf("connection closed gracefully by peer: %w", err)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return DisconnectUnexpectedEOF, fmt.Errorf("connection closed mid-packet: %w", err)
+	case errors.Is(err, ErrFrameTooLarge):
+		return DisconnectOutOfSync, fmt.Errorf("stream out of sync: %w", err)
+	case errors.Is(err, syscall.ECONNRESET):
+		return DisconnectReset, fmt.Errorf("connection reset by peer: %w", err)
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return DisconnectTimeout, fmt.Errorf("read timeout: %w", err)
+	default:
+		return DisconnectError, fmt.Errorf("error reading from server: %w", err)
+	}
+}
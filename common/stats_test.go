@@ -0,0 +1,143 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+// serveFrames writes the frames to every connection accepted by the listener, then closes it unless hold is set
+func serveFrames(listener net.Listener, data []byte, hold bool) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			go io.Copy(io.Discard, conn)
+
+			conn.Write(data)
+			if hold {
+				time.Sleep(time.Minute)
+			}
+		}()
+	}
+}
+
+func TestTCPStreamStats(t *testing.T) {
+	listener, cert := newTLSListener(t)
+	data := bytes.Join([][]byte{
+		frame(common.FRAME_TYPE_VIDEO, 1, []byte("first")),
+		frame(common.FRAME_TYPE_KEEPALIVE, 2, make([]byte, 24)),
+		frame(common.FRAME_TYPE_VIDEO, 3, []byte("second")),
+	}, nil)
+	go serveFrames(listener, data, false)
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	connInfo := common.ConnectionDetails{Host: host, Port: port, ClientId: 1, ConnectionId: "abc"}
+	stats := common.NewStreamStats()
+	opts := common.StreamOptions{TLS: trustedBy(cert), Stats: stats}
+
+	err := common.TCPStreamWithOptions(context.Background(), connInfo, io.Discard, opts)
+	assert.Equal(t, true, errors.Is(err, io.EOF))
+
+	snapshot := stats.Snapshot()
+	assert.Equal(t, int64(len(data)), snapshot.BytesReceived)
+	assert.Equal(t, true, snapshot.Reads > 0)
+	assert.Equal(t, int64(len("firstsecond")), snapshot.VideoBytes)
+	assert.Equal(t, int64(3), snapshot.Packets)
+	assert.Equal(t, int64(1), snapshot.KeepalivesAcknowledged)
+	assert.Equal(t, true, snapshot.TimeToFirstByte > 0)
+	assert.Equal(t, common.DisconnectEOF, snapshot.DisconnectReason)
+	assert.Equal(t, err.Error(), snapshot.Error)
+	assert.Equal(t, false, snapshot.Connected)
+	assert.Equal(t, 0, snapshot.Reconnects)
+
+	// Renewed sessions share the stats
+	err = common.TCPStreamWithOptions(context.Background(), connInfo, io.Discard, opts)
+	assert.Equal(t, true, errors.Is(err, io.EOF))
+
+	snapshot = stats.Snapshot()
+	assert.Equal(t, int64(2*len(data)), snapshot.BytesReceived)
+	assert.Equal(t, 1, snapshot.Reconnects)
+}
+
+func TestTCPStreamStatsWhileRunning(t *testing.T) {
+	listener, cert := newTLSListener(t)
+	go serveFrames(listener, frame(common.FRAME_TYPE_VIDEO, 1, []byte("first")), true)
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	mockCtx, mockCancel := context.WithCancel(context.Background())
+	defer mockCancel()
+
+	stats := common.NewStreamStats()
+	received := make(chan struct{}, 1)
+	done := make(chan error)
+	go func() {
+		done <- common.TCPStreamWithOptions(mockCtx, common.ConnectionDetails{Host: host, Port: port, ClientId: 1, ConnectionId: "abc"}, io.Discard, common.StreamOptions{
+			TLS:   trustedBy(cert),
+			Stats: stats,
+			OnPacket: func(packet *common.StreamPacket) {
+				received <- struct{}{}
+			},
+		})
+	}()
+
+	<-received
+	snapshot := stats.Snapshot()
+	assert.Equal(t, true, snapshot.Connected)
+	assert.Equal(t, int64(1), snapshot.Packets)
+	assert.Equal(t, common.DisconnectNone, snapshot.DisconnectReason)
+
+	mockCancel()
+	assert.Equal(t, nil, <-done)
+
+	snapshot = stats.Snapshot()
+	assert.Equal(t, false, snapshot.Connected)
+	assert.Equal(t, common.DisconnectCancelled, snapshot.DisconnectReason)
+	assert.Equal(t, "", snapshot.Error)
+}
+
+func TestTCPStreamStatsDial(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Equal(t, nil, err)
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+
+	stats := common.NewStreamStats()
+	err = common.TCPStreamWithOptions(context.Background(), common.ConnectionDetails{Host: host, Port: port}, io.Discard, common.StreamOptions{Stats: stats})
+	assert.NotEqual(t, nil, err)
+
+	snapshot := stats.Snapshot()
+	assert.Equal(t, common.DisconnectDial, snapshot.DisconnectReason)
+	assert.Equal(t, false, snapshot.Connected)
+	assert.Equal(t, 0, snapshot.Reconnects)
+}
+
+func TestStreamStatsJSON(t *testing.T) {
+	snapshot := common.StreamStatsSnapshot{
+		BytesReceived:    1024,
+		TimeToFirstByte:  120 * time.Millisecond,
+		LongestReadGap:   2 * time.Second,
+		DisconnectReason: common.DisconnectTimeout,
+	}
+
+	encoded, err := json.Marshal(snapshot)
+	assert.Equal(t, nil, err)
+
+	var data map[string]interface{}
+	assert.Equal(t, nil, json.Unmarshal(encoded, &data))
+	assert.Equal(t, float64(1024), data["bytes_received"])
+	assert.Equal(t, float64(120), data["time_to_first_byte_ms"])
+	assert.Equal(t, float64(2000), data["longest_read_gap_ms"])
+	assert.Equal(t, "timeout", data["disconnect_reason"])
+}
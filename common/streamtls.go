@@ -0,0 +1,295 @@
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// STREAM_DIAL_TIMEOUT is the timeout for the TCP connection to the liveview stream server
+var STREAM_DIAL_TIMEOUT = 10 * time.Second
+
+// STREAM_HANDSHAKE_TIMEOUT is the timeout for the TLS handshake with the liveview stream server
+var STREAM_HANDSHAKE_TIMEOUT = 10 * time.Second
+
+// STREAM_CERTIFICATE_DOMAINS are the domains a stream server certificate may be issued to when the
+// connection string only holds an IP address
+var STREAM_CERTIFICATE_DOMAINS = []string{"immedia-semi.com"}
+
+// SPKI_PIN_PREFIX prefixes the base64 SHA-256 hash of a SubjectPublicKeyInfo in a pin
+const SPKI_PIN_PREFIX = "sha256/"
+
+type StreamTLS struct {
+	// Skips the certificate chain and host name verification. Pins are still enforced.
+	// Exposes the stream to interception without pins
+	Insecure bool
+	// The CA certificates to verify the stream server with. Nil uses the system roots
+	RootCAs *x509.CertPool
+	// The accepted public keys, as "sha256/<base64 SHA-256 of the SubjectPublicKeyInfo>".
+	// At least one certificate in the chain must match. Empty accepts any verified certificate
+	Pins []string
+}
+
+// DefaultStreamTLS is the TLS configuration used by stream connections that do not set StreamOptions.TLS.
+// Verifies the stream server with the system roots.
+//
+// Connection strings hold IP addresses, and a certificate for any STREAM_CERTIFICATE_DOMAINS name is
+// accepted for them, so the peer IP is never tied to the name. Anyone holding a publicly trusted
+// certificate for such a name can intercept the stream. No pins are shipped, so set Pins (--pin)
+// for real protection against interception. The verification has not been checked against every
+// stream server of the real service, which may present certificates the system roots reject.
+var DefaultStreamTLS = &StreamTLS{}
+
+// CertificateError is returned when the stream server certificate cannot be verified
+// (e.g. unknown authority, expired, issued to another host)
+type CertificateError struct {
+	// The stream server host
+	Host string
+	// The verification error
+	Err error
+}
+
+// Error returns a description of the certificate error
+//
+// Example: Error() = "untrusted certificate for 3.233.10.25: x509: certificate signed by unknown authority"
+func (e *CertificateError) Error() string {
+	return fmt.Sprintf("untrusted certificate for %s: %v", e.Host, e.Err)
+}
+
+// Unwrap returns the verification error
+func (e *CertificateError) Unwrap() error {
+	return e.Err
+}
+
+// PinError is returned when no certificate of the stream server matches the pinned public keys
+type PinError struct {
+	// The stream server host
+	Host string
+	// The pins of the certificates presented by the server
+	Presented []string
+}
+
+// Error returns a description of the pin mismatch
+//
+// Example: Error() = "no certificate for 3.233.10.25 matches the pinned keys (presented sha256/abc=)"
+func (e *PinError) Error() string {
+	return fmt.Sprintf("no certificate for %s matches the pinned keys (presented %s)", e.Host, strings.Join(e.Presented, ", "))
+}
+
+// HandshakeError is returned when the TLS handshake with the stream server fails or times out
+type HandshakeError struct {
+	// The stream server host
+	Host string
+	// The handshake error
+	Err error
+}
+
+// Error returns a description of the handshake error
+//
+// Example: Error() = "TLS handshake with 3.233.10.25 failed: context deadline exceeded"
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("TLS handshake with %s failed: %v", e.Host, e.Err)
+}
+
+// Unwrap returns the handshake error
+func (e *HandshakeError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports whether the handshake timed out
+func (e *HandshakeError) Timeout() bool {
+	return errors.Is(e.Err, context.DeadlineExceeded)
+}
+
+// IsTLSError reports whether the error is a certificate, pin or handshake error
+//
+// err: the error to check
+//
+// Example: IsTLSError(&PinError{}) = true
+func IsTLSError(err error) bool {
+	var certErr *CertificateError
+	var pinErr *PinError
+	var handshakeErr *HandshakeError
+
+	return errors.As(err, &certErr) || errors.As(err, &pinErr) || errors.As(err, &handshakeErr)
+}
+
+// LoadCABundle reads the PEM encoded CA certificates to verify the stream server with
+//
+// path: the path of the PEM bundle
+//
+// Example: LoadCABundle("/etc/ssl/blink.pem") = &x509.CertPool{}, nil
+func LoadCABundle(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CA bundle: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", path)
+	}
+
+	return pool, nil
+}
+
+// SPKIPin returns the pin of the certificate's public key
+//
+// cert: the certificate to pin
+//
+// Example: SPKIPin(cert) = "sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+func SPKIPin(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+	return SPKI_PIN_PREFIX + base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// ValidatePin checks that the pin is a base64 SHA-256 hash with the SPKI_PIN_PREFIX
+//
+// pin: the pin to check
+//
+// Example: ValidatePin("sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=") = nil
+func ValidatePin(pin string) error {
+	encoded, ok := strings.CutPrefix(pin, SPKI_PIN_PREFIX)
+	if !ok {
+		return fmt.Errorf("pin %q must start with %s", pin, SPKI_PIN_PREFIX)
+	}
+
+	hash, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(hash) != sha256.Size {
+		return fmt.Errorf("pin %q is not a base64 SHA-256 hash", pin)
+	}
+
+	return nil
+}
+
+// DialStream connects to the stream server and completes the TLS handshake, verifying the server.
+// The dial (including the proxy negotiation) and the handshake have separate timeouts (STREAM_DIAL_TIMEOUT, STREAM_HANDSHAKE_TIMEOUT).
+// Returns a CertificateError, PinError or HandshakeError when the server cannot be trusted.
+//
+// ctx: the context to use for the dial and handshake, including cancellation
+//
+// host: the stream server host
+//
+// port: the stream server port
+//
+// opts: the TLS options. Nil uses DefaultStreamTLS
+//
+// proxy: the proxy to connect through. Nil uses DefaultProxy
+//
+// Example: DialStream(ctx, "3.233.10.25", "443", nil, nil) = &tls.Conn{}, nil
+func DialStream(ctx context.Context, host string, port string, opts *StreamTLS, proxy *ProxyConfig) (*tls.Conn, error) {
+	if opts == nil {
+		opts = DefaultStreamTLS
+	}
+	if proxy == nil {
+		proxy = DefaultProxy
+	}
+
+	dialCtx, cancelDial := context.WithTimeout(ctx, STREAM_DIAL_TIMEOUT)
+	defer cancelDial()
+
+	rawConn, err := proxy.DialContext(dialCtx, net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, err
+	}
+
+	conn := tls.Client(rawConn, opts.config(host))
+
+	handshakeCtx, cancelHandshake := context.WithTimeout(ctx, STREAM_HANDSHAKE_TIMEOUT)
+	defer cancelHandshake()
+
+	if err := conn.HandshakeContext(handshakeCtx); err != nil {
+		rawConn.Close()
+
+		var certErr *CertificateError
+		var pinErr *PinError
+		if ctx.Err() != nil || errors.As(err, &certErr) || errors.As(err, &pinErr) {
+			return nil, err
+		}
+		return nil, &HandshakeError{Host: host, Err: err}
+	}
+
+	return conn, nil
+}
+
+// config builds the TLS configuration for the stream server.
+// The standard verification is replaced by verifyConnection, which also accepts IP hosts
+// with a certificate issued to one of STREAM_CERTIFICATE_DOMAINS, and enforces the pins.
+func (o *StreamTLS) config(host string) *tls.Config {
+	return &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			return o.verifyConnection(host, state)
+		},
+	}
+}
+
+// verifyConnection verifies the certificate chain and host, then the pins
+func (o *StreamTLS) verifyConnection(host string, state tls.ConnectionState) error {
+	certs := state.PeerCertificates
+	if len(certs) == 0 {
+		return &CertificateError{Host: host, Err: errors.New("no certificate presented")}
+	}
+
+	if !o.Insecure {
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+
+		if _, err := certs[0].Verify(x509.VerifyOptions{Roots: o.RootCAs, Intermediates: intermediates}); err != nil {
+			return &CertificateError{Host: host, Err: err}
+		}
+		if err := verifyStreamHost(certs[0], host); err != nil {
+			return &CertificateError{Host: host, Err: err}
+		}
+	}
+
+	if len(o.Pins) == 0 {
+		return nil
+	}
+
+	var presented []string
+	for _, cert := range certs {
+		pin := SPKIPin(cert)
+		if slices.Contains(o.Pins, pin) {
+			return nil
+		}
+		presented = append(presented, pin)
+	}
+
+	return &PinError{Host: host, Presented: presented}
+}
+
+// verifyStreamHost checks that the certificate was issued to the host.
+// Connection strings usually hold an IP address, so a certificate issued to one of
+// STREAM_CERTIFICATE_DOMAINS is also accepted for IP hosts, whichever name it was issued to.
+// Certificates for unrelated domains are still rejected.
+func verifyStreamHost(cert *x509.Certificate, host string) error {
+	err := cert.VerifyHostname(host)
+	if err == nil || net.ParseIP(host) == nil {
+		return err
+	}
+
+	for _, name := range cert.DNSNames {
+		name = strings.TrimPrefix(name, "*.")
+		for _, domain := range STREAM_CERTIFICATE_DOMAINS {
+			if name == domain || strings.HasSuffix(name, "."+domain) {
+				return nil
+			}
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,202 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+// newCertificate generates a self-signed certificate for the IP addresses and host names
+func newCertificate(t *testing.T, hosts ...string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Equal(t, nil, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Equal(t, nil, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// newStreamServer starts a TLS server on localhost that completes the handshake with the certificate
+func newStreamServer(t *testing.T, certificate tls.Certificate) (string, *x509.Certificate) {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{certificate}})
+	assert.Equal(t, nil, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if conn.(*tls.Conn).Handshake() == nil {
+					io.Copy(io.Discard, conn)
+				}
+			}()
+		}
+	}()
+
+	cert, err := x509.ParseCertificate(certificate.Certificate[0])
+	assert.Equal(t, nil, err)
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	return port, cert
+}
+
+func TestDialStreamUnknownAuthority(t *testing.T) {
+	certificate := newCertificate(t, "127.0.0.1")
+	port, cert := newStreamServer(t, certificate)
+
+	_, err := common.DialStream(context.Background(), "127.0.0.1", port, nil, nil)
+	var certErr *common.CertificateError
+	assert.Equal(t, true, errors.As(err, &certErr))
+	assert.Equal(t, true, common.IsTLSError(err))
+
+	conn, err := common.DialStream(context.Background(), "127.0.0.1", port, trustedBy(cert), nil)
+	assert.Equal(t, nil, err)
+	conn.Close()
+}
+
+func TestDialStreamHost(t *testing.T) {
+	// Connection strings hold IP addresses, so certificates issued to the stream domains are accepted
+	certificate := newCertificate(t, "lv-01.immedia-semi.com")
+	port, cert := newStreamServer(t, certificate)
+
+	conn, err := common.DialStream(context.Background(), "127.0.0.1", port, trustedBy(cert), nil)
+	assert.Equal(t, nil, err)
+	conn.Close()
+
+	certificate = newCertificate(t, "example.com")
+	port, cert = newStreamServer(t, certificate)
+
+	_, err = common.DialStream(context.Background(), "127.0.0.1", port, trustedBy(cert), nil)
+	var certErr *common.CertificateError
+	assert.Equal(t, true, errors.As(err, &certErr))
+	assert.Equal(t, certErr.Host, "127.0.0.1")
+}
+
+func TestDialStreamHostUnrelatedDomain(t *testing.T) {
+	// Trusted certificates for other domains are rejected for IP hosts, including look-alike names
+	for _, name := range []string{"example.com", "immedia-semi.com.example.com", "notimmedia-semi.com"} {
+		port, cert := newStreamServer(t, newCertificate(t, name))
+
+		_, err := common.DialStream(context.Background(), "127.0.0.1", port, trustedBy(cert), nil)
+		var certErr *common.CertificateError
+		assert.Equal(t, true, errors.As(err, &certErr))
+		assert.Equal(t, "127.0.0.1", certErr.Host)
+	}
+}
+
+func TestDialStreamPins(t *testing.T) {
+	certificate := newCertificate(t, "127.0.0.1")
+	port, cert := newStreamServer(t, certificate)
+	pin := common.SPKIPin(cert)
+	otherPin := "sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+
+	opts := trustedBy(cert)
+	opts.Pins = []string{otherPin, pin}
+	conn, err := common.DialStream(context.Background(), "127.0.0.1", port, opts, nil)
+	assert.Equal(t, nil, err)
+	conn.Close()
+
+	opts.Pins = []string{otherPin}
+	_, err = common.DialStream(context.Background(), "127.0.0.1", port, opts, nil)
+	var pinErr *common.PinError
+	assert.Equal(t, true, errors.As(err, &pinErr))
+	assert.Equal(t, pinErr.Presented, []string{pin})
+
+	// Pins are enforced without verification
+	_, err = common.DialStream(context.Background(), "127.0.0.1", port, &common.StreamTLS{Insecure: true, Pins: []string{otherPin}}, nil)
+	assert.Equal(t, true, errors.As(err, &pinErr))
+
+	conn, err = common.DialStream(context.Background(), "127.0.0.1", port, &common.StreamTLS{Insecure: true, Pins: []string{pin}}, nil)
+	assert.Equal(t, nil, err)
+	conn.Close()
+
+	conn, err = common.DialStream(context.Background(), "127.0.0.1", port, &common.StreamTLS{Insecure: true}, nil)
+	assert.Equal(t, nil, err)
+	conn.Close()
+}
+
+func TestDialStreamHandshakeTimeout(t *testing.T) {
+	// Accepts the TCP connection but never completes the TLS handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Equal(t, nil, err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(io.Discard, conn)
+	}()
+
+	handshakeTimeout := common.STREAM_HANDSHAKE_TIMEOUT
+	common.STREAM_HANDSHAKE_TIMEOUT = 100 * time.Millisecond
+	defer func() { common.STREAM_HANDSHAKE_TIMEOUT = handshakeTimeout }()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	_, err = common.DialStream(context.Background(), "127.0.0.1", port, nil, nil)
+
+	var handshakeErr *common.HandshakeError
+	assert.Equal(t, true, errors.As(err, &handshakeErr))
+	assert.Equal(t, true, handshakeErr.Timeout())
+}
+
+func TestValidatePin(t *testing.T) {
+	assert.Equal(t, nil, common.ValidatePin("sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="))
+	assert.NotEqual(t, nil, common.ValidatePin("47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="))
+	assert.NotEqual(t, nil, common.ValidatePin("sha256/not-base64"))
+	assert.NotEqual(t, nil, common.ValidatePin("sha256/AAAA"))
+}
+
+func TestLoadCABundle(t *testing.T) {
+	certificate := newCertificate(t, "127.0.0.1")
+	port, _ := newStreamServer(t, certificate)
+
+	bundle := filepath.Join(t.TempDir(), "bundle.pem")
+	assert.Equal(t, nil, os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Certificate[0]}), 0600))
+
+	pool, err := common.LoadCABundle(bundle)
+	assert.Equal(t, nil, err)
+
+	conn, err := common.DialStream(context.Background(), "127.0.0.1", port, &common.StreamTLS{RootCAs: pool}, nil)
+	assert.Equal(t, nil, err)
+	conn.Close()
+
+	assert.Equal(t, nil, os.WriteFile(bundle, []byte("not a certificate"), 0600))
+	_, err = common.LoadCABundle(bundle)
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,188 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// COMMAND_STOP_TIMEOUT is the maximum time to wait for Blink to acknowledge a stop request
+var COMMAND_STOP_TIMEOUT = 10 * time.Second
+
+// COMMAND_UPDATE_BUFFER is the number of command updates kept for slow readers of Updates
+var COMMAND_UPDATE_BUFFER = 16
+
+// CommandSupervisor keeps a long running command (e.g. liveview) alive and tracks its lifecycle.
+// The context returned by Start is cancelled as soon as Blink marks the command as complete or polling fails.
+type CommandSupervisor struct {
+	// Called with every command status update. Optional. Must not block
+	OnUpdate func(CommandResponse)
+
+	client       *BlinkClient
+	networkId    int
+	commandId    int
+	pollInterval int
+
+	updates chan CommandResponse
+	done    chan struct{}
+
+	mu      sync.Mutex
+	started bool
+	cancel  context.CancelCauseFunc
+	err     error
+
+	stopOnce sync.Once
+	stopErr  error
+}
+
+// NewCommandSupervisor creates a supervisor for a command on the network
+//
+// networkId: the network the command was issued on
+//
+// commandId: the ID of the command
+//
+// pollInterval: the interval to wait between polls in seconds. Defaults to COMMAND_POLL_INTERVAL when zero
+//
+// Example: client.NewCommandSupervisor(5678, 75888, 15)
+func (c *BlinkClient) NewCommandSupervisor(networkId int, commandId int, pollInterval int) *CommandSupervisor {
+	if pollInterval <= 0 {
+		pollInterval = COMMAND_POLL_INTERVAL
+	}
+
+	return &CommandSupervisor{
+		client:       c,
+		networkId:    networkId,
+		commandId:    commandId,
+		pollInterval: pollInterval,
+		updates:      make(chan CommandResponse, COMMAND_UPDATE_BUFFER),
+		done:         make(chan struct{}),
+	}
+}
+
+// Start polls the command in the background until the returned context is done.
+// The returned context is cancelled when the parent is cancelled, when Stop is called,
+// or when the command completes or fails. context.Cause reports the reason.
+//
+// ctx: the parent context, usually the stream's context
+//
+// Example: streamCtx := supervisor.Start(ctx)
+func (s *CommandSupervisor) Start(ctx context.Context) context.Context {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		cancel(fmt.Errorf("command %d is already supervised", s.commandId))
+		return ctx
+	}
+	s.started = true
+	s.cancel = cancel
+	s.mu.Unlock()
+
+	go func() {
+		defer close(s.done)
+		defer close(s.updates)
+
+		if err := s.poll(ctx); err != nil {
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+
+			cancel(err)
+		}
+	}()
+
+	return ctx
+}
+
+// Updates returns the command status updates. The channel is closed when supervision ends.
+// The oldest update is dropped when the reader falls more than COMMAND_UPDATE_BUFFER updates behind.
+//
+// Example: for update := range supervisor.Updates() { ... }
+func (s *CommandSupervisor) Updates() <-chan CommandResponse {
+	return s.updates
+}
+
+// Done returns a channel that is closed when supervision ends
+//
+// Example: <-supervisor.Done()
+func (s *CommandSupervisor) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns why supervision ended early. Nil while running or when the parent context was cancelled.
+// Returns ErrCommandComplete when Blink marked the command as complete.
+//
+// Example: supervisor.Err() = ErrCommandComplete
+func (s *CommandSupervisor) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// Stop ends supervision and marks the command as done on Blink.
+// The stop request is sent exactly once, bounded by COMMAND_STOP_TIMEOUT. Later calls return the first result.
+//
+// Example: defer supervisor.Stop()
+func (s *CommandSupervisor) Stop() error {
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.cancel(context.Canceled)
+	}
+	s.mu.Unlock()
+
+	s.stopOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), COMMAND_STOP_TIMEOUT)
+		defer cancel()
+
+		s.stopErr = s.client.StopCommand(ctx, s.client.commandUrl(s.networkId, s.commandId)+"/done")
+	})
+
+	return s.stopErr
+}
+
+// poll fetches the command status on every tick until the context is done or the command ends
+func (s *CommandSupervisor) poll(ctx context.Context) error {
+	ticker := time.NewTicker(time.Duration(s.pollInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			result, err := s.client.pollCommandOnce(ctx, s.client.commandUrl(s.networkId, s.commandId))
+			if ctx.Err() != nil {
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			s.publish(*result)
+
+			if result.Complete {
+				return ErrCommandComplete
+			}
+		}
+	}
+}
+
+// publish delivers an update to the callback and the updates channel, dropping the oldest buffered update when full
+func (s *CommandSupervisor) publish(update CommandResponse) {
+	if s.OnUpdate != nil {
+		s.OnUpdate(update)
+	}
+
+	select {
+	case s.updates <- update:
+	default:
+		// poll is the only sender, so draining one update always makes room
+		select {
+		case <-s.updates:
+		default:
+		}
+		s.updates <- update
+	}
+}
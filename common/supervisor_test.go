@@ -0,0 +1,165 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+// newSupervisorServer serves the command status and counts the stop requests
+func newSupervisorServer(t *testing.T, status func(poll int) (int, string), stops *atomic.Int32) *common.BlinkClient {
+	var polls atomic.Int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /network/5678/command/75888", func(w http.ResponseWriter, r *http.Request) {
+		code, body := status(int(polls.Add(1)))
+		w.WriteHeader(code)
+		w.Write([]byte(body))
+	})
+	mux.HandleFunc("POST /network/5678/command/75888/done", func(w http.ResponseWriter, r *http.Request) {
+		stops.Add(1)
+		w.Write([]byte(`{"code": 902, "message": "Command has been cancelled"}`))
+	})
+
+	mockServer := httptest.NewServer(mux)
+	t.Cleanup(mockServer.Close)
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1234)
+	client.RestUrl = mockServer.URL
+	client.Retry = nil
+
+	return client
+}
+
+func TestCommandSupervisorComplete(t *testing.T) {
+	var stops atomic.Int32
+	client := newSupervisorServer(t, func(poll int) (int, string) {
+		if poll >= 2 {
+			return http.StatusOK, `{"complete": true, "status_msg": "done"}`
+		}
+		return http.StatusOK, `{"complete": false, "status_msg": "running"}`
+	}, &stops)
+
+	var callbacks atomic.Int32
+	supervisor := client.NewCommandSupervisor(5678, 75888, 1)
+	supervisor.OnUpdate = func(update common.CommandResponse) {
+		callbacks.Add(1)
+	}
+
+	ctx := supervisor.Start(context.Background())
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream context was not cancelled when the command completed")
+	}
+
+	assert.Equal(t, true, errors.Is(context.Cause(ctx), common.ErrCommandComplete))
+	assert.Equal(t, common.ErrCommandComplete, supervisor.Err())
+
+	updates := []common.CommandResponse{}
+	for update := range supervisor.Updates() {
+		updates = append(updates, update)
+	}
+	assert.Equal(t, 2, len(updates))
+	assert.Equal(t, "running", updates[0].StatusMessage)
+	assert.Equal(t, true, updates[1].Complete)
+	assert.Equal(t, int32(2), callbacks.Load())
+
+	assert.Equal(t, nil, supervisor.Stop())
+	assert.Equal(t, int32(1), stops.Load())
+}
+
+func TestCommandSupervisorPollError(t *testing.T) {
+	var stops atomic.Int32
+	client := newSupervisorServer(t, func(poll int) (int, string) {
+		return http.StatusInternalServerError, ``
+	}, &stops)
+
+	supervisor := client.NewCommandSupervisor(5678, 75888, 1)
+	ctx := supervisor.Start(context.Background())
+
+	select {
+	case <-supervisor.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("supervisor did not end when polling failed")
+	}
+
+	assert.Equal(t, true, ctx.Err() != nil)
+	assert.Equal(t, "error polling command. HTTP Status Code 500", supervisor.Err().Error())
+	assert.Equal(t, supervisor.Err(), context.Cause(ctx))
+}
+
+func TestCommandSupervisorParentCancel(t *testing.T) {
+	var stops atomic.Int32
+	client := newSupervisorServer(t, func(poll int) (int, string) {
+		return http.StatusOK, `{"complete": false}`
+	}, &stops)
+
+	parent, cancel := context.WithCancel(context.Background())
+	supervisor := client.NewCommandSupervisor(5678, 75888, 1)
+	supervisor.Start(parent)
+	cancel()
+
+	select {
+	case <-supervisor.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("supervisor did not end when the parent context was cancelled")
+	}
+
+	assert.Equal(t, nil, supervisor.Err())
+}
+
+func TestCommandSupervisorStopOnce(t *testing.T) {
+	var stops atomic.Int32
+	client := newSupervisorServer(t, func(poll int) (int, string) {
+		return http.StatusOK, `{"complete": false}`
+	}, &stops)
+
+	supervisor := client.NewCommandSupervisor(5678, 75888, 1)
+	ctx := supervisor.Start(context.Background())
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			supervisor.Stop()
+		}()
+	}
+	wg.Wait()
+
+	<-supervisor.Done()
+	assert.Equal(t, context.Canceled, ctx.Err())
+	assert.Equal(t, int32(1), stops.Load())
+}
+
+func TestCommandSupervisorStopTimeout(t *testing.T) {
+	previous := common.COMMAND_STOP_TIMEOUT
+	common.COMMAND_STOP_TIMEOUT = 100 * time.Millisecond
+	t.Cleanup(func() { common.COMMAND_STOP_TIMEOUT = previous })
+
+	release := make(chan struct{})
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(mockServer.Close)
+	t.Cleanup(func() { close(release) })
+
+	client := common.NewBlinkClient("xyz-auth-token", "", 1234)
+	client.RestUrl = mockServer.URL
+
+	start := time.Now()
+	err := client.NewCommandSupervisor(5678, 75888, 1).Stop()
+
+	assert.Equal(t, true, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, true, time.Since(start) < 5*time.Second)
+}
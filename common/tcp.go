@@ -2,13 +2,11 @@ package common
 
 import (
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
-	"syscall"
 	"time"
 )
 
@@ -17,13 +15,7 @@ import (
 var READ_TIMEOUT = 10 * time.Second
 
 // FRAMES_KEEPALIVE is the keep-alive ping frame sent to the Blink stream server.
-var FRAMES_KEEPALIVE = []byte{
-	0x12, 0x00, 0x00, 0x03, 0xe8, 0x00, 0x00, 0x00,
-	0x18, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00,
-	0x00,
-}
+var FRAMES_KEEPALIVE = NewKeepalive().Marshal()
 
 type ConnectionDetails struct {
 	// The TCP host to connect to
@@ -36,8 +28,24 @@ type ConnectionDetails struct {
 	ConnectionId string
 }
 
+type StreamOptions struct {
+	// Called with every packet read from the stream, including video. Optional.
+	// Must not block, and must copy the payload to keep it
+	OnPacket func(*StreamPacket)
+	// Records the raw bytes read from and written to the stream server. Optional
+	Capture *CaptureWriter
+	// Verifies the stream server. Nil uses DefaultStreamTLS
+	TLS *StreamTLS
+	// The proxy to connect through. Nil uses DefaultProxy
+	Proxy *ProxyConfig
+	// Records the transport statistics, including the disconnect reason. Optional
+	Stats *StreamStats
+}
+
 // TCPStream connects to the liveview server using a TCP connection.
+// Only the MPEG-TS video payload is written to the writer, without the framing.
 // Returns an error if the connection fails or if the stream ends unexpectedly.
+// The server certificate is verified (see DialStream). Cancelling the context aborts the dial and TLS handshake, and closes an open connection.
 // TODO: Support audio I/O
 // TODO: Support command I/O (e.g. PTZ commands)
 //
@@ -49,31 +57,70 @@ type ConnectionDetails struct {
 //
 // Example: TCPStream(ctx, ConnectionDetails{Host: "example.com", Port: "443", ConnectionId: 1234, ClientId: 5678})
 func TCPStream(ctx context.Context, connInfo ConnectionDetails, writer io.Writer) error {
+	return TCPStreamWithOptions(ctx, connInfo, writer, StreamOptions{})
+}
+
+// TCPStreamWithOptions connects to the liveview server like TCPStream, and reports every packet
+// (e.g. audio and control messages) through the options.
+// Streams that do not use the frame format are written to the writer unchanged, without calling OnPacket.
+//
+// ctx: the context to use for the stream
+//
+// connInfo: the connection details to use to connect to the liveview server
+//
+// writer: the pipe to write the video stream to
+//
+// opts: the stream options
+//
+// Example: TCPStreamWithOptions(ctx, connInfo, pipe, StreamOptions{OnPacket: func(p *StreamPacket) { ... }})
+func TCPStreamWithOptions(ctx context.Context, connInfo ConnectionDetails, writer io.Writer, opts StreamOptions) error {
 	log.Printf("Connecting to %s:%s\n", connInfo.Host, connInfo.Port)
 
-	client, err := tls.Dial("tcp", fmt.Sprintf("%s:%s", connInfo.Host, connInfo.Port), &tls.Config{
-		InsecureSkipVerify: true,
-		ServerName:         connInfo.Host,
-		Certificates:       []tls.Certificate{},
-	})
+	stats := opts.Stats
+	stats.dialing()
+
+	client, err := DialStream(ctx, connInfo.Host, connInfo.Port, opts.TLS, opts.Proxy)
 	if err != nil {
+		stats.disconnected(DisconnectDial, err)
 		return fmt.Errorf("unable to initialize stream: %w", err)
-	} else {
-		log.Println("Connected to", client.RemoteAddr())
 	}
+	log.Println("Connected to", client.RemoteAddr())
 	defer client.Close()
+	stats.connected()
+
+	// Unblock pending reads and writes as soon as the context is cancelled
+	stop := context.AfterFunc(ctx, func() {
+		client.Close()
+	})
+	defer stop()
 	defer log.Printf("Disconnected from %s:%s\n", connInfo.Host, connInfo.Port)
 
+	// Reads and writes go through the stats and the capture, if any
+	var stream net.Conn = client
+	if stats != nil {
+		stream = stats.Conn(stream)
+	}
+	if opts.Capture != nil {
+		if err := opts.Capture.Record(CaptureEventConnect, []byte(client.RemoteAddr().String())); err != nil {
+			stats.disconnected(DisconnectError, err)
+			return err
+		}
+		stream = opts.Capture.Conn(stream)
+	}
+
 	start := time.Now()
 	frames := GetTCPAuthFrames(connInfo.ConnectionId, connInfo.ClientId)
 	for _, frame := range frames {
-		if _, err := client.Write(frame); err != nil {
-			return fmt.Errorf("error sending connection header: %w", err)
+		if _, err := stream.Write(frame); err != nil {
+			err = fmt.Errorf("error sending connection header: %w", err)
+			stats.disconnected(DisconnectError, err)
+			return err
 		}
 	}
 
-	buf := make([]byte, 64)
+	demuxer := NewDemuxer(stream)
 	var streamErr error
+	reason := DisconnectCancelled
 stream:
 	for {
 		select {
@@ -82,35 +129,48 @@ stream:
 			break stream
 		default:
 			if err := client.SetReadDeadline(time.Now().Add(READ_TIMEOUT)); err != nil {
-				streamErr = fmt.Errorf("error setting read deadline: %w", err)
+				if ctx.Err() == nil {
+					reason = DisconnectError
+					streamErr = fmt.Errorf("error setting read deadline: %w", err)
+				}
 				break stream
 			}
 
-			n, err := client.Read(buf)
-			if err != nil {
-				if errors.Is(err, io.EOF) {
-					streamErr = fmt.Errorf("connection closed gracefully by peer: %w", err)
-				} else if errors.Is(err, syscall.ECONNRESET) {
-					streamErr = fmt.Errorf("connection reset by peer: %w", err)
-				} else if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-					streamErr = fmt.Errorf("read timeout: %w", err)
+			packet, err := demuxer.Next()
+			if errors.Is(err, ErrUnknownFraming) {
+				log.Println("WARNING: passing the stream through unframed,", err)
+				reason, streamErr = passThrough(ctx, client, stream, demuxer.Raw(), writer, stats)
+				break stream
+			} else if err != nil {
+				if ctx.Err() != nil {
+					log.Println("Closing stream")
 				} else {
-					streamErr = fmt.Errorf("error reading from server: %w", err)
+					reason, streamErr = classifyDisconnect(err)
 				}
 				break stream
 			}
 
-			if _, err := writer.Write(buf[:n]); err != nil {
-				streamErr = fmt.Errorf("error writing to writer: %w", err)
-				break stream
+			stats.packet(packet)
+			if opts.OnPacket != nil {
+				opts.OnPacket(packet)
+			}
+
+			if packet.Kind == PacketKindVideo {
+				if _, err := writer.Write(packet.Payload); err != nil {
+					reason = DisconnectWriter
+					streamErr = fmt.Errorf("error writing to writer: %w", err)
+					break stream
+				}
 			}
 
 			// Send a keep-alive ping to the server
 			if time.Since(start) > time.Second {
-				if err := sendPing(client); err != nil {
+				if err := sendPing(stream); err != nil {
+					reason = DisconnectError
 					streamErr = fmt.Errorf("error sending keep-alive: %w", err)
 					break stream
 				}
+				stats.keepaliveSent()
 
 				// Reset the timer
 				start = time.Now()
@@ -118,15 +178,71 @@ stream:
 		}
 	}
 
+	stats.disconnected(reason, streamErr)
 	return streamErr
 }
 
+// passThrough writes the stream to the writer without demuxing it, sending a keep-alive every second.
+// Used when the server does not use the frame format, like the client did before the framing was decoded.
+//
+// ctx: the context of the stream
+//
+// client: the connection to set the read deadlines on
+//
+// stream: the connection to send the keep-alives on
+//
+// reader: the rest of the stream
+//
+// writer: the pipe to write the stream to
+//
+// stats: the stream statistics. Optional
+//
+// Example: passThrough(ctx, client, stream, demuxer.Raw(), pipe, stats) = DisconnectEOF, err
+func passThrough(ctx context.Context, client net.Conn, stream net.Conn, reader io.Reader, writer io.Writer, stats *StreamStats) (DisconnectReason, error) {
+	start := time.Now()
+	buf := make([]byte, 64*1024)
+	for {
+		if err := client.SetReadDeadline(time.Now().Add(READ_TIMEOUT)); err != nil {
+			if ctx.Err() != nil {
+				return DisconnectCancelled, nil
+			}
+			return DisconnectError, fmt.Errorf("error setting read deadline: %w", err)
+		}
+
+		n, err := reader.Read(buf)
+		if n > 0 {
+			stats.video(n)
+			if _, err := writer.Write(buf[:n]); err != nil {
+				return DisconnectWriter, fmt.Errorf("error writing to writer: %w", err)
+			}
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Println("Closing stream")
+				return DisconnectCancelled, nil
+			}
+			return classifyDisconnect(err)
+		}
+
+		// Send a keep-alive ping to the server
+		if time.Since(start) > time.Second {
+			if err := sendPing(stream); err != nil {
+				return DisconnectError, fmt.Errorf("error sending keep-alive: %w", err)
+			}
+			stats.keepaliveSent()
+
+			// Reset the timer
+			start = time.Now()
+		}
+	}
+}
+
 // sendPing sends a keep-alive ping to the server.
 //
 // client: the client connection to send the ping on
 //
 // Example: sendPing(client) = nil
-func sendPing(client *tls.Conn) (err error) {
+func sendPing(client net.Conn) (err error) {
 	if err := client.SetWriteDeadline(time.Now().Add(2 * time.Second)); err != nil {
 		return fmt.Errorf("error setting write deadline: %w", err)
 	}
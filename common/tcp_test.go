@@ -1,9 +1,165 @@
 package common_test
 
 import (
+	"blink-liveview-websocket/common"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/big"
+	"net"
 	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
 )
 
 func TestTCPStream(t *testing.T) {
 	t.Skip("Not implemented")
 }
+
+func TestTCPStreamHandshakeCancel(t *testing.T) {
+	// Accepts the TCP connection but never completes the TLS handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Equal(t, nil, err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(io.Discard, conn)
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	mockCtx, mockCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer mockCancel()
+
+	start := time.Now()
+	err = common.TCPStream(mockCtx, common.ConnectionDetails{Host: host, Port: port}, io.Discard)
+
+	assert.Equal(t, true, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, true, time.Since(start) < 5*time.Second)
+}
+
+// newTLSListener starts a TLS listener on localhost with a self-signed certificate
+func newTLSListener(t *testing.T) (net.Listener, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Equal(t, nil, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Equal(t, nil, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Equal(t, nil, err)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	})
+	assert.Equal(t, nil, err)
+	t.Cleanup(func() { listener.Close() })
+
+	return listener, cert
+}
+
+// trustedBy returns the TLS options trusting the certificate as a root
+func trustedBy(cert *x509.Certificate) *common.StreamTLS {
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return &common.StreamTLS{RootCAs: pool}
+}
+
+func TestTCPStreamDemuxesVideo(t *testing.T) {
+	listener, cert := newTLSListener(t)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		go io.Copy(io.Discard, conn)
+
+		conn.Write(bytes.Join([][]byte{
+			frame(common.FRAME_TYPE_VIDEO, 1, []byte("first")),
+			frame(common.FRAME_TYPE_KEEPALIVE, 2, make([]byte, 24)),
+			frame(common.FRAME_TYPE_VIDEO, 3, []byte("second")),
+		}, nil))
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	var video bytes.Buffer
+	var kinds []common.PacketKind
+	err := common.TCPStreamWithOptions(context.Background(), common.ConnectionDetails{Host: host, Port: port, ClientId: 1, ConnectionId: "abc"}, &video, common.StreamOptions{
+		TLS: trustedBy(cert),
+		OnPacket: func(packet *common.StreamPacket) {
+			kinds = append(kinds, packet.Kind)
+		},
+	})
+
+	assert.Equal(t, true, errors.Is(err, io.EOF))
+	assert.Equal(t, "firstsecond", video.String())
+	assert.Equal(t, []common.PacketKind{common.PacketKindVideo, common.PacketKindControl, common.PacketKindVideo}, kinds)
+}
+
+func TestTCPStreamPassThrough(t *testing.T) {
+	listener, cert := newTLSListener(t)
+	first, second := tsPackets(7), tsPackets(14)
+
+	keepalive := make(chan bool, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// A server that does not frame the stream, paused long enough for a keep-alive
+		conn.Write(first)
+		time.Sleep(1100 * time.Millisecond)
+		conn.Write(second)
+
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var received []byte
+		buf := make([]byte, 1024)
+		for !bytes.Contains(received, common.FRAMES_KEEPALIVE) {
+			n, err := conn.Read(buf)
+			if err != nil {
+				break
+			}
+			received = append(received, buf[:n]...)
+		}
+		keepalive <- bytes.Contains(received, common.FRAMES_KEEPALIVE)
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	var video bytes.Buffer
+	stats := common.NewStreamStats()
+	err := common.TCPStreamWithOptions(context.Background(), common.ConnectionDetails{Host: host, Port: port, ClientId: 1, ConnectionId: "abc"}, &video, common.StreamOptions{
+		TLS:   trustedBy(cert),
+		Stats: stats,
+	})
+
+	assert.Equal(t, true, errors.Is(err, io.EOF))
+	assert.Equal(t, true, <-keepalive)
+	assert.Equal(t, append(first, second...), video.Bytes())
+
+	snapshot := stats.Snapshot()
+	assert.Equal(t, int64(video.Len()), snapshot.VideoBytes)
+	assert.Equal(t, int64(0), snapshot.Packets)
+	assert.Equal(t, true, snapshot.KeepalivesSent > 0)
+	assert.Equal(t, common.DisconnectEOF, snapshot.DisconnectReason)
+}
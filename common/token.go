@@ -0,0 +1,215 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TOKEN_REFRESH_MARGIN is how long before expiry an access token is proactively refreshed
+var TOKEN_REFRESH_MARGIN = 5 * time.Minute
+
+type RefreshBody struct {
+	GrantType    string `json:"grant_type"`
+	RefreshToken string `json:"refresh_token"`
+	ClientId     string `json:"client_id"`
+	Scope        string `json:"scope"`
+}
+
+type TokenSource struct {
+	// The hardware ID (fingerprint) used when the tokens were issued
+	HardwareId string
+	// Called with the OAuth response after each successful refresh. Optional
+	OnRefresh func(*LoginResponse)
+
+	mu           sync.Mutex
+	accessToken  string
+	refreshToken string
+	expiresAt    time.Time
+}
+
+// NewTokenSource creates a TokenSource from an OAuth login response
+//
+// resp: the login (or refresh) response containing the tokens
+//
+// hardwareId: the fingerprint value used to log in
+//
+// Example: NewTokenSource(loginResp, fingerprint.Value)
+func NewTokenSource(resp *LoginResponse, hardwareId string) *TokenSource {
+	ts := &TokenSource{HardwareId: hardwareId}
+	ts.update(resp)
+
+	return ts
+}
+
+// NewTokenSourceWithExpiry creates a TokenSource from previously issued tokens
+//
+// accessToken: the current access token
+//
+// refreshToken: the refresh token to use once the access token expires
+//
+// expiresAt: when the access token expires. A zero value disables proactive refresh
+//
+// hardwareId: the fingerprint value used to log in
+//
+// Example: NewTokenSourceWithExpiry("access", "refresh", time.Now().Add(time.Hour), fingerprint.Value)
+func NewTokenSourceWithExpiry(accessToken string, refreshToken string, expiresAt time.Time, hardwareId string) *TokenSource {
+	return &TokenSource{
+		HardwareId:   hardwareId,
+		accessToken:  accessToken,
+		refreshToken: refreshToken,
+		expiresAt:    expiresAt,
+	}
+}
+
+// Token returns a valid access token, refreshing it first if it is about to expire
+//
+// ctx: the context of the refresh request
+//
+// c: the client used to reach the OAuth endpoint
+//
+// Example: Token(ctx, client) = "access-token", nil
+func (ts *TokenSource) Token(ctx context.Context, c *BlinkClient) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.refreshToken != "" && !ts.expiresAt.IsZero() && time.Until(ts.expiresAt) < TOKEN_REFRESH_MARGIN {
+		if err := ts.refresh(ctx, c); err != nil {
+			return "", err
+		}
+	}
+
+	return ts.accessToken, nil
+}
+
+// Refresh forces a refresh of the access token, regardless of the expiry
+//
+// ctx: the context of the refresh request
+//
+// c: the client used to reach the OAuth endpoint
+//
+// Example: Refresh(ctx, client) = nil
+func (ts *TokenSource) Refresh(ctx context.Context, c *BlinkClient) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return ts.refresh(ctx, c)
+}
+
+// RefreshIfCurrent refreshes the access token only if it is still the rejected token.
+// Concurrent requests rejected with the same token trigger a single refresh, and the others reuse its result.
+//
+// ctx: the context of the refresh request
+//
+// c: the client used to reach the OAuth endpoint
+//
+// rejected: the access token the failed request was sent with
+//
+// Example: RefreshIfCurrent(ctx, client, "expired-access-token") = nil
+func (ts *TokenSource) RefreshIfCurrent(ctx context.Context, c *BlinkClient, rejected string) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.accessToken != rejected {
+		return nil
+	}
+
+	return ts.refresh(ctx, c)
+}
+
+// ExpiresAt returns the time the current access token expires
+//
+// Example: ExpiresAt() = time.Time{...}
+func (ts *TokenSource) ExpiresAt() time.Time {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return ts.expiresAt
+}
+
+// refresh performs the refresh_token grant. The caller must hold the lock
+func (ts *TokenSource) refresh(ctx context.Context, c *BlinkClient) error {
+	if ts.refreshToken == "" {
+		return fmt.Errorf("cannot refresh access token without a refresh token")
+	}
+
+	resp, err := c.RefreshToken(ctx, ts.refreshToken, ts.HardwareId)
+	if err != nil {
+		return fmt.Errorf("error refreshing access token: %w", err)
+	}
+
+	ts.update(resp)
+	if ts.OnRefresh != nil {
+		ts.OnRefresh(resp)
+	}
+
+	return nil
+}
+
+// update stores the tokens from the OAuth response. The caller must hold the lock
+func (ts *TokenSource) update(resp *LoginResponse) {
+	ts.accessToken = resp.AccessToken
+	if resp.RefreshToken != "" {
+		ts.refreshToken = resp.RefreshToken
+	}
+
+	ts.expiresAt = time.Time{}
+	if resp.ExpiresIn > 0 {
+		ts.expiresAt = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+}
+
+// RefreshToken exchanges a refresh token for a new access token
+//
+// ctx: the context of the request
+//
+// refreshToken: the refresh token returned by a previous login
+//
+// hardwareId: the fingerprint value used to log in
+//
+// Example: client.RefreshToken(ctx, "refresh-token", fingerprint.Value)
+func (c *BlinkClient) RefreshToken(ctx context.Context, refreshToken string, hardwareId string) (*LoginResponse, error) {
+	jsonBody, _ := json.Marshal(&RefreshBody{
+		GrantType:    "refresh_token",
+		RefreshToken: refreshToken,
+		ClientId:     "android",
+		Scope:        "client",
+	})
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.OAuthBaseUrl()+"/oauth/token", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("content-type", "application/json; charset=UTF-8")
+	if hardwareId != "" {
+		req.Header.Set("hardware_id", hardwareId)
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	var result LoginResponse
+	if err := decodeBody(resp, &result); err != nil {
+		return nil, err
+	}
+	if result.AccessToken == "" {
+		return nil, fmt.Errorf("refresh response did not include an access token")
+	}
+
+	return &result, nil
+}
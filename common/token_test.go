@@ -0,0 +1,216 @@
+package common_test
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestRefreshTokenNominal(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body common.RefreshBody
+		json.NewDecoder(r.Body).Decode(&body)
+
+		assert.Equal(t, "/oauth/token", r.URL.Path)
+		assert.Equal(t, "refresh_token", body.GrantType)
+		assert.Equal(t, "r1", body.RefreshToken)
+		assert.Equal(t, "mock-fingerprint", r.Header.Get("hardware_id"))
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"access_token":"a2","expires_in":3600,"refresh_token":"r2"}`))
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("", "", 0)
+	client.OAuthUrl = mockServer.URL
+
+	resp, err := client.RefreshToken(context.Background(), "r1", "mock-fingerprint")
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "a2", resp.AccessToken)
+	assert.Equal(t, "r2", resp.RefreshToken)
+}
+
+func TestRefreshTokenHttpError(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("", "", 0)
+	client.OAuthUrl = mockServer.URL
+
+	resp, err := client.RefreshToken(context.Background(), "r1", "mock-fingerprint")
+
+	assert.Equal(t, (*common.LoginResponse)(nil), resp)
+	assert.Equal(t, "HTTP Status Code 401", err.Error())
+}
+
+func TestTokenSourceValidToken(t *testing.T) {
+	ts := common.NewTokenSource(&common.LoginResponse{AccessToken: "a1", RefreshToken: "r1", ExpiresIn: 3600}, "")
+
+	token, err := ts.Token(context.Background(), common.NewBlinkClient("", "", 0))
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "a1", token)
+}
+
+func TestTokenSourceRefreshBeforeExpiry(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"access_token":"a2","expires_in":3600}`))
+	}))
+	defer mockServer.Close()
+
+	client := common.NewBlinkClient("", "", 0)
+	client.OAuthUrl = mockServer.URL
+
+	var refreshed *common.LoginResponse
+	ts := common.NewTokenSourceWithExpiry("a1", "r1", time.Now().Add(time.Minute), "")
+	ts.OnRefresh = func(resp *common.LoginResponse) { refreshed = resp }
+
+	token, err := ts.Token(context.Background(), client)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "a2", token)
+	assert.Equal(t, "a2", refreshed.AccessToken)
+	assert.Equal(t, true, time.Until(ts.ExpiresAt()) > 50*time.Minute)
+}
+
+func TestTokenSourceRefreshWithoutRefreshToken(t *testing.T) {
+	ts := common.NewTokenSourceWithExpiry("a1", "", time.Now(), "")
+
+	err := ts.Refresh(context.Background(), common.NewBlinkClient("", "", 0))
+
+	assert.Equal(t, "cannot refresh access token without a refresh token", err.Error())
+}
+
+func TestBlinkClientRefreshOnUnauthorized(t *testing.T) {
+	var mu sync.Mutex
+	var authHeaders []string
+
+	oauthServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"access_token":"a2","expires_in":3600}`))
+	}))
+	defer oauthServer.Close()
+
+	restServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		authHeaders = append(authHeaders, r.Header.Get("Authorization"))
+		mu.Unlock()
+
+		if r.Header.Get("Authorization") != "Bearer a2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"tier": "u011", "account_id": 1234}`))
+	}))
+	defer restServer.Close()
+
+	client := common.NewBlinkClient("", "", 0)
+	client.RestUrl = restServer.URL
+	client.OAuthUrl = oauthServer.URL
+	client.Tokens = common.NewTokenSource(&common.LoginResponse{AccessToken: "a1", RefreshToken: "r1", ExpiresIn: 3600}, "")
+
+	resp, err := client.GetTierInfo(context.Background())
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1234, resp.AccountId)
+	assert.Equal(t, []string{"Bearer a1", "Bearer a2"}, authHeaders)
+}
+
+func TestBlinkClientConcurrentUnauthorizedRefreshOnce(t *testing.T) {
+	var refreshes atomic.Int32
+	oauthServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		refreshes.Add(1)
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"access_token":"a2","refresh_token":"r2","expires_in":3600}`))
+	}))
+	defer oauthServer.Close()
+
+	// Hold every request until all of them were sent with the expired token
+	const requests = 8
+	var rejected sync.WaitGroup
+	rejected.Add(requests)
+	restServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer a2" {
+			rejected.Done()
+			rejected.Wait()
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"tier": "u011", "account_id": 1234}`))
+	}))
+	defer restServer.Close()
+
+	client := common.NewBlinkClient("", "", 0)
+	client.RestUrl = restServer.URL
+	client.OAuthUrl = oauthServer.URL
+	client.Tokens = common.NewTokenSource(&common.LoginResponse{AccessToken: "a1", RefreshToken: "r1", ExpiresIn: 3600}, "")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, requests)
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.GetTierInfo(context.Background())
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.Equal(t, nil, err)
+	}
+	assert.Equal(t, int32(1), refreshes.Load())
+}
+
+func TestBlinkClientRefreshRetriesBody(t *testing.T) {
+	oauthServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"access_token":"a2","expires_in":3600}`))
+	}))
+	defer oauthServer.Close()
+
+	var bodies []string
+	restServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var input common.LiveviewInput
+		json.NewDecoder(r.Body).Decode(&input)
+		bodies = append(bodies, input.Intent)
+
+		if r.Header.Get("Authorization") != "Bearer a2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"command_id": 1, "polling_interval": 15, "server": "immis://93.93.93.93:443"}`))
+	}))
+	defer restServer.Close()
+
+	client := common.NewBlinkClient("", "", 0)
+	client.OAuthUrl = oauthServer.URL
+	client.Tokens = common.NewTokenSourceWithExpiry("a1", "r1", time.Time{}, "")
+
+	resp, err := client.BeginLiveview(context.Background(), restServer.URL)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, resp.CommandId)
+	assert.Equal(t, []string{"liveview", "liveview"}, bodies)
+}
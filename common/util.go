@@ -1,13 +1,22 @@
 package common
 
 import (
-	"encoding/binary"
+	"errors"
 	"fmt"
 	"net/url"
 	"strconv"
 	"strings"
 )
 
+// LIVEVIEW_PORT is the only port accepted in liveview connection strings
+var LIVEVIEW_PORT = "443"
+
+// ErrUnknownDeviceType is returned when no API path exists for the device type
+var ErrUnknownDeviceType = errors.New("cannot build path for unknown device type")
+
+// ErrInvalidConnectionString is returned when the liveview connection string cannot be parsed
+var ErrInvalidConnectionString = errors.New("connection string parsing error")
+
 // GetApiUrl builds the Blink API URL based on the region if provided
 // region: region to build the URL for
 //
@@ -37,7 +46,81 @@ func GetLiveviewPath(deviceType string) (string, error) {
 		return "%s/api/v2/accounts/%d/networks/%d/doorbells/%d/liveview", nil
 	}
 
-	return "", fmt.Errorf("cannot build path for unknown device type: %s", deviceType)
+	return "", fmt.Errorf("%w: %s", ErrUnknownDeviceType, deviceType)
+}
+
+// GetMotionPath returns the path used to enable or disable motion detection based on the device type
+//
+// deviceType: the type of device to get the motion detection path for
+//
+// enabled: true to enable motion detection, false to disable it
+//
+// Example: GetMotionPath("camera", true) = "%s/network/%[3]d/camera/%[4]d/enable"
+func GetMotionPath(deviceType string, enabled bool) (string, error) {
+	action := "disable"
+	if enabled {
+		action = "enable"
+	}
+
+	switch deviceType {
+	case "camera":
+		return "%s/network/%[3]d/camera/%[4]d/" + action, nil
+	case "owl", "hawk":
+		return "%s/api/v1/accounts/%d/networks/%d/owls/%d/config", nil
+	case "doorbell", "lotus":
+		return "%s/api/v1/accounts/%d/networks/%d/doorbells/%d/" + action, nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnknownDeviceType, deviceType)
+}
+
+// GetThumbnailPath returns the path used to request a new thumbnail based on the device type
+//
+// deviceType: the type of device to get the thumbnail path for
+//
+// Example: GetThumbnailPath("owl") = "%s/api/v1/accounts/%d/networks/%d/owls/%d/thumbnail"
+func GetThumbnailPath(deviceType string) (string, error) {
+	switch deviceType {
+	case "camera":
+		return "%s/network/%[3]d/camera/%[4]d/thumbnail", nil
+	case "owl", "hawk":
+		return "%s/api/v1/accounts/%d/networks/%d/owls/%d/thumbnail", nil
+	case "doorbell", "lotus":
+		return "%s/api/v1/accounts/%d/networks/%d/doorbells/%d/thumbnail", nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnknownDeviceType, deviceType)
+}
+
+// GetConfigPath returns the path used to read the device configuration based on the device type
+//
+// deviceType: the type of device to get the config path for
+//
+// Example: GetConfigPath("camera") = "%s/network/%[3]d/camera/%[4]d/config"
+func GetConfigPath(deviceType string) (string, error) {
+	switch deviceType {
+	case "camera":
+		return "%s/network/%[3]d/camera/%[4]d/config", nil
+	case "owl", "hawk":
+		return "%s/api/v1/accounts/%d/networks/%d/owls/%d/config", nil
+	case "doorbell", "lotus":
+		return "%s/api/v1/accounts/%d/networks/%d/doorbells/%d/config", nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnknownDeviceType, deviceType)
+}
+
+// GetConfigUpdatePath returns the path used to update the device configuration based on the device type
+//
+// deviceType: the type of device to get the config update path for
+//
+// Example: GetConfigUpdatePath("camera") = "%s/network/%[3]d/camera/%[4]d/update"
+func GetConfigUpdatePath(deviceType string) (string, error) {
+	if deviceType == "camera" {
+		return "%s/network/%[3]d/camera/%[4]d/update", nil
+	}
+
+	return GetConfigPath(deviceType)
 }
 
 // ParseConnectionString parses the connection string to extract the connection details
@@ -55,8 +138,8 @@ func ParseConnectionString(server string) (*ConnectionDetails, error) {
 		return nil, fmt.Errorf("invalid host")
 	}
 
-	if parsedUrl.Port() != "443" {
-		return nil, fmt.Errorf("unexpected port %s. Expecting 443", parsedUrl.Port())
+	if parsedUrl.Port() != LIVEVIEW_PORT {
+		return nil, fmt.Errorf("unexpected port %s. Expecting %s", parsedUrl.Port(), LIVEVIEW_PORT)
 	}
 
 	pathSegments := strings.Split(parsedUrl.Path, "/")
@@ -90,55 +173,13 @@ func ParseConnectionString(server string) (*ConnectionDetails, error) {
 //
 // Example: GetTCPAuthFrames("connection-id", 123)
 func GetTCPAuthFrames(connectionId string, clientId int) [][]byte {
-	// Frame 1 (unknown)
-	frame1 := []byte{
-		0x00, 0x00, 0x00, 0x28, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-	}
-
-	// Frame 2 (Client ID)
-	clientIDBytes := make([]byte, 4)
-	binary.BigEndian.PutUint32(clientIDBytes, uint32(clientId))
-	frame2 := []byte{
-		clientIDBytes[0], clientIDBytes[1], clientIDBytes[2], clientIDBytes[3],
-	}
-
-	// Frame 3 (unknown)
-	frame3 := []byte{
-		0x01, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x10,
-	}
-
-	// Frame 4 (Connection ID)
-	frame4 := []byte(connectionId)
-
-	// Frame 5 (unknown)
-	frame5 := []byte{
-		0x00, 0x00, 0x00, 0x01, 0x0a, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00,
-	}
-
-	return [][]byte{
-		frame1,
-		frame2,
-		frame3,
-		frame4,
-		frame5,
-	}
+	return NewHandshake(connectionId, clientId).Frames()
 }
 
 type NetworkGroup struct {
-	Name    string
-	Devices []BaseCameraDevice
+	Name        string
+	Devices     []BaseCameraDevice
+	SyncModules []SyncModule
 }
 
 type DeviceOption struct {
@@ -150,6 +191,7 @@ type DeviceOption struct {
 }
 
 // PrintDeviceOptions prints the device options and returns a list of DeviceOption structs
+// Sync modules are listed for each network, but cannot be selected
 //
 // resp: the HomescreenResponse containing the device information
 //
@@ -158,7 +200,7 @@ func PrintDeviceOptions(resp *HomescreenResponse) (string, []DeviceOption) {
 	if len(resp.Networks) == 0 {
 		return "", nil
 	}
-	if len(resp.Owls) == 0 && len(resp.Doorbells) == 0 {
+	if len(resp.Owls) == 0 && len(resp.Doorbells) == 0 && len(resp.Cameras) == 0 {
 		return "", nil
 	}
 
@@ -175,10 +217,25 @@ func PrintDeviceOptions(resp *HomescreenResponse) (string, []DeviceOption) {
 				devices = append(devices, device)
 			}
 		}
+		for _, device := range resp.Cameras {
+			if device.NetworkId == network.Id {
+				// Classic cameras report their model as the type
+				device.Type = "camera"
+				devices = append(devices, device)
+			}
+		}
+
+		var syncModules []SyncModule
+		for _, syncModule := range resp.SyncModules {
+			if syncModule.NetworkId == network.Id {
+				syncModules = append(syncModules, syncModule)
+			}
+		}
 
 		networkGroups = append(networkGroups, NetworkGroup{
-			Name:    network.Name,
-			Devices: devices,
+			Name:        network.Name,
+			Devices:     devices,
+			SyncModules: syncModules,
 		})
 	}
 
@@ -187,6 +244,9 @@ func PrintDeviceOptions(resp *HomescreenResponse) (string, []DeviceOption) {
 	var idx int = 1
 	for _, group := range networkGroups {
 		sb.WriteString(fmt.Sprintf("Network: %s\n", group.Name))
+		for _, syncModule := range group.SyncModules {
+			sb.WriteString(fmt.Sprintf("  Sync Module: %s (%s)\n", syncModule.Name, syncModule.Status))
+		}
 		for _, device := range group.Devices {
 			formattedName := fmt.Sprintf("%s (%s)", device.Name, device.Type)
 			sb.WriteString(fmt.Sprintf("  [%02d] %s\n", idx, formattedName))
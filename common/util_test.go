@@ -479,3 +479,160 @@ func TestPrintDeviceOptionsMissingNetwork(t *testing.T) {
 		},
 	})
 }
+
+func TestPrintDeviceOptionsCameras(t *testing.T) {
+	resp := common.HomescreenResponse{
+		Networks: []common.BaseNetwork{
+			{
+				Id:   1,
+				Name: "Network 1",
+			},
+		},
+		SyncModules: []common.SyncModule{
+			{
+				Id:        10,
+				Name:      "Sync Module 1",
+				NetworkId: 1,
+				Status:    "online",
+			},
+		},
+		Cameras: []common.BaseCameraDevice{
+			{
+				Id:        3,
+				Name:      "Backyard",
+				Type:      "catalina",
+				NetworkId: 1,
+			},
+		},
+		Owls: []common.BaseCameraDevice{
+			{
+				Id:        1,
+				Name:      "Owl 1",
+				Type:      "owl",
+				NetworkId: 1,
+			},
+		},
+	}
+
+	output, options := common.PrintDeviceOptions(&resp)
+
+	assert.Equal(t, output, "Network: Network 1\n  Sync Module: Sync Module 1 (online)\n  [01] Owl 1 (owl)\n  [02] Backyard (camera)\n")
+	assert.Equal(t, options, []common.DeviceOption{
+		{
+			Option:        1,
+			FormattedName: "Owl 1 (owl)",
+			NetworkId:     1,
+			DeviceId:      1,
+			DeviceType:    "owl",
+		},
+		{
+			Option:        2,
+			FormattedName: "Backyard (camera)",
+			NetworkId:     1,
+			DeviceId:      3,
+			DeviceType:    "camera",
+		},
+	})
+}
+
+func TestPrintDeviceOptionsOnlySyncModules(t *testing.T) {
+	resp := common.HomescreenResponse{
+		Networks: []common.BaseNetwork{
+			{
+				Id:   1,
+				Name: "Network 1",
+			},
+		},
+		SyncModules: []common.SyncModule{
+			{
+				Id:        10,
+				Name:      "Sync Module 1",
+				NetworkId: 1,
+			},
+		},
+	}
+
+	output, options := common.PrintDeviceOptions(&resp)
+
+	assert.Equal(t, output, "")
+	assert.Equal(t, options, nil)
+}
+
+func TestGetMotionPathCamera(t *testing.T) {
+	path, err := common.GetMotionPath("camera", true)
+
+	assert.Equal(t, "%s/network/%[3]d/camera/%[4]d/enable", path)
+	assert.Equal(t, "https://example.com/network/2/camera/3/enable", fmt.Sprintf(path, "https://example.com", 1, 2, 3))
+	assert.Equal(t, err, nil)
+}
+
+func TestGetMotionPathOwl(t *testing.T) {
+	path, err := common.GetMotionPath("owl", false)
+
+	assert.Equal(t, "%s/api/v1/accounts/%d/networks/%d/owls/%d/config", path)
+	assert.Equal(t, err, nil)
+}
+
+func TestGetMotionPathDoorbell(t *testing.T) {
+	path, err := common.GetMotionPath("lotus", false)
+
+	assert.Equal(t, "%s/api/v1/accounts/%d/networks/%d/doorbells/%d/disable", path)
+	assert.Equal(t, err, nil)
+}
+
+func TestGetMotionPathUnknown(t *testing.T) {
+	path, err := common.GetMotionPath("unknown", true)
+
+	assert.Equal(t, "", path)
+	assert.NotEqual(t, err, nil)
+}
+
+func TestGetThumbnailPath(t *testing.T) {
+	cameraPath, err := common.GetThumbnailPath("camera")
+	assert.Equal(t, "%s/network/%[3]d/camera/%[4]d/thumbnail", cameraPath)
+	assert.Equal(t, err, nil)
+
+	owlPath, err := common.GetThumbnailPath("hawk")
+	assert.Equal(t, "%s/api/v1/accounts/%d/networks/%d/owls/%d/thumbnail", owlPath)
+	assert.Equal(t, err, nil)
+
+	doorbellPath, err := common.GetThumbnailPath("doorbell")
+	assert.Equal(t, "%s/api/v1/accounts/%d/networks/%d/doorbells/%d/thumbnail", doorbellPath)
+	assert.Equal(t, err, nil)
+
+	unknownPath, err := common.GetThumbnailPath("unknown")
+	assert.Equal(t, "", unknownPath)
+	assert.NotEqual(t, err, nil)
+}
+
+func TestGetConfigPath(t *testing.T) {
+	cameraPath, err := common.GetConfigPath("camera")
+	assert.Equal(t, "%s/network/%[3]d/camera/%[4]d/config", cameraPath)
+	assert.Equal(t, err, nil)
+
+	owlPath, err := common.GetConfigPath("owl")
+	assert.Equal(t, "%s/api/v1/accounts/%d/networks/%d/owls/%d/config", owlPath)
+	assert.Equal(t, err, nil)
+
+	doorbellPath, err := common.GetConfigPath("lotus")
+	assert.Equal(t, "%s/api/v1/accounts/%d/networks/%d/doorbells/%d/config", doorbellPath)
+	assert.Equal(t, err, nil)
+
+	unknownPath, err := common.GetConfigPath("unknown")
+	assert.Equal(t, "", unknownPath)
+	assert.NotEqual(t, err, nil)
+}
+
+func TestGetConfigUpdatePath(t *testing.T) {
+	cameraPath, err := common.GetConfigUpdatePath("camera")
+	assert.Equal(t, "%s/network/%[3]d/camera/%[4]d/update", cameraPath)
+	assert.Equal(t, err, nil)
+
+	owlPath, err := common.GetConfigUpdatePath("hawk")
+	assert.Equal(t, "%s/api/v1/accounts/%d/networks/%d/owls/%d/config", owlPath)
+	assert.Equal(t, err, nil)
+
+	unknownPath, err := common.GetConfigUpdatePath("unknown")
+	assert.Equal(t, "", unknownPath)
+	assert.NotEqual(t, err, nil)
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"os/signal"
+)
+
+// Prints the current configuration of a single device as JSON
+//
+// client: the Blink API client holding the token, account ID and region
+//
+// deviceType: the Blink device type (e.g. camera, owl, doorbell)
+//
+// networkId: the network ID that the device is on
+//
+// deviceId: the ID of the device to read
+func Get(client *common.BlinkClient, deviceType string, networkId int, deviceId int) {
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancelCtx()
+
+	cfg, err := client.GetDeviceConfig(ctx, deviceType, networkId, deviceId)
+	if common.IsUnauthorized(err) {
+		log.Println("the API token is invalid or expired", err)
+		os.Exit(1)
+	} else if err != nil {
+		log.Println("error reading device config", err)
+		os.Exit(1)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(cfg); err != nil {
+		log.Println("error writing device config", err)
+		os.Exit(1)
+	}
+}
+
+// Applies a JSON configuration to a single device and waits for the command to complete.
+// Settings missing from the JSON are left unchanged.
+//
+// client: the Blink API client holding the token, account ID and region
+//
+// deviceType: the Blink device type (e.g. camera, owl, doorbell)
+//
+// networkId: the network ID that the device is on
+//
+// deviceId: the ID of the device to update
+//
+// data: the JSON configuration to apply
+func Set(client *common.BlinkClient, deviceType string, networkId int, deviceId int, data []byte) {
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancelCtx()
+
+	cfg, err := common.ParseDeviceConfig(deviceType, data)
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+
+	_, err = client.UpdateDeviceConfig(ctx, deviceType, networkId, deviceId, cfg)
+	if common.IsUnauthorized(err) {
+		log.Println("the API token is invalid or expired", err)
+		os.Exit(1)
+	} else if err != nil {
+		log.Println("error updating device config", err)
+		os.Exit(1)
+	}
+
+	log.Printf("Updated config for %s %d\n", deviceType, deviceId)
+}
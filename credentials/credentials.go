@@ -0,0 +1,83 @@
+package credentials
+
+import (
+	"blink-liveview-websocket/common"
+	"errors"
+	"log"
+	"os"
+)
+
+// Re-encrypts the saved profiles and the fingerprint file with a new passphrase
+//
+// current: the store the credentials are currently encrypted with. Nil for plaintext
+//
+// next: the store to re-encrypt the credentials with. Nil to store them in plaintext
+func ChangePassphrase(current *common.CredentialStore, next *common.CredentialStore) {
+	names, err := common.RekeyProfiles("", current, next)
+	if err != nil {
+		log.Println("error changing passphrase", err)
+		os.Exit(1)
+	}
+
+	if _, err := os.Stat(common.FINGERPRINT_FILE); err == nil {
+		if err := current.Rekey(common.FINGERPRINT_FILE, next); err != nil {
+			log.Println("error changing passphrase of the fingerprint file", err)
+			os.Exit(1)
+		}
+	}
+
+	if next == nil {
+		log.Printf("Decrypted %d profiles. Credentials are now stored in plaintext\n", len(names))
+		return
+	}
+
+	log.Printf("Re-encrypted %d profiles. Update %s to the new passphrase\n", len(names), common.CREDENTIAL_PASSPHRASE_ENV)
+}
+
+// Exports saved profiles into a single encrypted file
+//
+// names: the profiles to export. Every profile is exported when empty
+//
+// output: the file to write the bundle to
+//
+// exportStore: the store to encrypt the bundle with
+func Export(names []string, output string, exportStore *common.CredentialStore) {
+	data, err := common.ExportProfiles("", names, exportStore)
+	if err != nil {
+		log.Println("error exporting profiles", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(output, data, 0600); err != nil {
+		log.Println("error writing export file", err)
+		os.Exit(1)
+	}
+
+	log.Printf("Exported profiles to %s\n", output)
+}
+
+// Imports the profiles from an exported file
+//
+// input: the file to read the bundle from
+//
+// exportStore: the store the bundle was encrypted with
+//
+// overwrite: replace existing profiles with the same name
+func Import(input string, exportStore *common.CredentialStore, overwrite bool) {
+	data, err := os.ReadFile(input)
+	if err != nil {
+		log.Println("error reading export file", err)
+		os.Exit(1)
+	}
+
+	imported, err := common.ImportProfiles("", data, exportStore, overwrite)
+	if errors.Is(err, common.ErrWrongPassphrase) {
+		log.Println("the export passphrase is incorrect", err)
+		os.Exit(1)
+	} else if err != nil {
+		log.Println("error importing profiles", err)
+		os.Exit(1)
+	}
+
+	log.Printf("Imported profiles %v\n", imported)
+}
@@ -0,0 +1,188 @@
+package emulate
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"time"
+)
+
+// FILE_FRAME_INTERVAL is the default pause between two video frames for sources that are not real-time (about 1 Mbps)
+var FILE_FRAME_INTERVAL = 10 * time.Millisecond
+
+// Runs a local Blink backend: the OAuth and REST API on one address, and the liveview stream server on another
+//
+// address: the HTTP address of the OAuth and REST API (e.g. 127.0.0.1:8082)
+//
+// streamAddress: the TLS address of the liveview stream server (e.g. 127.0.0.1:8443)
+//
+// source: "testsrc" for an ffmpeg test pattern, "blank" for a stream without media, or the path of an MPEG-TS file to loop
+//
+// opts: the emulator options. StreamAddress and Source are set from the other parameters, and FrameInterval defaults to FILE_FRAME_INTERVAL for files
+func Run(address string, streamAddress string, source string, opts EmulatorOptions) {
+	host, port, err := net.SplitHostPort(streamAddress)
+	if err != nil {
+		log.Println("invalid stream address", err)
+		os.Exit(1)
+	}
+
+	certificate, err := NewEmulatorCertificate(host)
+	if err != nil {
+		log.Println("error generating the stream certificate", err)
+		os.Exit(1)
+	}
+
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		log.Println("error parsing the stream certificate", err)
+		os.Exit(1)
+	}
+
+	listener, err := tls.Listen("tcp", streamAddress, &tls.Config{Certificates: []tls.Certificate{certificate}})
+	if err != nil {
+		log.Println("error starting the stream server", err)
+		os.Exit(1)
+	}
+
+	opts.StreamAddress = streamAddress
+	opts.Source = sourceFunc(source)
+	if opts.FrameInterval == 0 && source != "testsrc" {
+		opts.FrameInterval = FILE_FRAME_INTERVAL
+	}
+	emulator := NewEmulator(opts)
+	server := &http.Server{Addr: address, Handler: emulator.Handler()}
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancelCtx()
+
+	go func() {
+		if err := emulator.ServeStream(ctx, listener); err != nil {
+			log.Println("stream server error", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		log.Println("Received SIGINT. Shutting down...")
+
+		shutdownCtx, cancelShutdownCtx := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelShutdownCtx()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Fatalf("HTTP shutdown error: %v", err)
+		}
+	}()
+
+	log.Printf("Emulating the Blink API on http://%s and the liveview server on %s\n", address, streamAddress)
+	log.Printf("Connect with: --rest-url http://%[1]s --oauth-url http://%[1]s --liveview-port %[2]s --insecure --pin %[3]s\n", address, port, common.SPKIPin(leaf))
+
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("HTTP server error: %v", err)
+	}
+}
+
+// sourceFunc returns the function opening the MPEG-TS for each liveview connection
+//
+// source: "testsrc", "blank" or the path of an MPEG-TS file
+func sourceFunc(source string) func(ctx context.Context) (io.ReadCloser, error) {
+	switch source {
+	case "testsrc":
+		return testPattern
+	case "blank":
+		return func(ctx context.Context) (io.ReadCloser, error) {
+			return NewGeneratedStream(), nil
+		}
+	}
+
+	return func(ctx context.Context) (io.ReadCloser, error) {
+		return openLoop(source)
+	}
+}
+
+// testPattern starts ffmpeg to generate a real-time test pattern with a tone
+//
+// ctx: kills ffmpeg when cancelled
+func testPattern(ctx context.Context) (io.ReadCloser, error) {
+	ffmpegCmd := exec.CommandContext(ctx, "ffmpeg",
+		"-loglevel", "error",
+		"-re",
+		"-f", "lavfi", "-i", "testsrc=size=1280x720:rate=15",
+		"-f", "lavfi", "-i", "sine=frequency=440",
+		"-c:v", "libx264", "-preset", "ultrafast", "-tune", "zerolatency",
+		"-c:a", "aac",
+		"-f", "mpegts", "pipe:1",
+	)
+
+	outputPipe, err := ffmpegCmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("error creating ffmpeg stdout pipe: %w", err)
+	}
+	if err := ffmpegCmd.Start(); err != nil {
+		return nil, fmt.Errorf("error starting ffmpeg: %w", err)
+	}
+
+	return &commandReader{ReadCloser: outputPipe, cmd: ffmpegCmd}, nil
+}
+
+// commandReader reads the output of a command and stops the command when closed
+type commandReader struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+// Close stops the command and waits for it to exit
+func (r *commandReader) Close() error {
+	r.cmd.Process.Kill()
+	r.ReadCloser.Close()
+	r.cmd.Wait()
+
+	return nil
+}
+
+// loopReader reads a file from the start again whenever it ends
+type loopReader struct {
+	file *os.File
+}
+
+// openLoop opens the file to read in a loop
+//
+// path: the path of the file
+func openLoop(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening stream file: %w", err)
+	}
+
+	return &loopReader{file: file}, nil
+}
+
+// Read reads from the file, rewinding it at the end
+func (r *loopReader) Read(p []byte) (int, error) {
+	n, err := r.file.Read(p)
+	if errors.Is(err, io.EOF) {
+		if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+			return n, err
+		}
+		if n == 0 {
+			return r.file.Read(p)
+		}
+		return n, nil
+	}
+
+	return n, err
+}
+
+// Close closes the file
+func (r *loopReader) Close() error {
+	return r.file.Close()
+}
@@ -0,0 +1,577 @@
+package emulate
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EMULATOR_CHUNK_SIZE is the video payload size of each frame sent by the emulator (7 MPEG-TS packets)
+const EMULATOR_CHUNK_SIZE = 7 * TS_PACKET_SIZE
+
+// EMULATOR_POLLING_INTERVAL is the command polling interval in seconds returned by the emulator
+var EMULATOR_POLLING_INTERVAL = 1
+
+// EMULATOR_TOKEN_LIFETIME is the lifetime of the access tokens issued by the emulator
+var EMULATOR_TOKEN_LIFETIME = time.Hour
+
+// EMULATOR_KEEPALIVE_TIMEOUT is how long the emulator waits for a client keep-alive before closing the stream
+var EMULATOR_KEEPALIVE_TIMEOUT = 10 * time.Second
+
+// The devices reported by the emulator homescreen
+const (
+	EMULATOR_NETWORK_ID     = 100
+	EMULATOR_SYNC_MODULE_ID = 200
+	EMULATOR_CAMERA_ID      = 300
+	EMULATOR_OWL_ID         = 301
+	EMULATOR_DOORBELL_ID    = 302
+)
+
+type EmulatorOptions struct {
+	// The region (tier) reported for the account. Defaults to "emu"
+	Region string
+	// The account ID reported for the account. Defaults to 1
+	AccountId int
+	// The email address accepted by the OAuth endpoint. Empty accepts any credentials
+	Email string
+	// The password accepted by the OAuth endpoint. Only checked when Email is set
+	Password string
+	// The verification code required after the password. Empty skips two-step verification
+	VerificationCode string
+	// The host:port of the liveview stream server advertised in liveview responses (e.g. "127.0.0.1:8443")
+	StreamAddress string
+	// How long a liveview command stays active before it is marked as complete. 0 keeps it active until stopped
+	SessionDuration time.Duration
+	// Opens the MPEG-TS streamed to each liveview connection. Defaults to NewGeneratedStream
+	Source func(ctx context.Context) (io.ReadCloser, error)
+	// The pause between two video frames, to pace sources that are not real-time (e.g. files). Optional
+	FrameInterval time.Duration
+}
+
+// Emulator is a local stand-in for the Blink OAuth, REST and liveview servers for offline development
+type Emulator struct {
+	opts EmulatorOptions
+
+	mu            sync.Mutex
+	accessTokens  map[string]bool
+	refreshTokens map[string]bool
+	sessions      map[int]*emulatorSession
+	nextCommandId int
+}
+
+type emulatorSession struct {
+	networkId    int
+	cameraId     int
+	connectionId string
+	clientId     uint32
+	started      time.Time
+	stopped      chan struct{}
+	stopOnce     sync.Once
+}
+
+// NewEmulator creates an emulator, applying the defaults to any unset options
+//
+// opts: the emulator options
+//
+// Example: NewEmulator(EmulatorOptions{StreamAddress: "127.0.0.1:8443"})
+func NewEmulator(opts EmulatorOptions) *Emulator {
+	if opts.Region == "" {
+		opts.Region = "emu"
+	}
+	if opts.AccountId == 0 {
+		opts.AccountId = 1
+	}
+	if opts.Source == nil {
+		opts.Source = func(ctx context.Context) (io.ReadCloser, error) {
+			return NewGeneratedStream(), nil
+		}
+	}
+
+	return &Emulator{
+		opts:          opts,
+		accessTokens:  map[string]bool{},
+		refreshTokens: map[string]bool{},
+		sessions:      map[int]*emulatorSession{},
+		nextCommandId: 1000,
+	}
+}
+
+// Handler returns the HTTP handler serving the OAuth, tier_info, homescreen, liveview and command endpoints
+//
+// Example: http.ListenAndServe("127.0.0.1:8082", emulator.Handler())
+func (e *Emulator) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /oauth/token", e.handleToken)
+	mux.HandleFunc("GET /api/v1/users/tier_info", e.authorized(e.handleTierInfo))
+	mux.HandleFunc("GET /api/v4/accounts/{account}/homescreen", e.authorized(e.handleHomescreen))
+	mux.HandleFunc("POST /api/v5/accounts/{account}/networks/{network}/cameras/{device}/liveview", e.authorized(e.handleLiveview))
+	mux.HandleFunc("POST /api/v2/accounts/{account}/networks/{network}/owls/{device}/liveview", e.authorized(e.handleLiveview))
+	mux.HandleFunc("POST /api/v2/accounts/{account}/networks/{network}/doorbells/{device}/liveview", e.authorized(e.handleLiveview))
+	mux.HandleFunc("GET /network/{network}/command/{command}", e.authorized(e.handleCommand))
+	mux.HandleFunc("POST /network/{network}/command/{command}/done", e.authorized(e.handleCommandDone))
+
+	return mux
+}
+
+// ServeStream accepts liveview connections on the TLS listener until the context is cancelled.
+// Each connection must send a valid handshake for an active liveview command and keep-alives while streaming.
+//
+// ctx: the context to use for the server, including cancellation
+//
+// listener: the TLS listener to accept connections on
+//
+// Example: emulator.ServeStream(ctx, listener) = nil
+func (e *Emulator) ServeStream(ctx context.Context, listener net.Listener) error {
+	stop := context.AfterFunc(ctx, func() {
+		listener.Close()
+	})
+	defer stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("error accepting liveview connection: %w", err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+
+			if err := e.serveConn(ctx, conn); err != nil {
+				log.Printf("Liveview connection from %s closed: %v\n", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// serveConn validates the handshake and streams the video to a single liveview connection
+func (e *Emulator) serveConn(ctx context.Context, conn net.Conn) error {
+	if err := conn.SetReadDeadline(time.Now().Add(common.READ_TIMEOUT)); err != nil {
+		return err
+	}
+
+	handshake, err := common.ReadHandshake(conn)
+	if err != nil {
+		return err
+	}
+	if err := handshake.Validate(); err != nil {
+		return fmt.Errorf("invalid handshake: %w", err)
+	}
+
+	session := e.findSession(handshake.ConnectionId, handshake.ClientId)
+	if session == nil {
+		return fmt.Errorf("no active liveview command for connection %s", handshake.ConnectionId)
+	}
+	log.Printf("Liveview connection from %s for device %d\n", conn.RemoteAddr(), session.cameraId)
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	var writeMu sync.Mutex
+	go func() {
+		cancel(e.readKeepalives(conn, &writeMu))
+	}()
+
+	source, err := e.opts.Source(ctx)
+	if err != nil {
+		return fmt.Errorf("error opening stream source: %w", err)
+	}
+	defer source.Close()
+
+	var sequence uint32
+	buf := make([]byte, EMULATOR_CHUNK_SIZE)
+	for {
+		if session.complete(e.opts.SessionDuration) {
+			return nil
+		}
+
+		n, err := io.ReadFull(source, buf)
+		if n > 0 {
+			sequence++
+			header := common.FrameHeader{Type: common.FRAME_TYPE_VIDEO, Sequence: sequence, Length: uint32(n)}
+
+			writeMu.Lock()
+			_, writeErr := conn.Write(append(header.Marshal(), buf[:n]...))
+			writeMu.Unlock()
+			if writeErr != nil {
+				if cause := context.Cause(ctx); cause != nil {
+					return cause
+				}
+				return fmt.Errorf("error writing video: %w", writeErr)
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("error reading stream source: %w", err)
+		}
+
+		if e.opts.FrameInterval > 0 {
+			select {
+			case <-ctx.Done():
+			case <-session.stopped:
+			case <-time.After(e.opts.FrameInterval):
+			}
+		}
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+	}
+}
+
+// readKeepalives validates and acknowledges the keep-alives sent by the client until the connection fails
+func (e *Emulator) readKeepalives(conn net.Conn, writeMu *sync.Mutex) error {
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(EMULATOR_KEEPALIVE_TIMEOUT)); err != nil {
+			return err
+		}
+
+		data := make([]byte, common.FRAME_HEADER_SIZE)
+		if _, err := io.ReadFull(conn, data); err != nil {
+			return fmt.Errorf("error reading keep-alive: %w", err)
+		}
+		header, err := common.ParseFrameHeader(data)
+		if err != nil {
+			return err
+		}
+		if header.Length > uint32(common.FRAME_MAX_PAYLOAD) {
+			return fmt.Errorf("%w: %d bytes", common.ErrFrameTooLarge, header.Length)
+		}
+
+		payload := make([]byte, header.Length)
+		if _, err := io.ReadFull(conn, payload); err != nil {
+			return fmt.Errorf("error reading keep-alive: %w", err)
+		}
+
+		var keepalive common.Keepalive
+		if err := keepalive.Unmarshal(append(data, payload...)); err != nil {
+			return fmt.Errorf("invalid keep-alive: %w", err)
+		}
+
+		writeMu.Lock()
+		_, err = conn.Write(keepalive.Marshal())
+		writeMu.Unlock()
+		if err != nil {
+			return fmt.Errorf("error acknowledging keep-alive: %w", err)
+		}
+	}
+}
+
+// findSession returns the liveview session matching the handshake, if it is still active
+func (e *Emulator) findSession(connectionId string, clientId uint32) *emulatorSession {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, session := range e.sessions {
+		if session.connectionId == connectionId && session.clientId == clientId && !session.complete(e.opts.SessionDuration) {
+			return session
+		}
+	}
+
+	return nil
+}
+
+// complete reports whether the command was stopped or outlived the session duration
+func (s *emulatorSession) complete(duration time.Duration) bool {
+	select {
+	case <-s.stopped:
+		return true
+	default:
+	}
+
+	return duration > 0 && time.Since(s.started) > duration
+}
+
+// authorized wraps a handler, rejecting requests without an access token issued by the emulator
+func (e *Emulator) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		e.mu.Lock()
+		valid := e.accessTokens[token]
+		e.mu.Unlock()
+
+		if !valid {
+			writeEmulatorJSON(w, http.StatusUnauthorized, map[string]any{"code": 101, "message": "Unauthorized Access"})
+			return
+		}
+
+		if account := r.PathValue("account"); account != "" && account != strconv.Itoa(e.opts.AccountId) {
+			writeEmulatorJSON(w, http.StatusForbidden, map[string]any{"code": 101, "message": "Unauthorized Access"})
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// handleToken serves the password and refresh token grants
+func (e *Emulator) handleToken(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		GrantType    string `json:"grant_type"`
+		Username     string `json:"username"`
+		Password     string `json:"password"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeEmulatorJSON(w, http.StatusBadRequest, map[string]any{"message": "Invalid request body"})
+		return
+	}
+
+	switch body.GrantType {
+	case "password":
+		if r.Header.Get("hardware_id") == "" {
+			writeEmulatorJSON(w, http.StatusBadRequest, map[string]any{"message": "Missing hardware_id"})
+			return
+		}
+		if e.opts.Email != "" && (body.Username != e.opts.Email || body.Password != e.opts.Password) {
+			writeEmulatorJSON(w, http.StatusUnauthorized, map[string]any{"message": "Invalid credentials"})
+			return
+		}
+
+		if e.opts.VerificationCode != "" {
+			code := r.Header.Get("2fa-code")
+			if code == "" {
+				writeEmulatorJSON(w, http.StatusPreconditionFailed, common.LoginResponse{
+					NextTimeInSeconds:   30,
+					Phone:               "+1******0000",
+					TwoStepVerification: "sms",
+				})
+				return
+			} else if code != e.opts.VerificationCode {
+				writeEmulatorJSON(w, http.StatusUnauthorized, map[string]any{"message": "Invalid verification code"})
+				return
+			}
+		}
+	case "refresh_token":
+		e.mu.Lock()
+		valid := e.refreshTokens[body.RefreshToken]
+		delete(e.refreshTokens, body.RefreshToken)
+		e.mu.Unlock()
+
+		if !valid {
+			writeEmulatorJSON(w, http.StatusUnauthorized, map[string]any{"message": "Invalid refresh token"})
+			return
+		}
+	default:
+		writeEmulatorJSON(w, http.StatusBadRequest, map[string]any{"message": "Unsupported grant type"})
+		return
+	}
+
+	accessToken, refreshToken := uuid.NewString(), uuid.NewString()
+
+	e.mu.Lock()
+	e.accessTokens[accessToken] = true
+	e.refreshTokens[refreshToken] = true
+	e.mu.Unlock()
+
+	writeEmulatorJSON(w, http.StatusOK, common.LoginResponse{
+		AccessToken:  accessToken,
+		ExpiresIn:    int(EMULATOR_TOKEN_LIFETIME.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        "client",
+		TokenType:    "Bearer",
+	})
+}
+
+// handleTierInfo serves the account region and ID
+func (e *Emulator) handleTierInfo(w http.ResponseWriter, r *http.Request) {
+	writeEmulatorJSON(w, http.StatusOK, common.TierInfoResponse{Tier: e.opts.Region, AccountId: e.opts.AccountId})
+}
+
+// handleHomescreen serves a network with a sync module and one device of each type
+func (e *Emulator) handleHomescreen(w http.ResponseWriter, r *http.Request) {
+	writeEmulatorJSON(w, http.StatusOK, EmulatorHomescreen())
+}
+
+// handleLiveview starts a liveview command and returns the connection string of the stream server
+func (e *Emulator) handleLiveview(w http.ResponseWriter, r *http.Request) {
+	networkId, _ := strconv.Atoi(r.PathValue("network"))
+	deviceId, _ := strconv.Atoi(r.PathValue("device"))
+	if networkId != EMULATOR_NETWORK_ID || !emulatorDeviceExists(deviceId) {
+		writeEmulatorJSON(w, http.StatusNotFound, map[string]any{"message": "Device not found"})
+		return
+	}
+
+	e.mu.Lock()
+	e.nextCommandId++
+	session := &emulatorSession{
+		networkId:    networkId,
+		cameraId:     deviceId,
+		connectionId: strings.ReplaceAll(uuid.NewString(), "-", "")[:common.CONNECTION_ID_LENGTH],
+		clientId:     uint32(e.nextCommandId),
+		started:      time.Now(),
+		stopped:      make(chan struct{}),
+	}
+	commandId := e.nextCommandId
+	e.sessions[commandId] = session
+	e.mu.Unlock()
+
+	writeEmulatorJSON(w, http.StatusOK, common.LiveviewResponse{
+		CommandId:       commandId,
+		PollingInterval: EMULATOR_POLLING_INTERVAL,
+		Server:          fmt.Sprintf("immis://%s/%s__IMDS_EMU%07d?client_id=%d", e.opts.StreamAddress, session.connectionId, deviceId, session.clientId),
+	})
+}
+
+// handleCommand serves the status of a liveview command
+func (e *Emulator) handleCommand(w http.ResponseWriter, r *http.Request) {
+	session, commandId := e.commandSession(r)
+	if session == nil {
+		writeEmulatorJSON(w, http.StatusNotFound, map[string]any{"message": "Command not found"})
+		return
+	}
+
+	writeEmulatorJSON(w, http.StatusOK, common.CommandResponse{
+		Message:       fmt.Sprintf("Command %d", commandId),
+		Complete:      session.complete(e.opts.SessionDuration),
+		StatusMessage: "Command succeeded",
+	})
+}
+
+// handleCommandDone marks a liveview command as done, closing its stream
+func (e *Emulator) handleCommandDone(w http.ResponseWriter, r *http.Request) {
+	session, commandId := e.commandSession(r)
+	if session == nil {
+		writeEmulatorJSON(w, http.StatusNotFound, map[string]any{"message": "Command not found"})
+		return
+	}
+
+	session.stopOnce.Do(func() {
+		close(session.stopped)
+	})
+
+	writeEmulatorJSON(w, http.StatusOK, common.CommandResponse{
+		Code:    902,
+		Message: fmt.Sprintf("Command %d set to done", commandId),
+	})
+}
+
+// commandSession returns the session of the command in the request path
+func (e *Emulator) commandSession(r *http.Request) (*emulatorSession, int) {
+	networkId, _ := strconv.Atoi(r.PathValue("network"))
+	commandId, _ := strconv.Atoi(r.PathValue("command"))
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	session := e.sessions[commandId]
+	if session == nil || session.networkId != networkId {
+		return nil, commandId
+	}
+
+	return session, commandId
+}
+
+// EmulatorHomescreen returns the devices reported by the emulator
+//
+// Example: EmulatorHomescreen().Owls[0].Id = EMULATOR_OWL_ID
+func EmulatorHomescreen() *common.HomescreenResponse {
+	device := func(id int, name string, deviceType string) common.BaseCameraDevice {
+		temperature := 70
+		return common.BaseCameraDevice{
+			Id:              id,
+			Name:            name,
+			Type:            deviceType,
+			NetworkId:       EMULATOR_NETWORK_ID,
+			Serial:          fmt.Sprintf("EMU%07d", id),
+			Enabled:         true,
+			Status:          "done",
+			Battery:         "ok",
+			FirmwareVersion: "0.0.0",
+			Signals:         common.DeviceSignals{Lfr: 5, Wifi: 5, Battery: 3, Temperature: &temperature},
+		}
+	}
+
+	return &common.HomescreenResponse{
+		Networks: []common.BaseNetwork{{Id: EMULATOR_NETWORK_ID, Name: "Emulated Network"}},
+		SyncModules: []common.SyncModule{{
+			Id:        EMULATOR_SYNC_MODULE_ID,
+			Name:      "Emulated Sync Module",
+			Type:      "sm2",
+			NetworkId: EMULATOR_NETWORK_ID,
+			Serial:    fmt.Sprintf("EMU%07d", EMULATOR_SYNC_MODULE_ID),
+			Status:    "online",
+		}},
+		Cameras:   []common.BaseCameraDevice{device(EMULATOR_CAMERA_ID, "Emulated Camera", "white")},
+		Owls:      []common.BaseCameraDevice{device(EMULATOR_OWL_ID, "Emulated Mini", "owl")},
+		Doorbells: []common.BaseCameraDevice{device(EMULATOR_DOORBELL_ID, "Emulated Doorbell", "lotus")},
+	}
+}
+
+// emulatorDeviceExists reports whether the device ID is one of the emulated devices
+func emulatorDeviceExists(deviceId int) bool {
+	return deviceId == EMULATOR_CAMERA_ID || deviceId == EMULATOR_OWL_ID || deviceId == EMULATOR_DOORBELL_ID
+}
+
+// writeEmulatorJSON writes the JSON encoding of the value with the status code
+func writeEmulatorJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// NewEmulatorCertificate generates a self-signed certificate for the liveview stream server
+//
+// hosts: the IP addresses and host names the certificate is valid for
+//
+// Example: NewEmulatorCertificate("127.0.0.1") = tls.Certificate{...}, nil
+func NewEmulatorCertificate(hosts ...string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -0,0 +1,223 @@
+package emulate_test
+
+import (
+	"blink-liveview-websocket/common"
+	"blink-liveview-websocket/emulate"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+// lockedBuffer is a buffer that can be read while the stream writes to it
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return bytes.Clone(b.buf.Bytes())
+}
+
+// newStreamListener starts a TLS listener with a certificate generated by the emulator
+func newStreamListener(t *testing.T) (net.Listener, *x509.Certificate) {
+	certificate, err := emulate.NewEmulatorCertificate("127.0.0.1")
+	assert.Equal(t, nil, err)
+	cert, err := x509.ParseCertificate(certificate.Certificate[0])
+	assert.Equal(t, nil, err)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{certificate}})
+	assert.Equal(t, nil, err)
+	t.Cleanup(func() { listener.Close() })
+
+	return listener, cert
+}
+
+// newEmulator starts the emulator API and stream servers, and returns a logged in client pointed at them
+func newEmulator(t *testing.T, opts emulate.EmulatorOptions) (*common.BlinkClient, net.Listener) {
+	listener, cert := newStreamListener(t)
+	opts.StreamAddress = listener.Addr().String()
+	emulator := emulate.NewEmulator(opts)
+
+	mockServer := httptest.NewServer(emulator.Handler())
+	t.Cleanup(mockServer.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		emulator.ServeStream(ctx, listener)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	liveviewPort := common.LIVEVIEW_PORT
+	common.LIVEVIEW_PORT = port
+	t.Cleanup(func() { common.LIVEVIEW_PORT = liveviewPort })
+
+	defaultTLS := common.DefaultStreamTLS
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	common.DefaultStreamTLS = &common.StreamTLS{RootCAs: pool}
+	t.Cleanup(func() { common.DefaultStreamTLS = defaultTLS })
+
+	client := common.NewBlinkClient("", "", 0)
+	client.RestUrl = mockServer.URL
+	client.OAuthUrl = mockServer.URL
+
+	resp, err := client.Login(context.Background(), opts.Email, opts.Password, opts.VerificationCode, &common.Fingerprint{Value: "mock-fingerprint"})
+	assert.Equal(t, err, nil)
+	client.Tokens = common.NewTokenSource(resp, "mock-fingerprint")
+
+	tierInfo, err := client.GetTierInfo(context.Background())
+	assert.Equal(t, err, nil)
+	client.Region = tierInfo.Tier
+	client.AccountId = tierInfo.AccountId
+
+	return client, listener
+}
+
+func TestEmulatorLogin(t *testing.T) {
+	mockServer := httptest.NewServer(emulate.NewEmulator(emulate.EmulatorOptions{
+		Email:            "email",
+		Password:         "password",
+		VerificationCode: "123456",
+	}).Handler())
+	defer mockServer.Close()
+
+	newLoginFlow := func() *common.LoginFlow {
+		client := common.NewBlinkClient("", "", 0)
+		client.OAuthUrl = mockServer.URL
+		return common.NewLoginFlow(client, "email", "password", &common.Fingerprint{Value: "mock-fingerprint"})
+	}
+
+	flow := newLoginFlow()
+	state, err := flow.Start(context.Background())
+	assert.Equal(t, err, nil)
+	assert.Equal(t, state, common.LoginStateAwaitingCode)
+	assert.Equal(t, flow.Method, "sms")
+
+	_, err = flow.SubmitCode(context.Background(), "000000")
+	assert.Equal(t, errors.Is(err, common.ErrInvalidCode), true)
+
+	state, err = flow.SubmitCode(context.Background(), "123456")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, state, common.LoginStateComplete)
+
+	client := common.NewBlinkClient("", "", 0)
+	client.RestUrl = mockServer.URL
+	client.OAuthUrl = mockServer.URL
+	client.Tokens = common.NewTokenSource(flow.Response, "mock-fingerprint")
+
+	tierInfo, err := client.GetTierInfo(context.Background())
+	assert.Equal(t, err, nil)
+	assert.Equal(t, tierInfo, &common.TierInfoResponse{Tier: "emu", AccountId: 1})
+
+	// Refreshed tokens replace the previous ones
+	assert.Equal(t, client.Tokens.Refresh(context.Background(), client), nil)
+	_, err = client.RefreshToken(context.Background(), flow.Response.RefreshToken, "mock-fingerprint")
+	assert.NotEqual(t, err, nil)
+	_, err = client.GetTierInfo(context.Background())
+	assert.Equal(t, err, nil)
+
+	rejected := newLoginFlow()
+	rejected.Start(context.Background())
+	assert.Equal(t, rejected.State, common.LoginStateAwaitingCode)
+	_, err = common.NewBlinkClient("", "", 0).GetTierInfo(context.Background())
+	assert.NotEqual(t, err, nil)
+}
+
+func TestEmulatorHomescreen(t *testing.T) {
+	client, _ := newEmulator(t, emulate.EmulatorOptions{})
+
+	devices, err := client.Homescreen(context.Background(), client.HomescreenUrl())
+	assert.Equal(t, err, nil)
+	assert.Equal(t, devices, emulate.EmulatorHomescreen())
+
+	_, options := common.PrintDeviceOptions(devices)
+	assert.Equal(t, len(options), 3)
+
+	unauthorized := common.NewBlinkClient("invalid-token", "emu", 1)
+	unauthorized.RestUrl = client.RestUrl
+	_, err = unauthorized.Homescreen(context.Background(), unauthorized.HomescreenUrl())
+	assert.Equal(t, common.IsUnauthorized(err), true)
+}
+
+func TestEmulatorLivestream(t *testing.T) {
+	client, _ := newEmulator(t, emulate.EmulatorOptions{FrameInterval: 10 * time.Millisecond})
+
+	// Stream long enough for the client to send keep-alives
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+
+	var writer lockedBuffer
+	err := client.Livestream(ctx, "owl", emulate.EMULATOR_NETWORK_ID, emulate.EMULATOR_OWL_ID, &writer)
+	assert.Equal(t, err, nil)
+
+	data := writer.Bytes()
+	assert.NotEqual(t, len(data), 0)
+	assert.Equal(t, len(data)%emulate.TS_PACKET_SIZE, 0)
+	for i := 0; i < len(data); i += emulate.TS_PACKET_SIZE {
+		assert.Equal(t, data[i], byte(0x47))
+	}
+}
+
+func TestEmulatorSessionDuration(t *testing.T) {
+	client, _ := newEmulator(t, emulate.EmulatorOptions{SessionDuration: 500 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The stream is closed and the command marked as complete once the session ends
+	err := client.Livestream(ctx, "camera", emulate.EMULATOR_NETWORK_ID, emulate.EMULATOR_CAMERA_ID, io.Discard)
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, ctx.Err(), nil)
+}
+
+func TestEmulatorUnknownDevice(t *testing.T) {
+	client, _ := newEmulator(t, emulate.EmulatorOptions{})
+
+	_, err := client.BeginLiveview(context.Background(), client.ApiUrl()+"/api/v2/accounts/1/networks/100/owls/999/liveview")
+	apiErr, ok := common.AsAPIError(err)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, apiErr.StatusCode, 404)
+}
+
+func TestEmulatorRejectsHandshake(t *testing.T) {
+	_, listener := newEmulator(t, emulate.EmulatorOptions{})
+
+	conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	assert.Equal(t, err, nil)
+	defer conn.Close()
+
+	// The connection ID was never issued by a liveview command
+	_, err = conn.Write(common.NewHandshake("Cy5gwipn7Bui8L7z", 123).Marshal())
+	assert.Equal(t, err, nil)
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(make([]byte, 1))
+	assert.Equal(t, n, 0)
+	assert.Equal(t, errors.Is(err, io.EOF), true)
+}
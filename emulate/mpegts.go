@@ -0,0 +1,118 @@
+package emulate
+
+import "encoding/binary"
+
+// TS_PACKET_SIZE is the size of an MPEG-TS packet
+const TS_PACKET_SIZE = 188
+
+// The PIDs used by the generated stream
+const (
+	tsPidPat  = 0x0000
+	tsPidPmt  = 0x1000
+	tsPidPcr  = 0x0100
+	tsPidNull = 0x1fff
+)
+
+// The number of null packets between two program tables in the generated stream
+const tsTableInterval = 40
+
+// generatedStream is an endless MPEG-TS made of program tables and null packets
+type generatedStream struct {
+	pending    []byte
+	packets    int
+	continuity byte
+}
+
+// NewGeneratedStream returns an endless, valid MPEG-TS without media, for streaming without a video source.
+// The program declares a single H.264 stream so players wait for video instead of failing.
+//
+// Example: io.ReadFull(NewGeneratedStream(), buf)
+func NewGeneratedStream() *generatedStream {
+	return &generatedStream{}
+}
+
+// Read fills the buffer with the next packets of the stream
+func (g *generatedStream) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(g.pending) == 0 {
+			g.pending = g.nextPacket()
+		}
+
+		copied := copy(p[n:], g.pending)
+		g.pending = g.pending[copied:]
+		n += copied
+	}
+
+	return n, nil
+}
+
+// Close does nothing. The generated stream holds no resources
+func (g *generatedStream) Close() error {
+	return nil
+}
+
+// nextPacket returns the next packet, sending the PAT and PMT at regular intervals
+func (g *generatedStream) nextPacket() []byte {
+	defer func() { g.packets++ }()
+
+	switch g.packets % tsTableInterval {
+	case 0:
+		// Program 1 is described by the PMT
+		return g.tablePacket(tsPidPat, []byte{
+			0x00, 0xb0, 0x0d, // PAT, section length 13
+			0x00, 0x01, 0xc1, 0x00, 0x00, // transport stream 1, version 0, current
+			0x00, 0x01, 0xe0 | tsPidPmt>>8, tsPidPmt & 0xff,
+		})
+	case 1:
+		// A single H.264 stream, which also carries the PCR
+		return g.tablePacket(tsPidPmt, []byte{
+			0x02, 0xb0, 0x12, // PMT, section length 18
+			0x00, 0x01, 0xc1, 0x00, 0x00, // program 1, version 0, current
+			0xe0 | tsPidPcr>>8, tsPidPcr & 0xff, 0xf0, 0x00,
+			0x1b, 0xe0 | tsPidPcr>>8, tsPidPcr & 0xff, 0xf0, 0x00,
+		})
+	}
+
+	packet := make([]byte, TS_PACKET_SIZE)
+	for i := range packet {
+		packet[i] = 0xff
+	}
+	copy(packet, []byte{0x47, tsPidNull >> 8, tsPidNull & 0xff, 0x10})
+
+	return packet
+}
+
+// tablePacket builds a packet carrying a single PSI section, appending its CRC
+func (g *generatedStream) tablePacket(pid int, section []byte) []byte {
+	packet := make([]byte, TS_PACKET_SIZE)
+	for i := range packet {
+		packet[i] = 0xff
+	}
+
+	// Payload unit start, payload only, pointer field 0
+	copy(packet, []byte{0x47, 0x40 | byte(pid>>8), byte(pid), 0x10 | g.continuity&0x0f, 0x00})
+	g.continuity++
+
+	n := copy(packet[5:], section)
+	binary.BigEndian.PutUint32(packet[5+n:], crc32Mpeg(section))
+
+	return packet
+}
+
+// crc32Mpeg computes the CRC-32/MPEG-2 checksum of a PSI section
+func crc32Mpeg(data []byte) uint32 {
+	crc := uint32(0xffffffff)
+	for _, b := range data {
+		crc ^= uint32(b) << 24
+		for range 8 {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc
+}
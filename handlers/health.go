@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"blink-liveview-websocket/common"
+	"net/http"
+)
+
+// HealthHandler serves the latest state of every device tracked by the monitor as JSON
+//
+// monitor: the health monitor to read the state from
+//
+// Example: http.HandleFunc("GET /devices/health", handlers.HealthHandler(monitor))
+func HealthHandler(monitor *common.HealthMonitor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusOK, monitor.Status())
+	}
+}
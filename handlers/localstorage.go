@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// LocalStorageHandler lists the clips stored on the sync module's USB storage.
+// The Blink token is read from the Authorization header. The region, account_id
+// and network_id are read from the query string.
+//
+// w is the http.ResponseWriter
+//
+// r is the http.Request
+//
+// Example: http.HandleFunc("GET /sync_modules/{id}/local_storage", handlers.LocalStorageHandler)
+func LocalStorageHandler(w http.ResponseWriter, r *http.Request) {
+	syncModuleId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || syncModuleId == 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid sync module ID"))
+		return
+	}
+
+	networkId, err := queryInt(r, "network_id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	client, err := restClient(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	manifest, err := client.LocalStorageManifest(r.Context(), networkId, syncModuleId)
+	if err != nil {
+		log.Println("error requesting local storage manifest", err)
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, manifest)
+}
+
+// LocalStorageClipHandler asks the sync module to upload a clip from its USB storage and streams the MP4.
+// The Blink token is read from the Authorization header. The region, account_id, network_id
+// and manifest_id are read from the query string. A fresh manifest is requested when manifest_id is omitted.
+//
+// w is the http.ResponseWriter
+//
+// r is the http.Request
+//
+// Example: http.HandleFunc("GET /sync_modules/{id}/local_storage/{clip_id}", handlers.LocalStorageClipHandler)
+func LocalStorageClipHandler(w http.ResponseWriter, r *http.Request) {
+	syncModuleId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || syncModuleId == 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid sync module ID"))
+		return
+	}
+
+	networkId, err := queryInt(r, "network_id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	client, err := restClient(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// The IDs end up in the Blink URL, so only numeric IDs are accepted
+	clipId := r.PathValue("clip_id")
+	if !isNumericId(clipId) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid clip ID"))
+		return
+	}
+	manifestId := r.URL.Query().Get("manifest_id")
+	if manifestId != "" && !isNumericId(manifestId) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid manifest_id"))
+		return
+	} else if manifestId == "" {
+		manifest, err := client.LocalStorageManifest(r.Context(), networkId, syncModuleId)
+		if err != nil {
+			log.Println("error requesting local storage manifest", err)
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		if _, ok := manifest.FindClip(clipId); !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("clip %s not found", clipId))
+			return
+		}
+
+		manifestId = manifest.ManifestId
+	}
+
+	// Clips can be large, so stream them. The headers are only sent once the first bytes arrive,
+	// so errors before the download starts can still be reported as JSON
+	n, err := client.DownloadLocalStorageClip(r.Context(), networkId, syncModuleId, manifestId, clipId, &clipWriter{w: w})
+	if err != nil && n == 0 {
+		log.Println("error downloading local storage clip", err)
+		writeError(w, http.StatusBadGateway, err)
+	} else if err != nil {
+		log.Println("error streaming local storage clip", err)
+	}
+}
+
+// isNumericId reports whether the value is a decimal ID, as used by Blink for clips and manifests
+//
+// value: the ID to check
+//
+// Example: isNumericId("866333964") = true
+func isNumericId(value string) bool {
+	_, err := strconv.ParseUint(value, 10, 64)
+
+	return err == nil
+}
+
+// clipWriter sends the MP4 headers before the first write
+type clipWriter struct {
+	w           http.ResponseWriter
+	wroteHeader bool
+}
+
+func (cw *clipWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.w.Header().Set("Content-Type", "video/mp4")
+		cw.w.Header().Set("Cache-Control", "no-store")
+		cw.w.WriteHeader(http.StatusOK)
+		cw.wroteHeader = true
+	}
+
+	return cw.w.Write(p)
+}
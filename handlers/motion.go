@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// motionHandler enables or disables motion detection on the device requested by the client and reports the result
+//
+// ctx: the context to use for the command, cancelled when the client disconnects
+//
+// c: the client connection to reply on
+//
+// data: the command data containing the credentials, network_id, camera_id, camera_type and enabled
+func motionHandler(ctx context.Context, c *clientConn, data map[string]interface{}) {
+	networkId, _ := data["network_id"].(string)
+	network_id, _ := strconv.Atoi(networkId)
+	cameraId, _ := data["camera_id"].(string)
+	camera_id, _ := strconv.Atoi(cameraId)
+	device_type, _ := data["camera_type"].(string)
+	enabled, ok := data["enabled"].(bool)
+	if network_id == 0 || camera_id == 0 || !ok {
+		c.WriteJSON(errorMessage("camera:motion", fmt.Errorf("network_id, camera_id and enabled are required")))
+		return
+	}
+
+	result, err := newClient(data).SetMotionDetection(ctx, device_type, network_id, camera_id, enabled)
+	if err != nil {
+		log.Println("error changing motion detection", err)
+		c.WriteJSON(errorMessage("camera:motion", err))
+		return
+	}
+
+	c.WriteJSON(CommandMessage{
+		Command: "camera:motion",
+		Data: map[string]interface{}{
+			"error":       false,
+			"message":     result.Command.StatusMessage,
+			"network_id":  network_id,
+			"camera_id":   result.DeviceId,
+			"camera_type": result.DeviceType,
+			"enabled":     result.Enabled,
+		},
+	})
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// networkHandler arms or disarms the network requested by the client and reports the result
+//
+// ctx: the context to use for the command, cancelled when the client disconnects
+//
+// c: the client connection to reply on
+//
+// command: the command received (network:arm or network:disarm)
+//
+// data: the command data containing the credentials and network_id
+func networkHandler(ctx context.Context, c *clientConn, command string, data map[string]interface{}) {
+	networkId, _ := data["network_id"].(string)
+	network_id, err := strconv.Atoi(networkId)
+	if err != nil || network_id == 0 {
+		c.WriteJSON(errorMessage(command, fmt.Errorf("invalid network_id %q", networkId)))
+		return
+	}
+
+	armed := command == "network:arm"
+	result, err := newClient(data).SetNetworkArmed(ctx, network_id, armed)
+	if err != nil {
+		log.Println("error changing the network state", err)
+		c.WriteJSON(errorMessage(command, err))
+		return
+	}
+
+	c.WriteJSON(CommandMessage{
+		Command: command,
+		Data: map[string]interface{}{
+			"error":      false,
+			"message":    result.StatusMessage,
+			"network_id": network_id,
+			"armed":      armed,
+		},
+	})
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"blink-liveview-websocket/common"
+	"crypto/subtle"
+	"net"
+)
+
+// PROFILE_SECRET_ENV is the environment variable holding the secret that unlocks the server's login profile
+var PROFILE_SECRET_ENV = "BLINK_PROFILE_SECRET"
+
+// PROFILE_SECRET_HEADER is the REST request header carrying the profile secret
+var PROFILE_SECRET_HEADER = "X-Profile-Secret"
+
+// The client built from the server's login profile. Nil when the server runs without a profile
+var profileClient *common.BlinkClient
+
+// The secret clients must send to use the profile. Empty when the server is bound to loopback only
+var profileSecret string
+
+// SetProfile makes the server act on behalf of a saved login profile.
+// Requests that do not include their own credentials use the profile instead, if they send the secret.
+// Every session shares the profile's tokens, so refreshed tokens are saved once.
+//
+// profile: the login profile to use
+//
+// secret: the secret clients must send to use the profile. Empty accepts every client, so only use it on loopback
+//
+// Example: handlers.SetProfile(profile, os.Getenv(handlers.PROFILE_SECRET_ENV))
+func SetProfile(profile *common.Profile, secret string) {
+	client := profile.Client()
+	client.HTTPClient = httpClient
+	profileClient = client
+	profileSecret = secret
+}
+
+// newProfileClient returns a copy of the profile client, or nil without a profile or when the secret does not match
+//
+// secret: the profile secret sent by the client
+//
+// Example: newProfileClient("s3cret") = &common.BlinkClient{...}
+func newProfileClient(secret string) *common.BlinkClient {
+	if profileClient == nil {
+		return nil
+	}
+	if profileSecret != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(profileSecret)) != 1 {
+		return nil
+	}
+
+	client := *profileClient
+	return &client
+}
+
+// IsLoopbackAddress reports whether the server address only accepts local connections
+//
+// address: the address the server binds to
+//
+// Example: IsLoopbackAddress("localhost:8080") = true
+func IsLoopbackAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,20 @@
+package handlers
+
+// The capture replayed to every liveview session. Empty streams from Blink
+var replayPath string
+
+// The replay speed relative to the original timing
+var replaySpeed float64
+
+// SetReplay makes every liveview session play back a capture instead of streaming from Blink.
+// Useful to reproduce a stream in the web client without a camera.
+//
+// path: the path of the capture file
+//
+// speed: the playback speed relative to the original timing. 0 plays as fast as possible
+//
+// Example: handlers.SetReplay("session.blvcap", 1)
+func SetReplay(path string, speed float64) {
+	replayPath = path
+	replaySpeed = speed
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"blink-liveview-websocket/common"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// restClient builds a Blink API client from a REST request.
+// The token is read from the Authorization header and the region and account ID from the query string.
+// Requests without an Authorization header use the server's login profile, if any, when they send the profile secret.
+//
+// r: the incoming request
+//
+// Example: restClient(r) = &common.BlinkClient{...}, nil
+func restClient(r *http.Request) (*common.BlinkClient, error) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		if client := newProfileClient(r.Header.Get(PROFILE_SECRET_HEADER)); client != nil {
+			return client, nil
+		}
+
+		return nil, fmt.Errorf("missing Authorization header")
+	}
+
+	accountId, err := strconv.Atoi(r.URL.Query().Get("account_id"))
+	if err != nil || accountId == 0 {
+		return nil, fmt.Errorf("invalid account_id")
+	}
+
+	client := common.NewBlinkClient(token, r.URL.Query().Get("region"), accountId)
+	client.HTTPClient = httpClient
+
+	return client, nil
+}
+
+// queryInt reads a required integer from the query string
+//
+// r: the incoming request
+//
+// key: the query parameter to read
+//
+// Example: queryInt(r, "network_id") = 1234, nil
+func queryInt(r *http.Request, key string) (int, error) {
+	value, err := strconv.Atoi(r.URL.Query().Get(key))
+	if err != nil || value == 0 {
+		return 0, fmt.Errorf("invalid %s", key)
+	}
+
+	return value, nil
+}
+
+// writeError writes the error as a JSON response.
+// Blink API errors are mapped to a matching status code for the client.
+//
+// w: the response writer
+//
+// status: the status code to use for non-API errors
+//
+// err: the error to write
+func writeError(w http.ResponseWriter, status int, err error) {
+	if apiErr, ok := common.AsAPIError(err); ok {
+		switch {
+		case common.IsUnauthorized(err):
+			status = http.StatusUnauthorized
+		case common.IsRateLimited(err), common.IsDeviceBusy(err):
+			status = http.StatusServiceUnavailable
+			if apiErr.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(apiErr.RetryAfter.Seconds())))
+			}
+		default:
+			status = http.StatusBadGateway
+		}
+	}
+
+	writeJSON(w, status, map[string]interface{}{
+		"error":   true,
+		"message": err.Error(),
+	})
+}
+
+// writeJSON writes the value as a JSON response
+//
+// w: the response writer
+//
+// status: the status code of the response
+//
+// v: the value to encode
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("error writing response", err)
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"blink-liveview-websocket/common"
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// SnapshotHandler captures a new thumbnail on the camera and responds with the JPEG.
+// The Blink token is read from the Authorization header. The region, account_id,
+// network_id and type (device type) are read from the query string.
+//
+// w is the http.ResponseWriter
+//
+// r is the http.Request
+//
+// Example: http.HandleFunc("GET /cameras/{id}/snapshot.jpg", handlers.SnapshotHandler)
+func SnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	cameraId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || cameraId == 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid camera ID"))
+		return
+	}
+
+	networkId, err := queryInt(r, "network_id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	deviceType := r.URL.Query().Get("type")
+	if _, err := common.GetThumbnailPath(deviceType); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid device type: %q", deviceType))
+		return
+	}
+
+	client, err := restClient(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var image bytes.Buffer
+	if err := client.Snapshot(r.Context(), deviceType, networkId, cameraId, &image); err != nil {
+		log.Println("error capturing snapshot", err)
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Content-Length", strconv.Itoa(image.Len()))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(image.Bytes())
+}
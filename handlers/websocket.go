@@ -3,12 +3,16 @@ package handlers
 import (
 	"blink-liveview-websocket/common"
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os/exec"
 	"slices"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -19,6 +23,29 @@ type CommandMessage struct {
 	Data    map[string]interface{} `json:"data"`
 }
 
+// clientConn serializes writes to the WebSocket connection.
+// The gorilla connection supports a single concurrent writer only.
+type clientConn struct {
+	*websocket.Conn
+	mu sync.Mutex
+}
+
+// WriteJSON writes the JSON encoding of v as a message
+func (c *clientConn) WriteJSON(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.Conn.WriteJSON(v)
+}
+
+// WriteMessage writes a message with the given type and payload
+func (c *clientConn) WriteMessage(messageType int, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.Conn.WriteMessage(messageType, data)
+}
+
 var upgrader = websocket.Upgrader{
 	// TODO: Check if this is useful
 	// EnableCompression: true,
@@ -27,6 +54,10 @@ var upgrader = websocket.Upgrader{
 var VALID_COMMANDS = []string{
 	"liveview:start",
 	"liveview:stop",
+	"liveview:stats",
+	"network:arm",
+	"network:disarm",
+	"camera:motion",
 }
 
 // The buffer size before dispatching the data to the WebSocket connection in bytes
@@ -35,15 +66,94 @@ var BUFFER_SIZE = 8 * 1024
 // The idle timeout before closing the connection
 var IDLE_TIMEOUT = 10 * time.Second
 
-func liveviewHandler(ctx context.Context, c *websocket.Conn, data map[string]interface{}) {
-	region := data["account_region"].(string)
-	token := data["api_token"].(string)
-	account_id, _ := strconv.Atoi(data["account_id"].(string))
+// The HTTP client shared by every session to reuse connections to the Blink API
+var httpClient = &http.Client{Timeout: common.HTTP_TIMEOUT, Transport: common.DefaultTransport}
+
+// newClient builds a Blink API client from the credentials sent by the client.
+// Clients that do not send an api_token use the server's login profile, if any, when they send the profile_secret.
+//
+// data: the command data containing the account_region, api_token and account_id, or the profile_secret
+//
+// Example: newClient(map[string]interface{}{"account_region": "u011", ...})
+func newClient(data map[string]interface{}) *common.BlinkClient {
+	region, _ := data["account_region"].(string)
+	token, _ := data["api_token"].(string)
+	if token == "" {
+		secret, _ := data["profile_secret"].(string)
+		if client := newProfileClient(secret); client != nil {
+			return client
+		}
+	}
+	accountId, _ := data["account_id"].(string)
+	account_id, _ := strconv.Atoi(accountId)
+
+	client := common.NewBlinkClient(token, region, account_id)
+	client.HTTPClient = httpClient
+
+	// Allow long-running sessions to outlive the access token
+	if refreshToken, ok := data["refresh_token"].(string); ok && refreshToken != "" {
+		hardwareId, _ := data["hardware_id"].(string)
+		client.Tokens = common.NewTokenSourceWithExpiry(token, refreshToken, time.Time{}, hardwareId)
+	}
+
+	return client
+}
+
+// renewalOptions reads the continuous mode settings sent by the client.
+// Returns nil unless continuous is true.
+//
+// data: the command data containing continuous and the optional max_renewals, renewal_gap and max_duration (seconds)
+//
+// Example: renewalOptions(map[string]interface{}{"continuous": true, "max_duration": 3600}) = &common.RenewalOptions{MaxDuration: time.Hour}
+func renewalOptions(data map[string]interface{}) *common.RenewalOptions {
+	if continuous, _ := data["continuous"].(bool); !continuous {
+		return nil
+	}
+
+	maxRenewals, _ := data["max_renewals"].(float64)
+	gap, _ := data["renewal_gap"].(float64)
+	maxDuration, _ := data["max_duration"].(float64)
+
+	return &common.RenewalOptions{
+		MaxRenewals: int(maxRenewals),
+		Gap:         time.Duration(gap * float64(time.Second)),
+		MaxDuration: time.Duration(maxDuration * float64(time.Second)),
+	}
+}
+
+// statsMessage builds a message with the transport statistics of the stream
+//
+// stats: the statistics of the stream
+//
+// Example: statsMessage(stats) = CommandMessage{Command: "liveview:stats", Data: {"bytes_received": 1024, ...}}
+func statsMessage(stats *common.StreamStats) CommandMessage {
+	var data map[string]interface{}
+	encoded, _ := json.Marshal(stats.Snapshot())
+	json.Unmarshal(encoded, &data)
+
+	return CommandMessage{
+		Command: "liveview:stats",
+		Data:    data,
+	}
+}
+
+// liveviewHandler streams a camera to the client until the context is cancelled or the stream ends.
+// The session is cancelled and ffmpeg's input closed as soon as the stream returns (e.g. the command completed).
+//
+// ctx: the context of the session
+//
+// cancel: cancels the session's context
+//
+// c: the client connection
+//
+// data: the liveview:start command data
+//
+// stats: the statistics of the stream
+func liveviewHandler(ctx context.Context, cancel context.CancelFunc, c *clientConn, data map[string]interface{}, stats *common.StreamStats) {
 	network_id, _ := strconv.Atoi(data["network_id"].(string))
 	camera_id, _ := strconv.Atoi(data["camera_id"].(string))
 	device_type := data["camera_type"].(string)
 
-	// TODO: Strip metadata from the stream before piping it to the client
 	ffmpegCmd := exec.Command("ffmpeg",
 		"-i", "pipe:0",
 		"-c:v", "libx264", "-preset", "ultrafast", "-tune", "zerolatency",
@@ -73,19 +183,57 @@ func liveviewHandler(ctx context.Context, c *websocket.Conn, data map[string]int
 	}
 	defer ffmpegCmd.Process.Kill()
 
+	client := newClient(data)
+	renewal := renewalOptions(data)
+	stream := common.StreamOptions{Stats: stats}
+
+	// Push the statistics on an interval when the client asks for it
+	if interval, _ := data["stats_interval"].(float64); interval > 0 {
+		go func() {
+			ticker := time.NewTicker(time.Duration(interval * float64(time.Second)))
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					c.WriteJSON(statsMessage(stats))
+				}
+			}
+		}()
+	}
+
+	streamErr := make(chan error, 1)
 	go func() {
-		err := common.Livestream(ctx, common.AccountDetails{
-			Region:     region,
-			Token:      token,
-			DeviceType: device_type,
-			AccountId:  account_id,
-			NetworkId:  network_id,
-			CameraId:   camera_id,
-		}, inputPipe)
+		// End the session once the stream returns, so ffmpeg flushes and the client is told it stopped
+		defer cancel()
+		defer inputPipe.Close()
+
+		var err error
+		if replayPath != "" {
+			err = common.ReplayCaptureFile(ctx, replayPath, inputPipe, common.ReplayOptions{Speed: replaySpeed})
+		} else if renewal != nil {
+			// Let the client know the stream is being renewed, as there is a short gap in the video
+			renewal.OnRenew = func(count int, err error) {
+				c.WriteJSON(CommandMessage{
+					Command: "liveview:renew",
+					Data: map[string]interface{}{
+						"message": fmt.Sprintf("Liveview session ended (%v). Renewing", err),
+						"renewal": count,
+					},
+				})
+			}
+			renewal.Stream = stream
+			err = client.ContinuousLivestream(ctx, device_type, network_id, camera_id, inputPipe, *renewal)
+		} else {
+			err = client.LivestreamWithOptions(ctx, device_type, network_id, camera_id, inputPipe, stream)
+		}
 		if err != nil {
 			log.Println("error starting liveview session", err)
-			// TODO: Notify the client about the error
+			c.WriteJSON(errorMessage("liveview:error", err))
 		}
+		streamErr <- err
 	}()
 
 	// Tell the client that the liveview has started
@@ -120,23 +268,58 @@ func liveviewHandler(ctx context.Context, c *websocket.Conn, data map[string]int
 		}
 	}()
 
-	// Wait for the context to be cancelled
+	// Wait for the context to be cancelled, by the client or by the end of the stream
 	<-ctx.Done()
+	err = <-streamErr
 
 	// Wait for the ffmpeg command to finish
 	if err := ffmpegCmd.Wait(); err != nil {
 		log.Println("error waiting for ffplay", err)
 	}
 
-	// Tell the client that the liveview has stopped
+	// Tell the client that the liveview has stopped, with the final statistics
+	message := "Liveview stopped. Context cancelled"
+	if err != nil {
+		message = fmt.Sprintf("Liveview stopped. %v", err)
+	}
 	c.WriteJSON(CommandMessage{
 		Command: "liveview:stop",
 		Data: map[string]interface{}{
-			"message": "Liveview stopped. Context cancelled",
+			"message": message,
+			"stats":   statsMessage(stats).Data,
 		},
 	})
 }
 
+// errorMessage builds a message describing the error for the client.
+// Blink API errors include the status, code and retry hints so the client can react.
+//
+// command: the command the error relates to
+//
+// err: the error to describe
+//
+// Example: errorMessage("liveview:error", err)
+func errorMessage(command string, err error) CommandMessage {
+	data := map[string]interface{}{
+		"error":   true,
+		"message": err.Error(),
+	}
+
+	if apiErr, ok := common.AsAPIError(err); ok {
+		data["status_code"] = apiErr.StatusCode
+		data["code"] = apiErr.Code
+		data["retry_after"] = int(apiErr.RetryAfter.Seconds())
+		data["unauthorized"] = common.IsUnauthorized(err)
+		data["rate_limited"] = common.IsRateLimited(err)
+		data["device_busy"] = common.IsDeviceBusy(err)
+	}
+
+	return CommandMessage{
+		Command: command,
+		Data:    data,
+	}
+}
+
 // WebsocketHandler handles WebSocket connections from clients and performs upgrades
 //
 // w is the http.ResponseWriter
@@ -145,18 +328,27 @@ func liveviewHandler(ctx context.Context, c *websocket.Conn, data map[string]int
 //
 // Example: http.HandleFunc("/ws", handlers.WebsocketHandler)
 func WebsocketHandler(w http.ResponseWriter, r *http.Request) {
-	c, err := upgrader.Upgrade(w, r, nil)
+	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println("error upgrading the client", err)
 		return
 	}
-	defer c.Close()
+	defer conn.Close()
+	c := &clientConn{Conn: conn}
 
 	var ctx context.Context
 	var cancelCtx context.CancelFunc
 	var lastMessage time.Time = time.Now()
-	var liveviewStarted bool = false
+	// The ID of the running liveview session. 0 when stopped
+	var liveviewSession atomic.Int32
+	var sessions int32
 	var closedClient bool = false
+	var pendingCommands atomic.Int32
+	var stats *common.StreamStats
+
+	// Cancels any API requests, commands and streams when the client disconnects
+	connCtx, cancelConnCtx := context.WithCancel(r.Context())
+	defer cancelConnCtx()
 
 	// Monitor for idle connections
 	go func() {
@@ -170,7 +362,7 @@ func WebsocketHandler(w http.ResponseWriter, r *http.Request) {
 			}
 
 			// Check if the client has sent a message or if liveview has started
-			if !liveviewStarted && time.Since(lastMessage) > IDLE_TIMEOUT {
+			if liveviewSession.Load() == 0 && pendingCommands.Load() == 0 && time.Since(lastMessage) > IDLE_TIMEOUT {
 				log.Println("Idle timeout reached. Closing connection")
 				c.Close()
 				return
@@ -194,13 +386,42 @@ func WebsocketHandler(w http.ResponseWriter, r *http.Request) {
 		if message.Command == "liveview:start" {
 			log.Println("Client requested liveview:start")
 
-			ctx, cancelCtx = context.WithCancel(context.Background())
-			go liveviewHandler(ctx, c, message.Data)
-			liveviewStarted = true
-		} else if message.Command == "liveview:stop" && liveviewStarted {
+			// Only one session runs at a time
+			if cancelCtx != nil {
+				cancelCtx()
+			}
+
+			ctx, cancelCtx = context.WithCancel(connCtx)
+			stats = common.NewStreamStats()
+			sessions++
+			liveviewSession.Store(sessions)
+			go func(ctx context.Context, cancel context.CancelFunc, session int32, data map[string]interface{}, stats *common.StreamStats) {
+				liveviewHandler(ctx, cancel, c, data, stats)
+				// The session ended on its own, unless a newer one replaced it
+				liveviewSession.CompareAndSwap(session, 0)
+			}(ctx, cancelCtx, sessions, message.Data, stats)
+		} else if message.Command == "liveview:stop" && liveviewSession.Load() != 0 {
 			log.Println("Client requested liveview:stop")
 			cancelCtx()
-			liveviewStarted = false
+			liveviewSession.Store(0)
+		} else if message.Command == "liveview:stats" && stats != nil {
+			c.WriteJSON(statsMessage(stats))
+		} else if message.Command == "network:arm" || message.Command == "network:disarm" {
+			log.Println("Client requested", message.Command)
+
+			pendingCommands.Add(1)
+			go func(message CommandMessage) {
+				defer pendingCommands.Add(-1)
+				networkHandler(connCtx, c, message.Command, message.Data)
+			}(message)
+		} else if message.Command == "camera:motion" {
+			log.Println("Client requested camera:motion")
+
+			pendingCommands.Add(1)
+			go func(message CommandMessage) {
+				defer pendingCommands.Add(-1)
+				motionHandler(connCtx, c, message.Data)
+			}(message)
 		}
 	}
 
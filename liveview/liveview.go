@@ -7,9 +7,42 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"time"
 )
 
-func Run(region string, token string, deviceType string, accountId int, networkId int, cameraId int) {
+// Starts a liveview stream for the specified device and pipes it to ffplay
+//
+// client: the Blink API client holding the token, account ID and region
+//
+// deviceType: the Blink device type (e.g. owl, doorbell)
+//
+// networkId: the network ID that the camera is on
+//
+// cameraId: the ID of the camera to watch
+//
+// renewal: renews the liveview session when Blink ends it. Nil for a single session
+//
+// capture: the path of a file to record the raw stream traffic to (see the replay command). Empty disables the capture
+//
+// redact: replaces the client and connection IDs in the capture
+func Run(client *common.BlinkClient, deviceType string, networkId int, cameraId int, renewal *common.RenewalOptions, capture string, redact bool) {
+	stream := common.StreamOptions{Stats: common.NewStreamStats()}
+	if capture != "" {
+		file, err := os.Create(capture)
+		if err != nil {
+			log.Println("error creating capture file", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+
+		if stream.Capture, err = common.NewCaptureWriter(file, time.Now()); err != nil {
+			log.Println("error creating capture file", err)
+			os.Exit(1)
+		}
+		stream.Capture.Redact = redact
+		log.Println("Capturing the stream traffic to", capture)
+	}
+
 	ffplayCmd := exec.Command("ffplay",
 		"-f", "mpegts",
 		"-err_detect", "ignore_err",
@@ -35,18 +68,26 @@ func Run(region string, token string, deviceType string, accountId int, networkI
 		cancelCtx()
 	}()
 
-	accountDetails := common.AccountDetails{
-		Region:     region,
-		Token:      token,
-		DeviceType: deviceType,
-		AccountId:  accountId,
-		NetworkId:  networkId,
-		CameraId:   cameraId,
+	if renewal != nil {
+		renewal.Stream = stream
+		err = client.ContinuousLivestream(ctx, deviceType, networkId, cameraId, inputPipe, *renewal)
+	} else {
+		err = client.LivestreamWithOptions(ctx, deviceType, networkId, cameraId, inputPipe, stream)
 	}
-	if err := common.Livestream(ctx, accountDetails, inputPipe); err != nil {
+
+	if common.IsUnauthorized(err) {
+		log.Println("the API token is invalid or expired", err)
+	} else if common.IsDeviceBusy(err) || common.IsRateLimited(err) {
+		log.Println("the device is busy. Wait a few seconds and try again", err)
+	} else if err != nil {
 		log.Println("error during livestream", err)
 	}
 
+	stats := stream.Stats.Snapshot()
+	log.Printf("Stream stats: %d bytes in %d reads, %d reconnects, %d/%d keep-alives acknowledged, %s to first byte, %s longest read gap, disconnect reason %q\n",
+		stats.BytesReceived, stats.Reads, stats.Reconnects, stats.KeepalivesAcknowledged, stats.KeepalivesSent,
+		stats.TimeToFirstByte, stats.LongestReadGap, stats.DisconnectReason)
+
 	inputPipe.Close()
 	if err := ffplayCmd.Wait(); err != nil {
 		log.Println("error waiting for ffplay", err)
@@ -0,0 +1,94 @@
+package localstorage
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+)
+
+// Lists the clips stored on the sync module's USB storage
+//
+// client: the Blink API client holding the token, account ID and region
+//
+// networkId: the network ID that the sync module is on
+//
+// syncModuleId: the ID of the sync module
+func List(client *common.BlinkClient, networkId int, syncModuleId int) {
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancelCtx()
+
+	manifest := requestManifest(ctx, client, networkId, syncModuleId)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tCAMERA\tSIZE")
+	for _, clip := range manifest.Clips {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", clip.Id, clip.CreatedAt.Local().Format(time.DateTime), clip.CameraName, clip.Size)
+	}
+	w.Flush()
+}
+
+// Downloads a clip from the sync module's USB storage to a file or stdout
+//
+// client: the Blink API client holding the token, account ID and region
+//
+// networkId: the network ID that the sync module is on
+//
+// syncModuleId: the ID of the sync module
+//
+// clipId: the ID of the clip to download, as listed by List
+//
+// output: the file to write the MP4 to. Use "-" for stdout
+func Download(client *common.BlinkClient, networkId int, syncModuleId int, clipId string, output string) {
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancelCtx()
+
+	// Clip IDs are only valid for the manifest they were listed in, so request a fresh one
+	manifest := requestManifest(ctx, client, networkId, syncModuleId)
+	if _, ok := manifest.FindClip(clipId); !ok {
+		log.Printf("clip %s not found on the sync module\n", clipId)
+		os.Exit(1)
+	}
+
+	file := os.Stdout
+	if output != "-" {
+		var err error
+		if file, err = os.Create(output); err != nil {
+			log.Println("error creating output file", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+	}
+
+	n, err := client.DownloadLocalStorageClip(ctx, networkId, syncModuleId, manifest.ManifestId, clipId, file)
+	if err != nil {
+		log.Println("error downloading clip", err)
+		if output != "-" {
+			file.Close()
+			os.Remove(output)
+		}
+		os.Exit(1)
+	}
+
+	if output != "-" {
+		log.Printf("Saved clip %s to %s (%d bytes)\n", clipId, output, n)
+	}
+}
+
+// requestManifest requests the local storage manifest or exits on error
+func requestManifest(ctx context.Context, client *common.BlinkClient, networkId int, syncModuleId int) *common.LocalStorageManifest {
+	manifest, err := client.LocalStorageManifest(ctx, networkId, syncModuleId)
+	if common.IsUnauthorized(err) {
+		log.Println("the API token is invalid or expired", err)
+		os.Exit(1)
+	} else if err != nil {
+		log.Println("error requesting local storage manifest", err)
+		os.Exit(1)
+	}
+
+	return manifest
+}
@@ -0,0 +1,157 @@
+package login
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// Logs in with the provided email and password and saves the session to a named profile.
+// The fingerprint of an existing profile is reused, so logging in again does not require a new verification code.
+//
+// client: the Blink API client used to reach the OAuth endpoint
+//
+// email: the Blink account email address
+//
+// password: the Blink account password
+//
+// profileName: the name of the profile to save the session to
+//
+// proxy: the proxy to save to the profile, in the --proxy format. Empty keeps the profile's proxy
+func Run(client *common.BlinkClient, email string, password string, profileName string, proxy string) {
+	// Interrupting the verification prompt exits the process, so the default signal handling is kept
+	profile := Authenticate(context.Background(), client, email, password, profileName)
+
+	if proxy != "" {
+		profile.Proxy = proxy
+		if err := profile.Save(""); err != nil {
+			log.Println("error saving proxy to profile", err)
+			os.Exit(1)
+		}
+	}
+
+	log.Printf("Logged in successfully. Saved profile %s (AccountId: %d, Region: %s)\n", profile.Name, profile.AccountId, profile.Region)
+}
+
+// Removes a saved profile
+//
+// profileName: the name of the profile to remove
+func Logout(profileName string) {
+	if err := common.DeleteProfile("", profileName); err != nil {
+		log.Println("error removing profile", err)
+		os.Exit(1)
+	}
+
+	log.Printf("Removed profile %s\n", profileName)
+}
+
+// Authenticates with the Blink API, prompting for a verification code if required,
+// and saves the session to the profile
+//
+// ctx: the context to use for the API requests
+//
+// client: the Blink API client used to reach the OAuth endpoint. Updated with the session details on success
+//
+// email: the Blink account email address
+//
+// password: the Blink account password
+//
+// profileName: the name of the profile to save the session to
+func Authenticate(ctx context.Context, client *common.BlinkClient, email string, password string, profileName string) *common.Profile {
+	// New profiles take over the fingerprint file, so upgrading does not require a new verification code
+	var fingerprint *common.Fingerprint
+	profile, err := common.LoadProfile("", profileName)
+	if errors.Is(err, common.ErrProfileNotFound) {
+		profile, fingerprint, err = common.NewProfileFromFingerprint(profileName, "")
+	}
+	if err != nil {
+		log.Println("error loading profile", err)
+		os.Exit(1)
+	}
+
+	flow := common.NewLoginFlow(client, email, password, profile.Fingerprint())
+	if _, err := flow.Start(ctx); err != nil {
+		log.Println("error logging in", err)
+		os.Exit(1)
+	}
+
+	if flow.State == common.LoginStateAwaitingCode {
+		printVerificationNotice(flow)
+	}
+
+	for flow.State == common.LoginStateAwaitingCode {
+		fmt.Print("Code (leave empty to resend): ")
+		codeBytes, err := term.ReadPassword(int(syscall.Stdin))
+		if err != nil {
+			os.Exit(1)
+		}
+		code := strings.TrimSpace(string(codeBytes))
+		fmt.Println()
+
+		if code == "" {
+			if err := flow.Resend(ctx); err != nil {
+				log.Println("error resending code", err)
+			} else {
+				printVerificationNotice(flow)
+			}
+			continue
+		}
+
+		if _, err := flow.SubmitCode(ctx, code); errors.Is(err, common.ErrInvalidCode) && flow.State == common.LoginStateAwaitingCode {
+			log.Println("Incorrect code. Please try again.")
+		} else if err != nil {
+			log.Println("error verifying code", err)
+			os.Exit(1)
+		}
+	}
+
+	client.Token = flow.Response.AccessToken
+	client.Tokens = common.NewTokenSource(flow.Response, profile.HardwareId)
+	tierInfo, err := client.GetTierInfo(ctx)
+	if err != nil {
+		log.Println("error getting tier info", err)
+		os.Exit(1)
+	}
+
+	profile.Email = email
+	profile.AccountId = tierInfo.AccountId
+	profile.Region = tierInfo.Tier
+	profile.Update(flow.Response)
+	if err := profile.Save(""); err != nil {
+		log.Println("error saving profile", err)
+		os.Exit(1)
+	}
+
+	// The fingerprint is now stored in the profile
+	if fingerprint != nil {
+		if err := fingerprint.Destroy(); err != nil {
+			log.Println("error removing the fingerprint file", err)
+		}
+	}
+
+	client.AccountId = tierInfo.AccountId
+	client.Region = tierInfo.Tier
+
+	return profile
+}
+
+// Prints where the two-step verification code was sent
+//
+// flow: the login flow awaiting a verification code
+func printVerificationNotice(flow *common.LoginFlow) {
+	switch flow.Method {
+	case "sms":
+		log.Printf("Client verification is required. A SMS code has been sent to %s.\n", flow.Phone)
+	case "email":
+		log.Println("Client verification is required. A code has been sent to your email address.")
+	default:
+		log.Printf("Client verification is required (%s). A code has been sent to you.\n", flow.Method)
+	}
+}
@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"blink-liveview-websocket/common"
+	"blink-liveview-websocket/handlers"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+)
+
+type Options struct {
+	// The interval between homescreen polls
+	Interval time.Duration
+	// The thresholds that raise alerts
+	Thresholds common.HealthThresholds
+	// The URL to POST events to. Events are written to stdout when empty
+	Webhook string
+	// Only emit events that breach a threshold
+	AlertsOnly bool
+	// The address to serve /devices/health on. Disabled when empty
+	Address string
+}
+
+// The HTTP client used to deliver webhooks
+var webhookClient = &http.Client{Timeout: common.HTTP_TIMEOUT, Transport: common.DefaultTransport}
+
+// Polls the homescreen on an interval and emits device health change events as JSON lines or webhooks
+//
+// client: the Blink API client holding the token, account ID and region
+//
+// opts: the monitor options
+func Run(client *common.BlinkClient, opts Options) {
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancelCtx()
+
+	encoder := json.NewEncoder(os.Stdout)
+	monitor := common.NewHealthMonitor(client, opts.Interval, opts.Thresholds)
+	monitor.OnEvent = func(event common.HealthEvent) {
+		if opts.AlertsOnly && !event.Alert {
+			return
+		}
+
+		if opts.Webhook == "" {
+			encoder.Encode(event)
+		} else if err := postWebhook(ctx, opts.Webhook, event); err != nil {
+			log.Println("error delivering webhook", err)
+		}
+	}
+
+	if opts.Address != "" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /devices/health", handlers.HealthHandler(monitor))
+		server := &http.Server{Addr: opts.Address, Handler: mux}
+
+		go func() {
+			log.Printf("Serving device health on http://%s/devices/health\n", opts.Address)
+			if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("HTTP server error: %v", err)
+			}
+		}()
+		defer server.Shutdown(context.Background())
+	}
+
+	log.Printf("Monitoring device health every %s\n", monitor.Interval)
+	err := monitor.Run(ctx)
+	if common.IsUnauthorized(err) {
+		log.Println("the API token is invalid or expired", err)
+		os.Exit(1)
+	} else if err != nil {
+		log.Println("error monitoring device health", err)
+		os.Exit(1)
+	}
+}
+
+// postWebhook delivers a single event to the webhook URL
+func postWebhook(ctx context.Context, url string, event common.HealthEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with HTTP Status Code %d", resp.StatusCode)
+	}
+
+	return nil
+}
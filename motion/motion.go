@@ -0,0 +1,40 @@
+package motion
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"log"
+	"os"
+	"os/signal"
+)
+
+// Enables or disables motion detection on a single device and waits for the command to complete
+//
+// client: the Blink API client holding the token, account ID and region
+//
+// deviceType: the Blink device type (e.g. camera, owl, doorbell)
+//
+// networkId: the network ID that the device is on
+//
+// deviceId: the ID of the device to update
+//
+// enabled: true to enable motion detection, false to disable it
+func Run(client *common.BlinkClient, deviceType string, networkId int, deviceId int, enabled bool) {
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancelCtx()
+
+	result, err := client.SetMotionDetection(ctx, deviceType, networkId, deviceId, enabled)
+	if common.IsUnauthorized(err) {
+		log.Println("the API token is invalid or expired", err)
+		os.Exit(1)
+	} else if err != nil {
+		log.Println("error changing motion detection", err)
+		os.Exit(1)
+	}
+
+	state := "disabled"
+	if result.Enabled {
+		state = "enabled"
+	}
+	log.Printf("Motion detection is now %s for %s %d\n", state, result.DeviceType, result.DeviceId)
+}
@@ -0,0 +1,49 @@
+package network
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"log"
+	"os"
+	"os/signal"
+)
+
+// Arms or disarms a Blink network and waits for the command to complete
+//
+// client: the Blink API client holding the token, account ID and region
+//
+// networkId: the ID of the network to arm or disarm
+//
+// armed: true to arm the network, false to disarm it
+func Run(client *common.BlinkClient, networkId int, armed bool) {
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancelCtx()
+
+	action := "Disarming"
+	if armed {
+		action = "Arming"
+	}
+	log.Printf("%s network %d\n", action, networkId)
+
+	result, err := client.SetNetworkArmed(ctx, networkId, armed)
+	if common.IsUnauthorized(err) {
+		log.Println("the API token is invalid or expired", err)
+		os.Exit(1)
+	} else if err != nil {
+		log.Println("error changing the network state", err)
+		os.Exit(1)
+	}
+
+	log.Printf("Network %d is now %s. %s\n", networkId, stateName(armed), result.StatusMessage)
+}
+
+// stateName returns the display name of the arm state
+//
+// armed: the arm state
+func stateName(armed bool) string {
+	if armed {
+		return "armed"
+	}
+
+	return "disarmed"
+}
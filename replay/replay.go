@@ -0,0 +1,78 @@
+package replay
+
+import (
+	"blink-liveview-websocket/common"
+	"context"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+)
+
+// Plays a capture recorded by the liveview command with ffplay
+//
+// path: the path of the capture file
+//
+// speed: the playback speed relative to the original timing. 0 plays as fast as possible
+func Run(path string, speed float64) {
+	ffplayCmd := exec.Command("ffplay",
+		"-f", "mpegts",
+		"-err_detect", "ignore_err",
+		"-window_title", "Blink Liveview Replay",
+		"-",
+	)
+	inputPipe, err := ffplayCmd.StdinPipe()
+	if err != nil {
+		log.Println("error creating ffplay stdin pipe", err)
+	}
+
+	if err := ffplayCmd.Start(); err != nil {
+		log.Println("error starting ffplay", err)
+	}
+	defer ffplayCmd.Process.Kill()
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancelCtx()
+
+	if err := common.ReplayCaptureFile(ctx, path, inputPipe, common.ReplayOptions{Speed: speed}); err != nil {
+		log.Println("error replaying capture", err)
+	}
+
+	inputPipe.Close()
+	if err := ffplayCmd.Wait(); err != nil {
+		log.Println("error waiting for ffplay", err)
+	}
+}
+
+// Writes a copy of the capture with the client and connection IDs replaced
+//
+// path: the path of the capture file
+//
+// output: the path of the redacted copy
+func Redact(path string, output string) {
+	input, err := os.Open(path)
+	if err != nil {
+		log.Println("error opening capture", err)
+		os.Exit(1)
+	}
+	defer input.Close()
+
+	file, err := os.Create(output)
+	if err != nil {
+		log.Println("error creating redacted capture", err)
+		os.Exit(1)
+	}
+
+	if err := common.RedactCapture(input, file); err != nil {
+		file.Close()
+		os.Remove(output)
+		log.Println("error redacting capture", err)
+		os.Exit(1)
+	}
+	if err := file.Close(); err != nil {
+		log.Println("error writing redacted capture", err)
+		os.Exit(1)
+	}
+
+	log.Println("Saved the redacted capture to", output)
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"blink-liveview-websocket/common"
 	"blink-liveview-websocket/handlers"
 	"context"
 	"errors"
@@ -12,10 +13,32 @@ import (
 	"time"
 )
 
-func Run(address string, env string, origins []string) {
+func Run(address string, env string, origins []string, profile *common.Profile, profileSecret string, replay string, replaySpeed float64) {
 	server := &http.Server{Addr: address}
 
+	if replay != "" {
+		log.Printf("Replaying %s to every liveview session\n", replay)
+		handlers.SetReplay(replay, replaySpeed)
+	}
+
+	if profile != nil {
+		// Without a secret, anyone who can reach the server acts as the profile owner
+		if profileSecret == "" && !handlers.IsLoopbackAddress(address) {
+			log.Printf("refusing to use login profile %s on %s without a secret. Set %s or bind to a loopback address\n", profile.Name, address, handlers.PROFILE_SECRET_ENV)
+			os.Exit(1)
+		}
+		if profileSecret == "" {
+			log.Printf("WARNING: every local client can use login profile %s. Set %s to require a secret\n", profile.Name, handlers.PROFILE_SECRET_ENV)
+		}
+
+		log.Printf("Using login profile %s for requests without credentials\n", profile.Name)
+		handlers.SetProfile(profile, profileSecret)
+	}
+
 	http.HandleFunc("/liveview", handlers.WebsocketHandler)
+	http.HandleFunc("GET /cameras/{id}/snapshot.jpg", handlers.SnapshotHandler)
+	http.HandleFunc("GET /sync_modules/{id}/local_storage", handlers.LocalStorageHandler)
+	http.HandleFunc("GET /sync_modules/{id}/local_storage/{clip_id}", handlers.LocalStorageClipHandler)
 
 	if env == "development" {
 		log.Println("Enabled static file server")
@@ -0,0 +1,52 @@
+package snapshot
+
+import (
+	"blink-liveview-websocket/common"
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"os/signal"
+)
+
+// Captures a new thumbnail on the device and writes the JPEG to a file or stdout
+//
+// client: the Blink API client holding the token, account ID and region
+//
+// deviceType: the Blink device type (e.g. camera, owl, doorbell)
+//
+// networkId: the network ID that the device is on
+//
+// cameraId: the ID of the camera to capture the snapshot on
+//
+// output: the file to write the JPEG to. Use "-" for stdout
+func Run(client *common.BlinkClient, deviceType string, networkId int, cameraId int, output string) {
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancelCtx()
+
+	// Buffer the image so a failed capture never leaves a partial file behind
+	var image bytes.Buffer
+	err := client.Snapshot(ctx, deviceType, networkId, cameraId, &image)
+	if common.IsUnauthorized(err) {
+		log.Println("the API token is invalid or expired", err)
+		os.Exit(1)
+	} else if err != nil {
+		log.Println("error capturing snapshot", err)
+		os.Exit(1)
+	}
+
+	if output == "-" {
+		if _, err := os.Stdout.Write(image.Bytes()); err != nil {
+			log.Println("error writing snapshot", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := os.WriteFile(output, image.Bytes(), 0644); err != nil {
+		log.Println("error writing snapshot", err)
+		os.Exit(1)
+	}
+
+	log.Printf("Saved snapshot to %s (%d bytes)\n", output, image.Len())
+}